@@ -3,24 +3,98 @@ package config
 
 import (
     "os"
+    "stock-service/models"
+    "strconv"
+    "strings"
     "github.com/go-redis/redis/v8"
     "go.uber.org/zap"
     "go.uber.org/zap/zapcore"
 )
 
+// Modes d'application du FEFO (premier périmé, premier sorti)
+const (
+    FEFO_MODE_SUGGESTION = "suggestion"
+    FEFO_MODE_STRICT     = "strict"
+)
+
 type Config struct {
     Port        string
     Environment string
     RedisURL    string
     JWTSecret   string
+    // FEFOMode indique si une sortie sur un lot qui n'est pas le premier à
+    // périmer est seulement signalée ("suggestion") ou refusée ("strict")
+    FEFOMode string
+    // ExpiryAlertDays est l'horizon, en jours, des alertes de péremption
+    ExpiryAlertDays int
+    // IdempotencyTTLHours est la durée de conservation des réponses associées
+    // à un en-tête Idempotency-Key
+    IdempotencyTTLHours int
+    // ValuationMethod est la méthode de valorisation du stock et du coût des
+    // sorties: "pmp" (prix moyen pondéré) ou "fifo"
+    ValuationMethod string
+    // Currency est la devise par défaut des nouvelles pièces et la devise des
+    // rapports de valorisation
+    Currency string
+    // ExchangeRates donne, pour chaque devise, la valeur d'une unité dans une
+    // devise de référence commune; les montants sont convertis par leur rapport
+    ExchangeRates map[string]models.Decimal
+    // DefaultLeadTimeDays est le délai de livraison, en jours, des
+    // fournisseurs créés sans délai explicite
+    DefaultLeadTimeDays int
+    // ServiceLevel est le niveau de service visé (probabilité de ne pas
+    // tomber en rupture pendant un réapprovisionnement) pour le calcul du
+    // stock de sécurité
+    ServiceLevel float64
+    // ConsumptionWindowDays est la période d'historique, en jours, analysée
+    // pour estimer la consommation des pièces
+    ConsumptionWindowDays int
+    // ClassificationIntervalHours est l'intervalle, en heures, du recalcul
+    // planifié de la classification ABC/XYZ; 0 le désactive
+    ClassificationIntervalHours int
+    // WebhookMaxAttempts est le nombre de tentatives d'envoi d'un webhook
+    // avant son passage en liste des échecs
+    WebhookMaxAttempts int
+    // WebhookBackoffSeconds est le délai, en secondes, avant la deuxième
+    // tentative; il double à chaque nouvel échec
+    WebhookBackoffSeconds int
+    // WebhookTimeoutSeconds est le délai de réponse accordé, en secondes, à
+    // chaque envoi
+    WebhookTimeoutSeconds int
+    // WebhookAllowPrivate autorise les webhooks vers des adresses internes
+    // (boucle locale, lien local, réseaux privés), refusées par défaut pour
+    // qu'un abonnement ne fasse pas appeler au service des points internes
+    WebhookAllowPrivate bool
+    // DomainEventsMaxLen est le nombre approximatif d'événements de domaine
+    // conservés dans le flux Redis; 0 le rend illimité
+    DomainEventsMaxLen int
+    // DomainEventGroups sont les groupes de consommateurs du flux des
+    // événements de domaine créés au démarrage, un par service consommateur
+    DomainEventGroups []string
 }
 
 func Load() *Config {
     return &Config{
-        Port:        getEnv("PORT", "8004"),
-        Environment: getEnv("ENVIRONMENT", "development"),
-        RedisURL:    getEnv("REDIS_URL", "redis://redis-stock:6379"),
-        JWTSecret:   getEnv("JWT_SECRET", "your-secret-key-here"),
+        Port:                        getEnv("PORT", "8004"),
+        Environment:                 getEnv("ENVIRONMENT", "development"),
+        RedisURL:                    getEnv("REDIS_URL", "redis://redis-stock:6379"),
+        JWTSecret:                   getEnv("JWT_SECRET", "your-secret-key-here"),
+        FEFOMode:                    getEnv("FEFO_MODE", FEFO_MODE_SUGGESTION),
+        ExpiryAlertDays:             getEnvInt("EXPIRY_ALERT_DAYS", 30),
+        IdempotencyTTLHours:         getEnvInt("IDEMPOTENCY_TTL_HOURS", 24),
+        ValuationMethod:             getEnv("VALUATION_METHOD", models.VALUATION_METHOD_AVERAGE),
+        Currency:                    models.CanonicalCurrency(getEnv("CURRENCY", models.DEFAULT_CURRENCY)),
+        ExchangeRates:               getEnvRates("EXCHANGE_RATES", "XOF=1,EUR=655.957"),
+        DefaultLeadTimeDays:         getEnvInt("DEFAULT_LEAD_TIME_DAYS", 14),
+        ServiceLevel:                getEnvFloat("SERVICE_LEVEL", 0.95),
+        ConsumptionWindowDays:       getEnvInt("CONSUMPTION_WINDOW_DAYS", 180),
+        ClassificationIntervalHours: getEnvInt("CLASSIFICATION_INTERVAL_HOURS", 24),
+        WebhookMaxAttempts:          getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+        WebhookBackoffSeconds:       getEnvInt("WEBHOOK_BACKOFF_SECONDS", 30),
+        WebhookTimeoutSeconds:       getEnvInt("WEBHOOK_TIMEOUT_SECONDS", 10),
+        WebhookAllowPrivate:         getEnvBool("WEBHOOK_ALLOW_PRIVATE", false),
+        DomainEventsMaxLen:          getEnvInt("DOMAIN_EVENTS_MAX_LEN", 100000),
+        DomainEventGroups:           getEnvList("DOMAIN_EVENT_GROUPS", "interventions-service,machines-service"),
     }
 }
 
@@ -31,6 +105,57 @@ func getEnv(key, defaultValue string) string {
     return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+    if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+        return value
+    }
+    return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+    if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+        return value
+    }
+    return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+    if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+        return value
+    }
+    return defaultValue
+}
+
+// getEnvList lit une liste de valeurs séparées par des virgules; les entrées
+// vides sont ignorées
+func getEnvList(key, defaultValue string) []string {
+    values := make([]string, 0)
+    for _, entry := range strings.Split(getEnv(key, defaultValue), ",") {
+        if entry = strings.TrimSpace(entry); entry != "" {
+            values = append(values, entry)
+        }
+    }
+    return values
+}
+
+// getEnvRates lit une liste de taux de la forme "XOF=1,EUR=655.957"; les
+// entrées illisibles sont ignorées
+func getEnvRates(key, defaultValue string) map[string]models.Decimal {
+    rates := make(map[string]models.Decimal)
+    for _, entry := range strings.Split(getEnv(key, defaultValue), ",") {
+        parts := strings.SplitN(entry, "=", 2)
+        if len(parts) != 2 {
+            continue
+        }
+        rate, err := models.ParseDecimal(parts[1])
+        if err != nil || rate <= 0 {
+            continue
+        }
+        rates[models.CanonicalCurrency(parts[0])] = rate
+    }
+    return rates
+}
+
 func InitRedis(cfg *Config) *redis.Client {
     opts, err := redis.ParseURL(cfg.RedisURL)
     if err != nil {
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+    "net/http"
+    "stock-service/models"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+)
+
+// ApplyMovementBatch applique un lot de mouvements en tout ou rien
+// @Summary Mouvements groupés
+// @Description Applique plusieurs entrées et sorties, sur une ou plusieurs pièces, dans une seule transaction: toutes les lignes sont appliquées ou aucune. Le résultat est détaillé ligne par ligne.
+// @Tags Stock
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param mouvements body models.BatchMovementRequest true "Lignes de mouvement"
+// @Param Idempotency-Key header string false "Clé d'idempotence: une répétition avec la même clé renvoie la première réponse sans nouvel effet"
+// @Success 200 {object} map[string]interface{} "Toutes les lignes appliquées"
+// @Failure 400 {object} map[string]interface{} "Données invalides"
+// @Failure 409 {object} map[string]interface{} "Requête idempotente en cours de traitement"
+// @Failure 422 {object} map[string]interface{} "Lot rejeté, aucune ligne appliquée, ou clé d'idempotence réutilisée"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/movements/batch [post]
+func (sc *StockController) ApplyMovementBatch(c *gin.Context) {
+    var req models.BatchMovementRequest
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        sc.logger.Warn("Données invalides pour mouvements groupés", zap.Error(err))
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    results, err := sc.stockService.ApplyMovementBatch(&req, currentUserID(c))
+    if err != nil {
+        if strings.HasPrefix(err.Error(), "lot de mouvements rejeté") {
+            c.JSON(http.StatusUnprocessableEntity, gin.H{
+                "error": "Lot de mouvements rejeté",
+                "details": err.Error(),
+                "data": results,
+            })
+            return
+        }
+
+        sc.logger.Error("Erreur lors des mouvements groupés", zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "Erreur lors des mouvements groupés",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Mouvements appliqués avec succès",
+        "data": results,
+        "count": len(results),
+    })
+}
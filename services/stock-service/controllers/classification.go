@@ -0,0 +1,73 @@
+package controllers
+
+import (
+    "net/http"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+)
+
+// ClassifyPieces recalcule la classification ABC/XYZ
+// @Summary Calculer la classification ABC/XYZ
+// @Description Classe les pièces selon la valeur annuelle de leur consommation (A: 80% de la valeur, B: 15%, C: le reste) et la régularité de leur demande hebdomadaire (X: coefficient de variation jusqu'à 0.5, Y: jusqu'à 1, Z: au-delà ou sans consommation), et enregistre le classement sur chaque pièce
+// @Tags Classification
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Classification calculée"
+// @Failure 403 {object} map[string]interface{} "Rôle insuffisant"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/classification [post]
+func (sc *StockController) ClassifyPieces(c *gin.Context) {
+    report, err := sc.stockService.ClassifyPieces(currentUserID(c))
+    if err != nil {
+        sc.logger.Error("Erreur lors de la classification des pièces", zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "Erreur lors de la classification des pièces",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Classification ABC/XYZ calculée",
+        "data": report,
+    })
+}
+
+// GetClassificationReport récupère le dernier calcul de classification
+// @Summary Dernière classification ABC/XYZ
+// @Description Retourne le rapport du dernier calcul de classification, à la demande ou planifié; les classes de chaque pièce se filtrent par GET /stock?classe_abc=&classe_xyz=
+// @Tags Classification
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Rapport de classification"
+// @Failure 404 {object} map[string]interface{} "Classification jamais calculée"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/classification [get]
+func (sc *StockController) GetClassificationReport(c *gin.Context) {
+    report, err := sc.stockService.GetClassificationReport()
+    if err != nil {
+        if strings.HasPrefix(err.Error(), "classification non calculée") {
+            c.JSON(http.StatusNotFound, gin.H{
+                "error": "Classification non calculée",
+                "details": err.Error(),
+            })
+            return
+        }
+
+        sc.logger.Error("Erreur lors de la récupération de la classification", zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "Erreur lors de la récupération de la classification",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Classification récupérée",
+        "data": report,
+    })
+}
@@ -0,0 +1,20 @@
+package controllers
+
+import (
+    "net/http"
+    "stock-service/models"
+
+    "github.com/gin-gonic/gin"
+)
+
+// GetDomainEventSchema retourne le schéma des événements de domaine
+// @Summary Schéma des événements de domaine
+// @Description Retourne le schéma JSON (draft 2020-12) du contenu des événements de domaine que le service ajoute au flux Redis "stock:domain-events" à chaque création, modification, suppression de pièce et mouvement de stock. Chaque entrée du flux porte les champs "id", "type", "version", "piece_id" et "payload", ce dernier conforme au schéma.
+// @Tags Événements
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Schéma JSON des événements"
+// @Router /stock/events/schema [get]
+func (sc *StockController) GetDomainEventSchema(c *gin.Context) {
+    c.Data(http.StatusOK, "application/schema+json", models.DomainEventSchema)
+}
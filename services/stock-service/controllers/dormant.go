@@ -0,0 +1,151 @@
+package controllers
+
+import (
+    "encoding/csv"
+    "fmt"
+    "net/http"
+    "sort"
+    "stock-service/models"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+)
+
+// GetDormantStock liste le stock dormant
+// @Summary Stock dormant
+// @Description Liste les pièces en stock sans sortie depuis N mois avec la valeur qu'elles immobilisent, et les totaux par catégorie et par emplacement. Une pièce jamais sortie est dormante si elle a été créée avant la date limite. Avec format=csv, le détail des pièces est retourné en CSV.
+// @Tags Valorisation
+// @Accept json
+// @Produce json,text/csv
+// @Security BearerAuth
+// @Param mois query int false "Mois sans sortie (12 par défaut)"
+// @Param format query string false "Format de sortie" Enums(json, csv)
+// @Param emplacement query string false "Emplacement de stockage"
+// @Param fournisseur_id query string false "ID du fournisseur"
+// @Param classe_abc query string false "Classes ABC, séparées par des virgules"
+// @Success 200 {object} map[string]interface{} "Stock dormant"
+// @Failure 400 {object} map[string]interface{} "Paramètre invalide"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/dormant [get]
+func (sc *StockController) GetDormantStock(c *gin.Context) {
+    months := 0
+    if value := c.Query("mois"); value != "" {
+        parsed, err := strconv.Atoi(value)
+        if err != nil || parsed < 1 {
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Paramètre invalide",
+                "details": fmt.Sprintf("mois invalide: %s", value),
+            })
+            return
+        }
+        months = parsed
+    }
+
+    format := strings.ToLower(c.DefaultQuery("format", "json"))
+    if format != "json" && format != "csv" {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Paramètre invalide",
+            "details": fmt.Sprintf("format invalide: %s (attendu json ou csv)", format),
+        })
+        return
+    }
+
+    report, err := sc.stockService.GetDormantStock(months, pieceFilterFromQuery(c))
+    if err != nil {
+        if strings.HasPrefix(err.Error(), "période invalide") {
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Paramètre invalide",
+                "details": err.Error(),
+            })
+            return
+        }
+
+        sc.logger.Error("Erreur lors du calcul du stock dormant", zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "Erreur lors du calcul du stock dormant",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    if format == "csv" {
+        sc.writeDormantCSV(c, report)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Stock dormant calculé",
+        "data": report,
+    })
+}
+
+// writeDormantCSV écrit le détail du stock dormant en CSV, une ligne par
+// pièce; les emplacements sont listés sous la forme "A1:3|B2:1"
+func (sc *StockController) writeDormantCSV(c *gin.Context, report *models.DormantStockReport) {
+    filename := fmt.Sprintf("stock-dormant-%dm-%s.csv", report.Mois, report.GeneratedAt.Format("20060102"))
+    c.Header("Content-Type", "text/csv; charset=utf-8")
+    c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+    c.Status(http.StatusOK)
+
+    writer := csv.NewWriter(c.Writer)
+    writer.Write([]string{
+        "piece_id", "nom", "categorie", "emplacements", "quantite", "unite",
+        "derniere_sortie", "derniere_entree", "jours_sans_sortie",
+        "cout_unitaire", "valeur", "devise",
+    })
+    for _, piece := range report.Pieces {
+        writer.Write([]string{
+            piece.PieceID,
+            csvText(piece.Nom),
+            csvText(piece.Categorie),
+            csvText(formatLocations(piece.Emplacements)),
+            models.FormatQuantity(piece.Quantite),
+            csvText(piece.Unite),
+            formatDate(piece.DerniereSortie),
+            formatDate(piece.DerniereEntree),
+            strconv.Itoa(piece.JoursSansSortie),
+            piece.CoutUnitaire.String(),
+            piece.Valeur.String(),
+            report.Devise,
+        })
+    }
+    writer.Flush()
+    if err := writer.Error(); err != nil {
+        sc.logger.Error("Erreur lors de l'écriture du stock dormant en CSV", zap.Error(err))
+    }
+}
+
+// csvText neutralise un texte libre qu'un tableur interpréterait comme une
+// formule, en le préfixant d'une apostrophe
+func csvText(value string) string {
+    if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+        return "'" + value
+    }
+    return value
+}
+
+// formatLocations formate les quantités par emplacement, triées par emplacement
+func formatLocations(locations map[string]float64) string {
+    keys := make([]string, 0, len(locations))
+    for location := range locations {
+        keys = append(keys, location)
+    }
+    sort.Strings(keys)
+
+    parts := make([]string, 0, len(keys))
+    for _, location := range keys {
+        parts = append(parts, location+":"+models.FormatQuantity(locations[location]))
+    }
+    return strings.Join(parts, "|")
+}
+
+// formatDate formate une date facultative au format AAAA-MM-JJ
+func formatDate(date *time.Time) string {
+    if date == nil {
+        return ""
+    }
+    return date.Format("2006-01-02")
+}
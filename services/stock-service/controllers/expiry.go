@@ -0,0 +1,156 @@
+package controllers
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+)
+
+// DEFAULT_EXPIRY_WITHIN est l'horizon par défaut du rapport de péremption
+const DEFAULT_EXPIRY_WITHIN = 30 * 24 * time.Hour
+
+// GetExpiringLots récupère les lots périmés ou proches de la péremption
+// @Summary Lots proches de la péremption
+// @Description Retourne les lots et numéros de série périmés ou périmant dans le délai donné, du plus proche au plus lointain
+// @Tags Stock
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param within query string false "Délai (ex: 30d, 12h), 30d par défaut"
+// @Param emplacement query string false "Emplacement de stockage"
+// @Success 200 {object} map[string]interface{} "Lots concernés"
+// @Failure 400 {object} map[string]interface{} "Délai invalide"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/expiring [get]
+func (sc *StockController) GetExpiringLots(c *gin.Context) {
+    within, err := parseWithin(c.Query("within"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Paramètre 'within' invalide",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    lots, err := sc.stockService.GetExpiringLots(within, pieceFilterFromQuery(c))
+    if err != nil {
+        sc.logger.Error("Erreur lors de la récupération des lots à péremption", zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "Erreur lors de la récupération des lots à péremption",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    valeur, devise, err := sc.stockService.ExpiringValue(lots)
+    if err != nil {
+        sc.logger.Error("Erreur lors de la valorisation des lots à péremption", zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "Erreur lors de la valorisation des lots à péremption",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    perimes := 0
+    for _, lot := range lots {
+        if lot.Perime {
+            perimes++
+        }
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Lots à péremption récupérés",
+        "data": lots,
+        "summary": gin.H{
+            "total": len(lots),
+            "perimes": perimes,
+            "valeur": valeur,
+            "devise": devise,
+            "within": within.String(),
+        },
+    })
+}
+
+// SuggestFEFO propose les lots à sortir dans l'ordre FEFO
+// @Summary Suggestion FEFO
+// @Description Propose les lots ou numéros de série à sortir en premier (premier périmé, premier sorti) pour une quantité donnée
+// @Tags Stock
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "ID de la pièce"
+// @Param quantite query int true "Quantité à sortir"
+// @Success 200 {object} map[string]interface{} "Lots proposés"
+// @Failure 400 {object} map[string]interface{} "Paramètre invalide, pièce non suivie ou stock insuffisant"
+// @Failure 404 {object} map[string]interface{} "Pièce non trouvée"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/{id}/fefo [get]
+func (sc *StockController) SuggestFEFO(c *gin.Context) {
+    id := c.Param("id")
+
+    quantite, err := strconv.ParseFloat(c.Query("quantite"), 64)
+    if err != nil || quantite <= 0 {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Paramètre 'quantite' invalide",
+        })
+        return
+    }
+
+    lots, err := sc.stockService.SuggestFEFO(id, quantite)
+    if err != nil {
+        msg := err.Error()
+        switch {
+        case strings.HasPrefix(msg, "pièce non trouvée"):
+            c.JSON(http.StatusNotFound, gin.H{
+                "error": "Pièce non trouvée",
+                "piece_id": id,
+            })
+        case strings.HasPrefix(msg, "suivi invalide"), strings.HasPrefix(msg, "stock insuffisant"),
+            strings.HasPrefix(msg, "quantité invalide"):
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Suggestion impossible",
+                "details": msg,
+            })
+        default:
+            sc.logger.Error("Erreur lors de la suggestion FEFO", zap.String("id", id), zap.Error(err))
+            c.JSON(http.StatusInternalServerError, gin.H{
+                "error": "Erreur lors de la suggestion FEFO",
+                "details": msg,
+            })
+        }
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Lots proposés dans l'ordre FEFO",
+        "piece_id": id,
+        "data": lots,
+    })
+}
+
+// parseWithin lit un délai exprimé en jours ("30d") ou en durée Go ("12h")
+func parseWithin(value string) (time.Duration, error) {
+    if value == "" {
+        return DEFAULT_EXPIRY_WITHIN, nil
+    }
+
+    if strings.HasSuffix(value, "d") {
+        days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+        if err != nil || days < 0 {
+            return 0, fmt.Errorf("nombre de jours invalide: %s", value)
+        }
+        return time.Duration(days) * 24 * time.Hour, nil
+    }
+
+    duration, err := time.ParseDuration(value)
+    if err != nil || duration < 0 {
+        return 0, fmt.Errorf("délai invalide: %s (attendu: 30d, 12h...)", value)
+    }
+    return duration, nil
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+    "fmt"
+    "net/http"
+    "stock-service/models"
+    "strconv"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+)
+
+// ForecastConsumption prévoit la consommation d'une pièce
+// @Summary Prévision de consommation
+// @Description Construit la série mensuelle des sorties de la pièce sur les derniers mois complets et prévoit les mois suivants par moyenne mobile et par lissage exponentiel, avec pour chaque méthode les erreurs des prévisions à un mois sur l'historique (MAE, RMSE, MAPE, biais)
+// @Tags Réapprovisionnement
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "ID de la pièce"
+// @Param historique query int false "Mois complets d'historique (12 par défaut)"
+// @Param horizon query int false "Mois à prévoir, à partir du mois en cours (3 par défaut)"
+// @Param fenetre query int false "Fenêtre de la moyenne mobile, en mois (3 par défaut)"
+// @Param alpha query number false "Coefficient de lissage; optimisé sur l'historique si omis"
+// @Success 200 {object} map[string]interface{} "Prévision de consommation"
+// @Failure 400 {object} map[string]interface{} "Paramètre invalide ou historique insuffisant"
+// @Failure 404 {object} map[string]interface{} "Pièce non trouvée"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/{id}/forecast [get]
+func (sc *StockController) ForecastConsumption(c *gin.Context) {
+    id := c.Param("id")
+
+    params, err := forecastParamsFromQuery(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Paramètre invalide",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    forecast, err := sc.stockService.ForecastConsumption(id, params)
+    if err != nil {
+        msg := err.Error()
+        switch {
+        case strings.HasPrefix(msg, "pièce non trouvée"):
+            c.JSON(http.StatusNotFound, gin.H{
+                "error": "Pièce non trouvée",
+                "piece_id": id,
+            })
+        case strings.HasPrefix(msg, "prévision invalide"):
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Paramètre invalide",
+                "details": msg,
+            })
+        case strings.HasPrefix(msg, "historique insuffisant"):
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Prévision impossible",
+                "details": msg,
+            })
+        default:
+            sc.logger.Error("Erreur lors de la prévision de consommation", zap.String("id", id), zap.Error(err))
+            c.JSON(http.StatusInternalServerError, gin.H{
+                "error": "Erreur lors de la prévision de consommation",
+                "details": msg,
+            })
+        }
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Prévision de consommation calculée",
+        "data": forecast,
+    })
+}
+
+// forecastParamsFromQuery lit les paramètres de prévision
+func forecastParamsFromQuery(c *gin.Context) (models.ForecastParams, error) {
+    var params models.ForecastParams
+
+    ints := map[string]*int{
+        "historique": &params.Historique,
+        "horizon":    &params.Horizon,
+        "fenetre":    &params.Fenetre,
+    }
+    for name, target := range ints {
+        value := c.Query(name)
+        if value == "" {
+            continue
+        }
+        parsed, err := strconv.Atoi(value)
+        if err != nil || parsed < 1 {
+            return params, fmt.Errorf("%s invalide: %s", name, value)
+        }
+        *target = parsed
+    }
+
+    if value := c.Query("alpha"); value != "" {
+        alpha, err := strconv.ParseFloat(value, 64)
+        if err != nil || alpha <= 0 {
+            return params, fmt.Errorf("alpha invalide: %s", value)
+        }
+        params.Alpha = alpha
+    }
+
+    return params, nil
+}
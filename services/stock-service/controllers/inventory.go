@@ -0,0 +1,294 @@
+package controllers
+
+import (
+    "net/http"
+    "stock-service/models"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+)
+
+// OpenInventory ouvre une session d'inventaire
+// @Summary Ouvrir une session d'inventaire
+// @Description Ouvre une session de comptage physique sur une liste de pièces, une catégorie et/ou un emplacement
+// @Tags Inventaires
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param inventaire body models.OpenInventoryRequest true "Périmètre de la session"
+// @Success 201 {object} map[string]interface{} "Session ouverte"
+// @Failure 400 {object} map[string]interface{} "Données invalides"
+// @Failure 404 {object} map[string]interface{} "Pièce non trouvée"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/inventories [post]
+func (sc *StockController) OpenInventory(c *gin.Context) {
+    var req models.OpenInventoryRequest
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        sc.logger.Warn("Données invalides pour ouverture d'inventaire", zap.Error(err))
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    session, err := sc.stockService.OpenInventory(&req, currentUserID(c))
+    if err != nil {
+        sc.respondInventoryError(c, err, "Erreur lors de l'ouverture de l'inventaire")
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "message": "Session d'inventaire ouverte",
+        "data": inventoryView(c, session),
+    })
+}
+
+// GetInventories récupère les sessions d'inventaire
+// @Summary Lister les sessions d'inventaire
+// @Description Retourne les sessions d'inventaire, éventuellement filtrées par statut
+// @Tags Inventaires
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param statut query string false "Statut (ouverte, validee, annulee)"
+// @Success 200 {object} map[string]interface{} "Liste des sessions"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/inventories [get]
+func (sc *StockController) GetInventories(c *gin.Context) {
+    sessions, err := sc.stockService.GetInventories(c.Query("statut"))
+    if err != nil {
+        sc.respondInventoryError(c, err, "Erreur lors de la récupération des inventaires")
+        return
+    }
+
+    views := make([]*models.InventorySession, len(sessions))
+    for i := range sessions {
+        views[i] = inventoryView(c, &sessions[i])
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Inventaires récupérés avec succès",
+        "data": views,
+        "count": len(views),
+    })
+}
+
+// GetInventory récupère une session d'inventaire
+// @Summary Récupérer une session d'inventaire
+// @Description Retourne une session et ses lignes; en mode aveugle, les quantités attendues sont masquées aux compteurs
+// @Tags Inventaires
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param session_id path string true "ID de la session"
+// @Success 200 {object} map[string]interface{} "Détails de la session"
+// @Failure 404 {object} map[string]interface{} "Session non trouvée"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/inventories/{session_id} [get]
+func (sc *StockController) GetInventory(c *gin.Context) {
+    session, err := sc.stockService.GetInventory(c.Param("session_id"))
+    if err != nil {
+        sc.respondInventoryError(c, err, "Erreur lors de la récupération de l'inventaire")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Inventaire trouvé",
+        "data": inventoryView(c, session),
+    })
+}
+
+// SubmitInventoryCounts enregistre des comptages
+// @Summary Saisir des comptages
+// @Description Enregistre les quantités comptées pour une ou plusieurs lignes de la session
+// @Tags Inventaires
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param session_id path string true "ID de la session"
+// @Param comptages body models.InventoryCountRequest true "Quantités comptées"
+// @Success 200 {object} map[string]interface{} "Comptages enregistrés"
+// @Failure 400 {object} map[string]interface{} "Données invalides"
+// @Failure 404 {object} map[string]interface{} "Session ou ligne non trouvée"
+// @Failure 409 {object} map[string]interface{} "Session non ouverte"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/inventories/{session_id}/counts [post]
+func (sc *StockController) SubmitInventoryCounts(c *gin.Context) {
+    id := c.Param("session_id")
+    var req models.InventoryCountRequest
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        sc.logger.Warn("Données invalides pour comptage d'inventaire", zap.String("id", id), zap.Error(err))
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    session, err := sc.stockService.SubmitInventoryCounts(id, &req, currentUserID(c))
+    if err != nil {
+        sc.respondInventoryError(c, err, "Erreur lors de l'enregistrement des comptages")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Comptages enregistrés",
+        "data": inventoryView(c, session),
+    })
+}
+
+// ValidateInventory valide une session d'inventaire
+// @Summary Valider une session d'inventaire
+// @Description Clôture la session et passe les mouvements d'ajustement (motif "inventaire"); refusée si un manquant laisse des réservations sans stock; réservé aux managers
+// @Tags Inventaires
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param session_id path string true "ID de la session"
+// @Success 200 {object} map[string]interface{} "Session validée"
+// @Failure 400 {object} map[string]interface{} "Session incomplète"
+// @Failure 403 {object} map[string]interface{} "Permissions insuffisantes"
+// @Failure 404 {object} map[string]interface{} "Session non trouvée"
+// @Failure 409 {object} map[string]interface{} "Session non ouverte, réservations non couvertes ou écart inapplicable"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/inventories/{session_id}/validate [post]
+func (sc *StockController) ValidateInventory(c *gin.Context) {
+    id := c.Param("session_id")
+
+    session, err := sc.stockService.ValidateInventory(id, currentUserID(c))
+    if err != nil {
+        sc.respondInventoryError(c, err, "Erreur lors de la validation de l'inventaire")
+        return
+    }
+
+    report, err := sc.stockService.GetInventoryReport(id)
+    if err != nil {
+        sc.respondInventoryError(c, err, "Erreur lors du calcul du rapport d'écarts")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Inventaire validé avec succès",
+        "data": session,
+        "rapport": report,
+    })
+}
+
+// CancelInventory annule une session d'inventaire
+// @Summary Annuler une session d'inventaire
+// @Description Annule une session ouverte sans modifier le stock; réservé aux managers
+// @Tags Inventaires
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param session_id path string true "ID de la session"
+// @Success 200 {object} map[string]interface{} "Session annulée"
+// @Failure 403 {object} map[string]interface{} "Permissions insuffisantes"
+// @Failure 404 {object} map[string]interface{} "Session non trouvée"
+// @Failure 409 {object} map[string]interface{} "Session non ouverte"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/inventories/{session_id}/cancel [post]
+func (sc *StockController) CancelInventory(c *gin.Context) {
+    session, err := sc.stockService.CancelInventory(c.Param("session_id"), currentUserID(c))
+    if err != nil {
+        sc.respondInventoryError(c, err, "Erreur lors de l'annulation de l'inventaire")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Inventaire annulé",
+        "data": session,
+    })
+}
+
+// GetInventoryReport récupère le rapport d'écarts d'une session
+// @Summary Rapport d'écarts d'inventaire
+// @Description Retourne les écarts entre quantités attendues et comptées, valorisés selon la méthode de valorisation configurée; réservé aux managers
+// @Tags Inventaires
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param session_id path string true "ID de la session"
+// @Success 200 {object} map[string]interface{} "Rapport d'écarts"
+// @Failure 403 {object} map[string]interface{} "Permissions insuffisantes"
+// @Failure 404 {object} map[string]interface{} "Session non trouvée"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/inventories/{session_id}/report [get]
+func (sc *StockController) GetInventoryReport(c *gin.Context) {
+    report, err := sc.stockService.GetInventoryReport(c.Param("session_id"))
+    if err != nil {
+        sc.respondInventoryError(c, err, "Erreur lors du calcul du rapport d'écarts")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Rapport d'écarts calculé",
+        "data": report,
+    })
+}
+
+// inventoryView masque les quantités attendues d'une session en aveugle
+// pour les utilisateurs qui ne sont ni manager ni admin
+func inventoryView(c *gin.Context, session *models.InventorySession) *models.InventorySession {
+    if session.Aveugle && !hasRole(c, "admin", "manager") {
+        return session.Blind()
+    }
+    return session
+}
+
+// hasRole vérifie si l'utilisateur courant possède l'un des rôles donnés
+func hasRole(c *gin.Context, roles ...string) bool {
+    role, _ := c.Get("role")
+    roleStr, _ := role.(string)
+    for _, allowed := range roles {
+        if roleStr == allowed {
+            return true
+        }
+    }
+    return false
+}
+
+// respondInventoryError traduit les erreurs d'inventaire en réponse HTTP
+func (sc *StockController) respondInventoryError(c *gin.Context, err error, message string) {
+    msg := err.Error()
+
+    switch {
+    case strings.HasPrefix(msg, "pièce non trouvée"),
+        strings.HasPrefix(msg, "inventaire non trouvé"),
+        strings.HasPrefix(msg, "ligne d'inventaire non trouvée"):
+        c.JSON(http.StatusNotFound, gin.H{
+            "error": "Ressource non trouvée",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "inventaire non ouvert"):
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "Inventaire non ouvert",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "réservations non couvertes"),
+        strings.HasPrefix(msg, "écart inapplicable"):
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "Ajustement impossible",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "périmètre d'inventaire vide"),
+        strings.HasPrefix(msg, "inventaire incomplet"),
+        strings.HasPrefix(msg, "suivi invalide"),
+        strings.HasPrefix(msg, "quantité invalide"),
+        strings.HasPrefix(msg, "emplacement requis"):
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": msg,
+        })
+    default:
+        sc.logger.Error(message, zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": message,
+            "details": msg,
+        })
+    }
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+)
+
+// GetStockKPIs calcule les indicateurs de stock
+// @Summary Indicateurs de stock
+// @Description Calcule sur la période, au global et par catégorie, la rotation du stock (coût des sorties rapporté à la valeur moyenne du stock), la couverture en jours du stock de fin de période et le taux de service (part des demandes de sortie servies sans refus pour stock insuffisant), depuis l'historique des mouvements. Sans dates, la période couvre l'année écoulée.
+// @Tags Indicateurs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "Début de la période (RFC3339 ou AAAA-MM-JJ)"
+// @Param to query string false "Fin de la période (RFC3339 ou AAAA-MM-JJ), au plus tard maintenant"
+// @Param categorie query string false "Catégorie de pièce"
+// @Param emplacement query string false "Emplacement de stockage"
+// @Param classe_abc query string false "Classes ABC, séparées par des virgules"
+// @Success 200 {object} map[string]interface{} "Indicateurs de stock"
+// @Failure 400 {object} map[string]interface{} "Paramètre invalide"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/kpis [get]
+func (sc *StockController) GetStockKPIs(c *gin.Context) {
+    var from, to *time.Time
+    if value := c.Query("from"); value != "" {
+        t, err := parseDateParam(value, false)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Paramètre invalide",
+                "details": "paramètre 'from' invalide: " + value,
+            })
+            return
+        }
+        from = &t
+    }
+    if value := c.Query("to"); value != "" {
+        t, err := parseDateParam(value, true)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Paramètre invalide",
+                "details": "paramètre 'to' invalide: " + value,
+            })
+            return
+        }
+        to = &t
+    }
+
+    kpis, err := sc.stockService.GetStockKPIs(from, to, pieceFilterFromQuery(c))
+    if err != nil {
+        if strings.HasPrefix(err.Error(), "période invalide") {
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Paramètre invalide",
+                "details": err.Error(),
+            })
+            return
+        }
+
+        sc.logger.Error("Erreur lors du calcul des indicateurs de stock", zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "Erreur lors du calcul des indicateurs de stock",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Indicateurs de stock calculés",
+        "data": kpis,
+    })
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+    "net/http"
+    "stock-service/models"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+)
+
+// TransferStock transfère des unités d'une pièce entre deux emplacements
+// @Summary Transférer du stock entre emplacements
+// @Description Déplace atomiquement des unités d'une pièce d'un emplacement vers un autre
+// @Tags Stock
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "ID de la pièce"
+// @Param transfert body models.TransferRequest true "Données du transfert"
+// @Success 200 {object} map[string]interface{} "Stock transféré"
+// @Failure 400 {object} map[string]interface{} "Données invalides ou stock insuffisant"
+// @Failure 404 {object} map[string]interface{} "Pièce non trouvée"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/{id}/transfer [post]
+func (sc *StockController) TransferStock(c *gin.Context) {
+    id := c.Param("id")
+    var req models.TransferRequest
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        sc.logger.Warn("Données invalides pour transfert de stock", zap.String("id", id), zap.Error(err))
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    piece, movement, err := sc.stockService.TransferStock(id, &req, currentUserID(c))
+    if err != nil {
+        if err.Error() == "pièce non trouvée: "+id {
+            c.JSON(http.StatusNotFound, gin.H{
+                "error": "Pièce non trouvée",
+                "piece_id": id,
+            })
+            return
+        }
+
+        if strings.HasPrefix(err.Error(), "stock insuffisant") || strings.HasPrefix(err.Error(), "emplacements identiques") ||
+            strings.HasPrefix(err.Error(), "quantité invalide") {
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Transfert impossible",
+                "details": err.Error(),
+            })
+            return
+        }
+
+        sc.logger.Error("Erreur lors du transfert de stock", zap.String("id", id), zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "Erreur lors du transfert de stock",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Stock transféré avec succès",
+        "data": piece,
+        "mouvement": movement,
+    })
+}
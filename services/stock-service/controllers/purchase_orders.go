@@ -0,0 +1,257 @@
+package controllers
+
+import (
+    "net/http"
+    "stock-service/models"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+)
+
+// GeneratePurchaseOrders génère des commandes depuis les alertes
+// @Summary Générer des commandes fournisseur
+// @Description Crée des commandes en brouillon, une par fournisseur préféré, pour les pièces en stock faible, déduction faite des quantités déjà en commande
+// @Tags Commandes fournisseur
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param emplacement query string false "Emplacement de stockage"
+// @Success 201 {object} map[string]interface{} "Commandes générées"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/purchase-orders/generate [post]
+func (sc *StockController) GeneratePurchaseOrders(c *gin.Context) {
+    orders, err := sc.stockService.GeneratePurchaseOrders(pieceFilterFromQuery(c), currentUserID(c))
+    if err != nil {
+        sc.respondPurchaseOrderError(c, err, "Erreur lors de la génération des commandes")
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "message": "Commandes fournisseur générées",
+        "data": orders,
+        "count": len(orders),
+    })
+}
+
+// GetPurchaseOrders récupère les commandes fournisseur
+// @Summary Lister les commandes fournisseur
+// @Description Retourne les commandes fournisseur, éventuellement filtrées par statut et par fournisseur
+// @Tags Commandes fournisseur
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param statut query string false "Statut (brouillon, envoyee, partiellement_recue, recue, annulee)"
+// @Param fournisseur query string false "ID ou nom du fournisseur"
+// @Success 200 {object} map[string]interface{} "Liste des commandes"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/purchase-orders [get]
+func (sc *StockController) GetPurchaseOrders(c *gin.Context) {
+    orders, err := sc.stockService.GetPurchaseOrders(c.Query("statut"), c.Query("fournisseur"))
+    if err != nil {
+        sc.respondPurchaseOrderError(c, err, "Erreur lors de la récupération des commandes")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Commandes récupérées avec succès",
+        "data": orders,
+        "count": len(orders),
+    })
+}
+
+// GetPurchaseOrder récupère une commande fournisseur
+// @Summary Récupérer une commande fournisseur
+// @Description Retourne une commande, ses lignes et les quantités déjà reçues
+// @Tags Commandes fournisseur
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param order_id path string true "ID de la commande"
+// @Success 200 {object} map[string]interface{} "Détails de la commande"
+// @Failure 404 {object} map[string]interface{} "Commande non trouvée"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/purchase-orders/{order_id} [get]
+func (sc *StockController) GetPurchaseOrder(c *gin.Context) {
+    order, err := sc.stockService.GetPurchaseOrder(c.Param("order_id"))
+    if err != nil {
+        sc.respondPurchaseOrderError(c, err, "Erreur lors de la récupération de la commande")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Commande trouvée",
+        "data": order,
+    })
+}
+
+// UpdatePurchaseOrder modifie une commande en brouillon
+// @Summary Modifier une commande fournisseur
+// @Description Modifie le fournisseur, les notes ou les lignes d'une commande en brouillon; les lignes fournies remplacent les lignes existantes
+// @Tags Commandes fournisseur
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param order_id path string true "ID de la commande"
+// @Param commande body models.UpdatePurchaseOrderRequest true "Modifications"
+// @Success 200 {object} map[string]interface{} "Commande modifiée"
+// @Failure 400 {object} map[string]interface{} "Données invalides"
+// @Failure 404 {object} map[string]interface{} "Commande ou pièce non trouvée"
+// @Failure 409 {object} map[string]interface{} "Commande non modifiable"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/purchase-orders/{order_id} [put]
+func (sc *StockController) UpdatePurchaseOrder(c *gin.Context) {
+    var req models.UpdatePurchaseOrderRequest
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        sc.logger.Warn("Données invalides pour modification de commande", zap.Error(err))
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    order, err := sc.stockService.UpdatePurchaseOrder(c.Param("order_id"), &req, currentUserID(c))
+    if err != nil {
+        sc.respondPurchaseOrderError(c, err, "Erreur lors de la modification de la commande")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Commande modifiée avec succès",
+        "data": order,
+    })
+}
+
+// SendPurchaseOrder envoie une commande
+// @Summary Envoyer une commande fournisseur
+// @Description Passe une commande en brouillon au statut envoyée; ses quantités apparaissent alors comme en commande dans les alertes
+// @Tags Commandes fournisseur
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param order_id path string true "ID de la commande"
+// @Success 200 {object} map[string]interface{} "Commande envoyée"
+// @Failure 400 {object} map[string]interface{} "Commande incomplète"
+// @Failure 403 {object} map[string]interface{} "Rôle insuffisant"
+// @Failure 404 {object} map[string]interface{} "Commande non trouvée"
+// @Failure 409 {object} map[string]interface{} "Commande non modifiable"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/purchase-orders/{order_id}/send [post]
+func (sc *StockController) SendPurchaseOrder(c *gin.Context) {
+    order, err := sc.stockService.SendPurchaseOrder(c.Param("order_id"), currentUserID(c))
+    if err != nil {
+        sc.respondPurchaseOrderError(c, err, "Erreur lors de l'envoi de la commande")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Commande envoyée",
+        "data": order,
+    })
+}
+
+// CancelPurchaseOrder annule une commande
+// @Summary Annuler une commande fournisseur
+// @Description Annule une commande non soldée; les quantités déjà reçues restent en stock
+// @Tags Commandes fournisseur
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param order_id path string true "ID de la commande"
+// @Success 200 {object} map[string]interface{} "Commande annulée"
+// @Failure 403 {object} map[string]interface{} "Rôle insuffisant"
+// @Failure 404 {object} map[string]interface{} "Commande non trouvée"
+// @Failure 409 {object} map[string]interface{} "Commande non modifiable"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/purchase-orders/{order_id}/cancel [post]
+func (sc *StockController) CancelPurchaseOrder(c *gin.Context) {
+    order, err := sc.stockService.CancelPurchaseOrder(c.Param("order_id"), currentUserID(c))
+    if err != nil {
+        sc.respondPurchaseOrderError(c, err, "Erreur lors de l'annulation de la commande")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Commande annulée",
+        "data": order,
+    })
+}
+
+// ReceivePurchaseOrder enregistre une réception de commande
+// @Summary Réceptionner une commande fournisseur
+// @Description Enregistre une livraison totale ou partielle: chaque ligne reçue passe une entrée de stock valorisée au prix de la commande
+// @Tags Commandes fournisseur
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param order_id path string true "ID de la commande"
+// @Param reception body models.ReceivePurchaseOrderRequest true "Lignes reçues"
+// @Param Idempotency-Key header string false "Clé d'idempotence: une répétition avec la même clé renvoie la première réponse sans nouvel effet"
+// @Success 200 {object} map[string]interface{} "Réception enregistrée"
+// @Failure 400 {object} map[string]interface{} "Données invalides"
+// @Failure 404 {object} map[string]interface{} "Commande, ligne ou pièce non trouvée"
+// @Failure 409 {object} map[string]interface{} "Commande non ouverte"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/purchase-orders/{order_id}/receipts [post]
+func (sc *StockController) ReceivePurchaseOrder(c *gin.Context) {
+    var req models.ReceivePurchaseOrderRequest
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        sc.logger.Warn("Données invalides pour réception de commande", zap.Error(err))
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    result, err := sc.stockService.ReceivePurchaseOrder(c.Param("order_id"), &req, currentUserID(c))
+    if err != nil {
+        sc.respondPurchaseOrderError(c, err, "Erreur lors de la réception de la commande")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Réception enregistrée",
+        "data": result,
+    })
+}
+
+// respondPurchaseOrderError traduit les erreurs de commande en réponse HTTP
+func (sc *StockController) respondPurchaseOrderError(c *gin.Context, err error, message string) {
+    msg := err.Error()
+
+    switch {
+    case strings.HasPrefix(msg, "pièce non trouvée"),
+        strings.HasPrefix(msg, "commande non trouvée"),
+        strings.HasPrefix(msg, "ligne de commande non trouvée"):
+        c.JSON(http.StatusNotFound, gin.H{
+            "error": "Ressource non trouvée",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "commande non modifiable"),
+        strings.HasPrefix(msg, "commande non ouverte"):
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "Statut de commande incompatible",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "commande invalide"),
+        strings.HasPrefix(msg, "réception invalide"),
+        strings.HasPrefix(msg, "suivi invalide"),
+        strings.HasPrefix(msg, "quantité invalide"),
+        strings.HasPrefix(msg, "devise invalide"),
+        strings.HasPrefix(msg, "fournisseur invalide"):
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": msg,
+        })
+    default:
+        sc.logger.Error(message, zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": message,
+            "details": msg,
+        })
+    }
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+    "fmt"
+    "net/http"
+    "stock-service/models"
+    "strconv"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+)
+
+// GetReorderPoints calcule les points de commande recommandés
+// @Summary Points de commande recommandés
+// @Description Calcule, pour chaque pièce, le stock de sécurité et le point de commande à partir de la consommation observée (moyenne et écart type journaliers), du délai de livraison du fournisseur préféré et du niveau de service visé, et les compare au seuil minimum actuel
+// @Tags Réapprovisionnement
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param niveau_service query number false "Niveau de service visé (ex: 0.95), configuration par défaut"
+// @Param periode_jours query int false "Historique de consommation analysé, en jours"
+// @Param emplacement query string false "Emplacement de stockage"
+// @Param fournisseur_id query string false "ID du fournisseur"
+// @Success 200 {object} map[string]interface{} "Points de commande recommandés"
+// @Failure 400 {object} map[string]interface{} "Paramètre invalide"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/reorder-points [get]
+func (sc *StockController) GetReorderPoints(c *gin.Context) {
+    params, err := reorderParamsFromQuery(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Paramètre invalide",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    points, err := sc.stockService.GetReorderPoints(pieceFilterFromQuery(c), params)
+    if err != nil {
+        sc.respondReorderError(c, err, "Erreur lors du calcul des points de commande")
+        return
+    }
+
+    aRevoir := 0
+    for _, point := range points {
+        if point.Fiable && point.Ecart != 0 {
+            aRevoir++
+        }
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Points de commande calculés",
+        "data": points,
+        "count": len(points),
+        "a_revoir": aRevoir,
+    })
+}
+
+// ApplyReorderPoints applique les points de commande recommandés
+// @Summary Appliquer les points de commande
+// @Description Remplace le seuil minimum des pièces par leur point de commande recommandé; les pièces sans historique suffisant, ou dont le nouveau seuil serait refusé, sont ignorées avec leur motif. Sans liste de pièces, toutes les pièces du filtre sont concernées.
+// @Tags Réapprovisionnement
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param emplacement query string false "Emplacement de stockage"
+// @Param fournisseur_id query string false "ID du fournisseur"
+// @Param application body models.ApplyReorderPointsRequest true "Pièces et paramètres du calcul"
+// @Success 200 {object} map[string]interface{} "Seuils mis à jour"
+// @Failure 400 {object} map[string]interface{} "Données invalides"
+// @Failure 403 {object} map[string]interface{} "Rôle insuffisant"
+// @Failure 404 {object} map[string]interface{} "Pièce non trouvée"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/reorder-points/apply [post]
+func (sc *StockController) ApplyReorderPoints(c *gin.Context) {
+    var req models.ApplyReorderPointsRequest
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        sc.logger.Warn("Données invalides pour application des points de commande", zap.Error(err))
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    result, err := sc.stockService.ApplyReorderPoints(pieceFilterFromQuery(c), &req, currentUserID(c))
+    if err != nil {
+        sc.respondReorderError(c, err, "Erreur lors de l'application des points de commande")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Points de commande appliqués",
+        "data": result,
+        "appliques": len(result.Appliques),
+        "ignores": len(result.Ignores),
+    })
+}
+
+// reorderParamsFromQuery lit le niveau de service et la période d'historique
+func reorderParamsFromQuery(c *gin.Context) (models.ReorderPointParams, error) {
+    var params models.ReorderPointParams
+
+    if value := c.Query("niveau_service"); value != "" {
+        level, err := strconv.ParseFloat(value, 64)
+        if err != nil {
+            return params, fmt.Errorf("niveau de service invalide: %s", value)
+        }
+        params.NiveauService = level
+    }
+
+    if value := c.Query("periode_jours"); value != "" {
+        days, err := strconv.Atoi(value)
+        if err != nil || days < 1 {
+            return params, fmt.Errorf("période invalide: %s", value)
+        }
+        params.PeriodeJours = days
+    }
+
+    return params, nil
+}
+
+// respondReorderError traduit les erreurs du calcul des points de commande en
+// réponse HTTP
+func (sc *StockController) respondReorderError(c *gin.Context, err error, message string) {
+    msg := err.Error()
+
+    switch {
+    case strings.HasPrefix(msg, "pièce non trouvée"):
+        c.JSON(http.StatusNotFound, gin.H{
+            "error": "Pièce non trouvée",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "niveau de service invalide"), strings.HasPrefix(msg, "période invalide"):
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Paramètre invalide",
+            "details": msg,
+        })
+    default:
+        sc.logger.Error(message, zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": message,
+            "details": msg,
+        })
+    }
+}
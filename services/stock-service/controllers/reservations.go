@@ -0,0 +1,265 @@
+package controllers
+
+import (
+    "net/http"
+    "stock-service/models"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+)
+
+// ReserveStock réserve des unités d'une pièce pour une intervention
+// @Summary Réserver du stock
+// @Description Réserve des unités disponibles d'une pièce pour une intervention planifiée
+// @Tags Réservations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "ID de la pièce"
+// @Param reservation body models.ReservationRequest true "Données de la réservation"
+// @Success 201 {object} map[string]interface{} "Réservation créée"
+// @Failure 400 {object} map[string]interface{} "Données invalides ou stock insuffisant"
+// @Failure 404 {object} map[string]interface{} "Pièce non trouvée"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/{id}/reservations [post]
+func (sc *StockController) ReserveStock(c *gin.Context) {
+    id := c.Param("id")
+    var req models.ReservationRequest
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        sc.logger.Warn("Données invalides pour réservation de stock", zap.String("id", id), zap.Error(err))
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    reservation, piece, err := sc.stockService.ReserveStock(id, &req, currentUserID(c))
+    if err != nil {
+        sc.respondReservationError(c, err, "Erreur lors de la réservation du stock")
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "message": "Stock réservé avec succès",
+        "data": reservation,
+        "piece": piece,
+    })
+}
+
+// GetPieceReservations récupère les réservations d'une pièce
+// @Summary Réservations d'une pièce
+// @Description Retourne les réservations d'une pièce, éventuellement filtrées par statut
+// @Tags Réservations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "ID de la pièce"
+// @Param statut query string false "Statut (active, consommee, liberee)"
+// @Success 200 {object} map[string]interface{} "Liste des réservations"
+// @Failure 404 {object} map[string]interface{} "Pièce non trouvée"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/{id}/reservations [get]
+func (sc *StockController) GetPieceReservations(c *gin.Context) {
+    id := c.Param("id")
+
+    reservations, err := sc.stockService.GetPieceReservations(id, c.Query("statut"))
+    if err != nil {
+        sc.respondReservationError(c, err, "Erreur lors de la récupération des réservations")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Réservations récupérées avec succès",
+        "piece_id": id,
+        "data": reservations,
+        "count": len(reservations),
+    })
+}
+
+// GetInterventionReservations récupère les réservations d'une intervention
+// @Summary Réservations d'une intervention
+// @Description Retourne les réservations de stock rattachées à une intervention
+// @Tags Réservations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param intervention_id query string true "ID de l'intervention"
+// @Param statut query string false "Statut (active, consommee, liberee)"
+// @Success 200 {object} map[string]interface{} "Liste des réservations"
+// @Failure 400 {object} map[string]interface{} "Paramètre manquant"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/reservations [get]
+func (sc *StockController) GetInterventionReservations(c *gin.Context) {
+    interventionID := c.Query("intervention_id")
+    if interventionID == "" {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Paramètre 'intervention_id' requis",
+        })
+        return
+    }
+
+    reservations, err := sc.stockService.GetInterventionReservations(interventionID, c.Query("statut"))
+    if err != nil {
+        sc.respondReservationError(c, err, "Erreur lors de la récupération des réservations")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Réservations récupérées avec succès",
+        "intervention_id": interventionID,
+        "data": reservations,
+        "count": len(reservations),
+    })
+}
+
+// GetReservation récupère une réservation par ID
+// @Summary Récupérer une réservation
+// @Description Retourne le détail d'une réservation de stock
+// @Tags Réservations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param reservation_id path string true "ID de la réservation"
+// @Success 200 {object} map[string]interface{} "Détails de la réservation"
+// @Failure 404 {object} map[string]interface{} "Réservation non trouvée"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/reservations/{reservation_id} [get]
+func (sc *StockController) GetReservation(c *gin.Context) {
+    reservation, err := sc.stockService.GetReservation(c.Param("reservation_id"))
+    if err != nil {
+        sc.respondReservationError(c, err, "Erreur lors de la récupération de la réservation")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Réservation trouvée",
+        "data": reservation,
+    })
+}
+
+// ReleaseReservation libère une réservation
+// @Summary Libérer une réservation
+// @Description Annule une réservation active et rend les unités disponibles
+// @Tags Réservations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param reservation_id path string true "ID de la réservation"
+// @Success 200 {object} map[string]interface{} "Réservation libérée"
+// @Failure 404 {object} map[string]interface{} "Réservation non trouvée"
+// @Failure 409 {object} map[string]interface{} "Réservation non active"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/reservations/{reservation_id}/release [post]
+func (sc *StockController) ReleaseReservation(c *gin.Context) {
+    reservation, piece, err := sc.stockService.ReleaseReservation(c.Param("reservation_id"))
+    if err != nil {
+        sc.respondReservationError(c, err, "Erreur lors de la libération de la réservation")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Réservation libérée avec succès",
+        "data": reservation,
+        "piece": piece,
+    })
+}
+
+// ConsumeReservation consomme une réservation
+// @Summary Consommer une réservation
+// @Description Transforme une réservation en sortie de stock; une quantité inférieure libère le reliquat
+// @Tags Réservations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param reservation_id path string true "ID de la réservation"
+// @Param consommation body models.ConsumeReservationRequest false "Quantité réellement consommée"
+// @Success 200 {object} map[string]interface{} "Réservation consommée"
+// @Failure 400 {object} map[string]interface{} "Données invalides"
+// @Failure 404 {object} map[string]interface{} "Réservation non trouvée"
+// @Failure 409 {object} map[string]interface{} "Réservation non active"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/reservations/{reservation_id}/consume [post]
+func (sc *StockController) ConsumeReservation(c *gin.Context) {
+    id := c.Param("reservation_id")
+    var req models.ConsumeReservationRequest
+
+    if c.Request.ContentLength != 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            sc.logger.Warn("Données invalides pour consommation de réservation", zap.String("id", id), zap.Error(err))
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Données invalides",
+                "details": err.Error(),
+            })
+            return
+        }
+    }
+
+    reservation, piece, err := sc.stockService.ConsumeReservation(id, &req, currentUserID(c))
+    if err != nil {
+        sc.respondReservationError(c, err, "Erreur lors de la consommation de la réservation")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Réservation consommée avec succès",
+        "data": reservation,
+        "piece": piece,
+        "mouvement": gin.H{
+            "type": models.MOVEMENT_TYPE_DECREMENT,
+            "quantite": reservation.QuantiteConsommee,
+            "reservation_id": reservation.ID,
+        },
+    })
+}
+
+// respondReservationError traduit les erreurs du service de réservation en réponse HTTP
+func (sc *StockController) respondReservationError(c *gin.Context, err error, message string) {
+    msg := err.Error()
+
+    switch {
+    case strings.HasPrefix(msg, "pièce non trouvée"):
+        c.JSON(http.StatusNotFound, gin.H{
+            "error": "Pièce non trouvée",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "réservation non trouvée"):
+        c.JSON(http.StatusNotFound, gin.H{
+            "error": "Réservation non trouvée",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "réservation non active"):
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "Réservation non active",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "suivi invalide"), strings.HasPrefix(msg, "quantité invalide"):
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "FEFO non respecté"):
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "FEFO non respecté",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "stock insuffisant"):
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Stock insuffisant",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "quantité supérieure à la réservation"):
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Quantité invalide",
+            "details": msg,
+        })
+    default:
+        sc.logger.Error(message, zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": message,
+            "details": msg,
+        })
+    }
+}
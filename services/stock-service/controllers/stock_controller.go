@@ -1,9 +1,13 @@
 package controllers
 
 import (
+    "fmt"
     "net/http"
     "stock-service/models"
     "stock-service/services"
+    "strconv"
+    "strings"
+    "time"
 
     "github.com/gin-gonic/gin"
     "go.uber.org/zap"
@@ -23,16 +27,21 @@ func NewStockController(stockService *services.StockService, logger *zap.Logger)
 
 // GetAllPieces récupère toutes les pièces en stock
 // @Summary Récupérer toutes les pièces
-// @Description Retourne la liste complète des pièces détachées en stock
+// @Description Retourne la liste des pièces détachées en stock, éventuellement filtrée par catégorie, par emplacement, par fournisseur ou par classe ABC/XYZ
 // @Tags Stock
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param categorie query string false "Catégorie de pièce"
+// @Param emplacement query string false "Emplacement de stockage"
+// @Param fournisseur_id query string false "ID d'un fournisseur de la pièce"
+// @Param classe_abc query string false "Classes ABC, séparées par des virgules (ex: A,B)"
+// @Param classe_xyz query string false "Classes XYZ, séparées par des virgules (ex: X)"
 // @Success 200 {object} map[string]interface{} "Liste des pièces"
 // @Failure 500 {object} map[string]interface{} "Erreur interne"
 // @Router /stock [get]
 func (sc *StockController) GetAllPieces(c *gin.Context) {
-    pieces, err := sc.stockService.GetAllPieces()
+    pieces, err := sc.stockService.ListPieces(pieceFilterFromQuery(c))
     if err != nil {
         sc.logger.Error("Erreur lors de la récupération des pièces", zap.Error(err))
         c.JSON(http.StatusInternalServerError, gin.H{
@@ -57,8 +66,11 @@ func (sc *StockController) GetAllPieces(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param piece body models.CreatePieceRequest true "Données de la pièce"
+// @Param Idempotency-Key header string false "Clé d'idempotence: une répétition avec la même clé renvoie la première réponse sans nouvel effet"
 // @Success 201 {object} map[string]interface{} "Pièce créée"
 // @Failure 400 {object} map[string]interface{} "Données invalides"
+// @Failure 409 {object} map[string]interface{} "Requête idempotente en cours de traitement"
+// @Failure 422 {object} map[string]interface{} "Clé d'idempotence réutilisée pour une autre requête"
 // @Failure 500 {object} map[string]interface{} "Erreur interne"
 // @Router /stock [post]
 func (sc *StockController) CreatePiece(c *gin.Context) {
@@ -73,21 +85,46 @@ func (sc *StockController) CreatePiece(c *gin.Context) {
         return
     }
 
+    if req.DatePeremption != nil && req.Suivi == "" {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": "suivi invalide: une date de péremption requiert un suivi par lot ou par numéro de série",
+        })
+        return
+    }
+
     // Conversion vers le modèle Piece
     piece := &models.Piece{
-        Nom:          req.Nom,
-        Description:  req.Description,
-        Quantite:     req.Quantite,
-        SeuilMin:     req.SeuilMin,
-        PrixUnitaire: req.PrixUnitaire,
-        Fournisseur:  req.Fournisseur,
-        Emplacement:  req.Emplacement,
-        CodeEAN:      req.CodeEAN,
-        Categorie:    req.Categorie,
-        UniteStock:   req.UniteStock,
-    }
-
-    if err := sc.stockService.CreatePiece(piece); err != nil {
+        Nom:              req.Nom,
+        Description:      req.Description,
+        Quantite:         req.Quantite,
+        SeuilMin:         req.SeuilMin,
+        PrixUnitaire:     req.PrixUnitaire,
+        Devise:           req.Devise,
+        Fournisseur:      req.Fournisseur,
+        Fournisseurs:     req.Fournisseurs,
+        PolitiqueReappro: req.PolitiqueReappro,
+        Emplacement:      req.Emplacement,
+        Emplacements:     req.Emplacements,
+        Suivi:            req.Suivi,
+        Lots:             models.BuildLots(req.Lots, req.NumerosSerie, req.DatePeremption, time.Now()),
+        CodeEAN:          req.CodeEAN,
+        Categorie:        req.Categorie,
+        UniteStock:       req.UniteStock,
+        Conversions:      req.Conversions,
+    }
+
+    if err := sc.stockService.CreatePiece(piece, currentUserID(c)); err != nil {
+        if strings.HasPrefix(err.Error(), "suivi invalide") || strings.HasPrefix(err.Error(), "quantité invalide") ||
+            strings.HasPrefix(err.Error(), "devise invalide") || strings.HasPrefix(err.Error(), "fournisseur invalide") ||
+            strings.HasPrefix(err.Error(), "politique invalide") {
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Données invalides",
+                "details": err.Error(),
+            })
+            return
+        }
+
         sc.logger.Error("Erreur lors de la création de la pièce", zap.Error(err))
         c.JSON(http.StatusInternalServerError, gin.H{
             "error": "Erreur lors de la création de la pièce",
@@ -143,7 +180,7 @@ func (sc *StockController) GetPiece(c *gin.Context) {
 
 // UpdatePiece met à jour une pièce existante
 // @Summary Mettre à jour une pièce
-// @Description Met à jour les informations d'une pièce existante
+// @Description Met à jour les informations d'une pièce existante; l'unité de stock ne peut changer qu'avec un stock et des réservations nuls
 // @Tags Stock
 // @Accept json
 // @Produce json
@@ -178,6 +215,16 @@ func (sc *StockController) UpdatePiece(c *gin.Context) {
             return
         }
 
+        if strings.HasPrefix(err.Error(), "suivi invalide") || strings.HasPrefix(err.Error(), "quantité invalide") ||
+            strings.HasPrefix(err.Error(), "devise invalide") || strings.HasPrefix(err.Error(), "fournisseur invalide") ||
+            strings.HasPrefix(err.Error(), "politique invalide") || strings.HasPrefix(err.Error(), "unité invalide") {
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Données invalides",
+                "details": err.Error(),
+            })
+            return
+        }
+
         sc.logger.Error("Erreur lors de la mise à jour de la pièce", zap.String("id", id), zap.Error(err))
         c.JSON(http.StatusInternalServerError, gin.H{
             "error": "Erreur lors de la mise à jour de la pièce",
@@ -194,7 +241,7 @@ func (sc *StockController) UpdatePiece(c *gin.Context) {
 
 // DeletePiece supprime une pièce
 // @Summary Supprimer une pièce
-// @Description Supprime une pièce du stock
+// @Description Supprime une pièce du stock; une pièce dont une partie du stock est réservée ne peut être supprimée qu'après la libération ou la consommation de ses réservations
 // @Tags Stock
 // @Accept json
 // @Produce json
@@ -202,6 +249,7 @@ func (sc *StockController) UpdatePiece(c *gin.Context) {
 // @Param id path string true "ID de la pièce"
 // @Success 204 "Pièce supprimée"
 // @Failure 404 {object} map[string]interface{} "Pièce non trouvée"
+// @Failure 409 {object} map[string]interface{} "Stock réservé"
 // @Failure 500 {object} map[string]interface{} "Erreur interne"
 // @Router /stock/{id} [delete]
 func (sc *StockController) DeletePiece(c *gin.Context) {
@@ -215,6 +263,13 @@ func (sc *StockController) DeletePiece(c *gin.Context) {
             })
             return
         }
+        if strings.HasPrefix(err.Error(), "suppression impossible") {
+            c.JSON(http.StatusConflict, gin.H{
+                "error": "Pièce réservée",
+                "details": err.Error(),
+            })
+            return
+        }
 
         sc.logger.Error("Erreur lors de la suppression de la pièce", zap.String("id", id), zap.Error(err))
         c.JSON(http.StatusInternalServerError, gin.H{
@@ -229,16 +284,19 @@ func (sc *StockController) DeletePiece(c *gin.Context) {
 
 // IncrementStock augmente la quantité d'une pièce
 // @Summary Incrémenter le stock
-// @Description Augmente la quantité en stock d'une pièce
+// @Description Augmente la quantité en stock d'une pièce; un coût unitaire de réception met à jour le prix moyen pondéré
 // @Tags Stock
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "ID de la pièce"
 // @Param movement body models.StockMovementRequest true "Données du mouvement"
+// @Param Idempotency-Key header string false "Clé d'idempotence: une répétition avec la même clé renvoie la première réponse sans nouvel effet"
 // @Success 200 {object} map[string]interface{} "Stock incrémenté"
 // @Failure 400 {object} map[string]interface{} "Données invalides"
 // @Failure 404 {object} map[string]interface{} "Pièce non trouvée"
+// @Failure 409 {object} map[string]interface{} "Requête idempotente en cours de traitement"
+// @Failure 422 {object} map[string]interface{} "Clé d'idempotence réutilisée pour une autre requête"
 // @Failure 500 {object} map[string]interface{} "Erreur interne"
 // @Router /stock/{id}/increment [post]
 func (sc *StockController) IncrementStock(c *gin.Context) {
@@ -254,7 +312,7 @@ func (sc *StockController) IncrementStock(c *gin.Context) {
         return
     }
 
-    piece, err := sc.stockService.IncrementStock(id, req.Quantite, req.Motif)
+    piece, err := sc.stockService.IncrementStock(id, &req, currentUserID(c))
     if err != nil {
         if err.Error() == "pièce non trouvée: "+id {
             c.JSON(http.StatusNotFound, gin.H{
@@ -264,6 +322,15 @@ func (sc *StockController) IncrementStock(c *gin.Context) {
             return
         }
 
+        if strings.HasPrefix(err.Error(), "suivi invalide") || strings.HasPrefix(err.Error(), "unité invalide") ||
+            strings.HasPrefix(err.Error(), "quantité invalide") || strings.HasPrefix(err.Error(), "devise invalide") {
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Données invalides",
+                "details": err.Error(),
+            })
+            return
+        }
+
         sc.logger.Error("Erreur lors de l'incrémentation du stock", zap.String("id", id), zap.Error(err))
         c.JSON(http.StatusInternalServerError, gin.H{
             "error": "Erreur lors de l'incrémentation du stock",
@@ -278,6 +345,7 @@ func (sc *StockController) IncrementStock(c *gin.Context) {
         "mouvement": gin.H{
             "type": "increment",
             "quantite": req.Quantite,
+            "unite": req.Unite,
             "motif": req.Motif,
         },
     })
@@ -285,16 +353,19 @@ func (sc *StockController) IncrementStock(c *gin.Context) {
 
 // DecrementStock diminue la quantité d'une pièce
 // @Summary Décrémenter le stock
-// @Description Diminue la quantité en stock d'une pièce
+// @Description Diminue la quantité en stock d'une pièce; pour une pièce suivie par lot, les lots sont choisis par ordre de péremption si aucun n'est précisé
 // @Tags Stock
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "ID de la pièce"
 // @Param movement body models.StockMovementRequest true "Données du mouvement"
+// @Param Idempotency-Key header string false "Clé d'idempotence: une répétition avec la même clé renvoie la première réponse sans nouvel effet"
 // @Success 200 {object} map[string]interface{} "Stock décrémenté"
 // @Failure 400 {object} map[string]interface{} "Données invalides ou stock insuffisant"
 // @Failure 404 {object} map[string]interface{} "Pièce non trouvée"
+// @Failure 409 {object} map[string]interface{} "FEFO non respecté (mode strict) ou requête idempotente en cours"
+// @Failure 422 {object} map[string]interface{} "Clé d'idempotence réutilisée pour une autre requête"
 // @Failure 500 {object} map[string]interface{} "Erreur interne"
 // @Router /stock/{id}/decrement [post]
 func (sc *StockController) DecrementStock(c *gin.Context) {
@@ -310,7 +381,7 @@ func (sc *StockController) DecrementStock(c *gin.Context) {
         return
     }
 
-    piece, err := sc.stockService.DecrementStock(id, req.Quantite, req.Motif)
+    piece, err := sc.stockService.DecrementStock(id, &req, currentUserID(c))
     if err != nil {
         if err.Error() == "pièce non trouvée: "+id {
             c.JSON(http.StatusNotFound, gin.H{
@@ -329,6 +400,23 @@ func (sc *StockController) DecrementStock(c *gin.Context) {
             return
         }
 
+        if strings.HasPrefix(err.Error(), "suivi invalide") || strings.HasPrefix(err.Error(), "unité invalide") ||
+            strings.HasPrefix(err.Error(), "quantité invalide") {
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Données invalides",
+                "details": err.Error(),
+            })
+            return
+        }
+
+        if strings.HasPrefix(err.Error(), "FEFO non respecté") {
+            c.JSON(http.StatusConflict, gin.H{
+                "error": "FEFO non respecté",
+                "details": err.Error(),
+            })
+            return
+        }
+
         sc.logger.Error("Erreur lors de la décrémentation du stock", zap.String("id", id), zap.Error(err))
         c.JSON(http.StatusInternalServerError, gin.H{
             "error": "Erreur lors de la décrémentation du stock",
@@ -343,6 +431,7 @@ func (sc *StockController) DecrementStock(c *gin.Context) {
         "mouvement": gin.H{
             "type": "decrement",
             "quantite": req.Quantite,
+            "unite": req.Unite,
             "motif": req.Motif,
         },
     })
@@ -350,16 +439,17 @@ func (sc *StockController) DecrementStock(c *gin.Context) {
 
 // GetLowStockAlerts récupère les alertes de stock faible
 // @Summary Récupérer les alertes de stock
-// @Description Retourne les pièces en stock faible ou critique
+// @Description Retourne les pièces en stock faible ou critique, avec la quantité déjà en commande et la quantité à commander suggérée par la politique de réapprovisionnement, et les lots périmés ou proches de la péremption, éventuellement filtrés par emplacement. Les pièces de classe A sont listées en premier.
 // @Tags Stock
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param emplacement query string false "Emplacement de stockage"
 // @Success 200 {object} map[string]interface{} "Alertes de stock"
 // @Failure 500 {object} map[string]interface{} "Erreur interne"
 // @Router /stock/alerts [get]
 func (sc *StockController) GetLowStockAlerts(c *gin.Context) {
-    alerts, err := sc.stockService.GetLowStockAlerts()
+    alerts, err := sc.stockService.GetLowStockAlerts(pieceFilterFromQuery(c))
     if err != nil {
         sc.logger.Error("Erreur lors de la récupération des alertes", zap.Error(err))
         c.JSON(http.StatusInternalServerError, gin.H{
@@ -369,15 +459,19 @@ func (sc *StockController) GetLowStockAlerts(c *gin.Context) {
         return
     }
 
-    // Comptage par sévérité
+    // Comptage par sévérité et par type
     critiques := 0
     attentions := 0
+    peremptions := 0
     for _, alert := range alerts {
         if alert.Severite == "critique" {
             critiques++
         } else {
             attentions++
         }
+        if alert.Type == models.ALERT_TYPE_EXPIRY {
+            peremptions++
+        }
     }
 
     c.JSON(http.StatusOK, gin.H{
@@ -387,6 +481,7 @@ func (sc *StockController) GetLowStockAlerts(c *gin.Context) {
             "total": len(alerts),
             "critiques": critiques,
             "attentions": attentions,
+            "peremptions": peremptions,
         },
     })
 }
@@ -428,4 +523,156 @@ func (sc *StockController) SearchPieces(c *gin.Context) {
         "data": pieces,
         "count": len(pieces),
     })
-}
\ No newline at end of file
+}
+
+// GetMovements récupère l'historique des mouvements d'une pièce
+// @Summary Historique des mouvements d'une pièce
+// @Description Retourne les mouvements de stock d'une pièce, du plus récent au plus ancien, avec filtrage par période et pagination
+// @Tags Stock
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "ID de la pièce"
+// @Param from query string false "Date de début (RFC3339 ou AAAA-MM-JJ)"
+// @Param to query string false "Date de fin (RFC3339 ou AAAA-MM-JJ)"
+// @Param page query int false "Numéro de page (défaut: 1)"
+// @Param limit query int false "Nombre de mouvements par page (défaut: 50, max: 200)"
+// @Success 200 {object} map[string]interface{} "Historique des mouvements"
+// @Failure 400 {object} map[string]interface{} "Paramètres invalides"
+// @Failure 404 {object} map[string]interface{} "Pièce non trouvée"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/{id}/movements [get]
+func (sc *StockController) GetMovements(c *gin.Context) {
+    id := c.Param("id")
+
+    filter, err := parseMovementFilter(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Paramètres invalides",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    movements, total, err := sc.stockService.GetMovements(id, filter)
+    if err != nil {
+        if err.Error() == "pièce non trouvée: "+id {
+            c.JSON(http.StatusNotFound, gin.H{
+                "error": "Pièce non trouvée",
+                "piece_id": id,
+            })
+            return
+        }
+
+        sc.logger.Error("Erreur lors de la récupération des mouvements", zap.String("id", id), zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "Erreur lors de la récupération des mouvements",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Mouvements récupérés avec succès",
+        "piece_id": id,
+        "data": movements,
+        "count": len(movements),
+        "pagination": gin.H{
+            "page": filter.Page,
+            "limit": filter.Limit,
+            "total": total,
+            "pages": (total + int64(filter.Limit) - 1) / int64(filter.Limit),
+        },
+    })
+}
+
+// pieceFilterFromQuery lit les critères de filtrage des listes de pièces
+func pieceFilterFromQuery(c *gin.Context) models.PieceFilter {
+    return models.PieceFilter{
+        Categorie:     c.Query("categorie"),
+        Emplacement:   c.Query("emplacement"),
+        FournisseurID: c.Query("fournisseur_id"),
+        ClasseABC:     c.Query("classe_abc"),
+        ClasseXYZ:     c.Query("classe_xyz"),
+    }
+}
+
+// parseMovementFilter lit les paramètres de période et de pagination
+func parseMovementFilter(c *gin.Context) (models.MovementFilter, error) {
+    filter := models.MovementFilter{
+        Page:  1,
+        Limit: services.DEFAULT_MOVEMENTS_LIMIT,
+    }
+
+    if from := c.Query("from"); from != "" {
+        t, err := parseDateParam(from, false)
+        if err != nil {
+            return filter, fmt.Errorf("paramètre 'from' invalide: %s", from)
+        }
+        filter.From = &t
+    }
+
+    if to := c.Query("to"); to != "" {
+        t, err := parseDateParam(to, true)
+        if err != nil {
+            return filter, fmt.Errorf("paramètre 'to' invalide: %s", to)
+        }
+        filter.To = &t
+    }
+
+    if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+        return filter, fmt.Errorf("la date 'from' doit précéder la date 'to'")
+    }
+
+    if page := c.Query("page"); page != "" {
+        value, err := strconv.Atoi(page)
+        if err != nil || value < 1 {
+            return filter, fmt.Errorf("paramètre 'page' invalide: %s", page)
+        }
+        filter.Page = value
+    }
+
+    if limit := c.Query("limit"); limit != "" {
+        value, err := strconv.Atoi(limit)
+        if err != nil || value < 1 || value > services.MAX_MOVEMENTS_LIMIT {
+            return filter, fmt.Errorf("paramètre 'limit' invalide: %s (1 à %d)", limit, services.MAX_MOVEMENTS_LIMIT)
+        }
+        filter.Limit = value
+    }
+
+    return filter, nil
+}
+
+// parseDateParam accepte une date RFC3339 ou AAAA-MM-JJ; une date seule
+// utilisée comme borne de fin couvre toute la journée
+func parseDateParam(value string, endOfDay bool) (time.Time, error) {
+    if t, err := time.Parse(time.RFC3339, value); err == nil {
+        return t, nil
+    }
+
+    t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+    if err != nil {
+        return time.Time{}, err
+    }
+    if endOfDay {
+        t = t.Add(24*time.Hour - time.Millisecond)
+    }
+    return t, nil
+}
+
+// currentUserID retourne l'identifiant de l'utilisateur extrait du JWT
+func currentUserID(c *gin.Context) string {
+    value, exists := c.Get("user_id")
+    if !exists || value == nil {
+        return ""
+    }
+
+    switch v := value.(type) {
+    case string:
+        return v
+    case float64:
+        return strconv.FormatFloat(v, 'f', -1, 64)
+    default:
+        return fmt.Sprint(v)
+    }
+}
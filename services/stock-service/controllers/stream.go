@@ -0,0 +1,173 @@
+package controllers
+
+import (
+    "fmt"
+    "net/http"
+    "stock-service/models"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/gorilla/websocket"
+    "go.uber.org/zap"
+)
+
+const (
+    STREAM_HEARTBEAT_INTERVAL = 25 * time.Second // inférieur aux délais d'inactivité usuels des proxys
+    STREAM_WRITE_TIMEOUT      = 10 * time.Second
+)
+
+// streamUpgrader ouvre les connexions WebSocket du flux. L'origine n'est pas
+// contrôlée: l'authentification repose sur le token transmis explicitement,
+// jamais sur un cookie.
+var streamUpgrader = websocket.Upgrader{
+    ReadBufferSize:  1024,
+    WriteBufferSize: 4096,
+    Subprotocols:    []string{"bearer"},
+    CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// eventFilterFromQuery lit les critères d'abonnement au flux
+func eventFilterFromQuery(c *gin.Context) models.StockEventFilter {
+    filter := models.StockEventFilter{
+        Categorie:   strings.TrimSpace(c.Query("categorie")),
+        Emplacement: strings.TrimSpace(c.Query("emplacement")),
+    }
+    for _, id := range strings.Split(c.Query("piece_id"), ",") {
+        if id = strings.TrimSpace(id); id != "" {
+            filter.PieceIDs = append(filter.PieceIDs, id)
+        }
+    }
+    return filter
+}
+
+// StreamEvents diffuse les événements des pièces en Server-Sent Events
+// @Summary Flux temps réel des pièces (SSE)
+// @Description Diffuse en Server-Sent Events les créations, modifications, suppressions et mouvements de pièces dès qu'ils sont enregistrés, quelle que soit l'instance qui les traite. Chaque événement porte son type dans le champ "event" et son contenu JSON dans le champ "data"; un commentaire est envoyé toutes les 25 secondes pour maintenir la connexion. Les événements survenus pendant une déconnexion ne sont pas rejoués: le client doit recharger l'état des pièces après s'être reconnecté.
+// @Tags Flux
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param piece_id query string false "IDs de pièces, séparés par des virgules"
+// @Param categorie query string false "Catégorie de pièce"
+// @Param emplacement query string false "Emplacement de stockage"
+// @Success 200 {object} models.StockEvent "Flux d'événements"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/stream [get]
+func (sc *StockController) StreamEvents(c *gin.Context) {
+    // La connexion reste ouverte au-delà du délai d'écriture du serveur
+    if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+        sc.logger.Error("Flux SSE non pris en charge", zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "Flux non pris en charge",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    sub := sc.stockService.SubscribeEvents(eventFilterFromQuery(c))
+    defer sub.Close()
+
+    c.Header("Content-Type", "text/event-stream")
+    c.Header("Cache-Control", "no-cache")
+    c.Header("Connection", "keep-alive")
+    c.Header("X-Accel-Buffering", "no")
+    c.Status(http.StatusOK)
+    fmt.Fprint(c.Writer, ": flux ouvert\n\n")
+    c.Writer.Flush()
+
+    heartbeat := time.NewTicker(STREAM_HEARTBEAT_INTERVAL)
+    defer heartbeat.Stop()
+
+    for {
+        select {
+        case <-c.Request.Context().Done():
+            return
+        case <-heartbeat.C:
+            if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+                return
+            }
+        case event, ok := <-sub.Events():
+            if !ok {
+                // Abonné trop lent: le client se reconnecte et recharge l'état
+                return
+            }
+            data, err := event.ToJSON()
+            if err != nil {
+                sc.logger.Error("Erreur de sérialisation d'un événement", zap.Error(err))
+                continue
+            }
+            if _, err := fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+                return
+            }
+        }
+        c.Writer.Flush()
+    }
+}
+
+// StreamEventsWebSocket diffuse les événements des pièces sur une WebSocket
+// @Summary Flux temps réel des pièces (WebSocket)
+// @Description Diffuse sur une WebSocket, un message JSON par événement, les créations, modifications, suppressions et mouvements de pièces dès qu'ils sont enregistrés, quelle que soit l'instance qui les traite. Un client qui ne peut pas envoyer l'en-tête Authorization transmet le token dans l'en-tête Sec-WebSocket-Protocol ("bearer, <token>"). Les messages du client sont ignorés. Les événements survenus pendant une déconnexion ne sont pas rejoués.
+// @Tags Flux
+// @Produce json
+// @Security BearerAuth
+// @Param piece_id query string false "IDs de pièces, séparés par des virgules"
+// @Param categorie query string false "Catégorie de pièce"
+// @Param emplacement query string false "Emplacement de stockage"
+// @Success 101 {object} models.StockEvent "Connexion WebSocket"
+// @Failure 400 {object} map[string]interface{} "Requête d'ouverture invalide"
+// @Router /stock/ws [get]
+func (sc *StockController) StreamEventsWebSocket(c *gin.Context) {
+    filter := eventFilterFromQuery(c)
+
+    // En cas d'échec, Upgrade répond lui-même au client
+    conn, err := streamUpgrader.Upgrade(c.Writer, c.Request, nil)
+    if err != nil {
+        sc.logger.Warn("Ouverture de WebSocket refusée", zap.Error(err))
+        return
+    }
+    defer conn.Close()
+
+    sub := sc.stockService.SubscribeEvents(filter)
+    defer sub.Close()
+
+    // La lecture traite les pongs et détecte la fermeture par le client
+    closed := make(chan struct{})
+    conn.SetReadLimit(1024)
+    conn.SetReadDeadline(time.Now().Add(2 * STREAM_HEARTBEAT_INTERVAL))
+    conn.SetPongHandler(func(string) error {
+        return conn.SetReadDeadline(time.Now().Add(2 * STREAM_HEARTBEAT_INTERVAL))
+    })
+    go func() {
+        defer close(closed)
+        for {
+            if _, _, err := conn.ReadMessage(); err != nil {
+                return
+            }
+        }
+    }()
+
+    heartbeat := time.NewTicker(STREAM_HEARTBEAT_INTERVAL)
+    defer heartbeat.Stop()
+
+    for {
+        select {
+        case <-closed:
+            return
+        case <-heartbeat.C:
+            if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(STREAM_WRITE_TIMEOUT)); err != nil {
+                return
+            }
+        case event, ok := <-sub.Events():
+            if !ok {
+                conn.WriteControl(websocket.CloseMessage,
+                    websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "abonné trop lent"),
+                    time.Now().Add(STREAM_WRITE_TIMEOUT))
+                return
+            }
+            conn.SetWriteDeadline(time.Now().Add(STREAM_WRITE_TIMEOUT))
+            if err := conn.WriteJSON(event); err != nil {
+                return
+            }
+        }
+    }
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+    "net/http"
+    "stock-service/models"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+)
+
+// CreateSupplier crée un fournisseur
+// @Summary Créer un fournisseur
+// @Description Ajoute un fournisseur au référentiel avec ses coordonnées, son délai de livraison par défaut et sa devise
+// @Tags Fournisseurs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param fournisseur body models.CreateSupplierRequest true "Données du fournisseur"
+// @Success 201 {object} map[string]interface{} "Fournisseur créé"
+// @Failure 400 {object} map[string]interface{} "Données invalides"
+// @Failure 403 {object} map[string]interface{} "Rôle insuffisant"
+// @Failure 409 {object} map[string]interface{} "Fournisseur existant"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/suppliers [post]
+func (sc *StockController) CreateSupplier(c *gin.Context) {
+    var req models.CreateSupplierRequest
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        sc.logger.Warn("Données invalides pour création de fournisseur", zap.Error(err))
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    supplier, err := sc.stockService.CreateSupplier(&req)
+    if err != nil {
+        sc.respondSupplierError(c, err, "Erreur lors de la création du fournisseur")
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "message": "Fournisseur créé avec succès",
+        "data": supplier,
+    })
+}
+
+// GetSuppliers récupère les fournisseurs
+// @Summary Lister les fournisseurs
+// @Description Retourne les fournisseurs par ordre alphabétique, éventuellement les seuls fournisseurs actifs
+// @Tags Fournisseurs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param actif query bool false "Seulement les fournisseurs actifs"
+// @Success 200 {object} map[string]interface{} "Liste des fournisseurs"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/suppliers [get]
+func (sc *StockController) GetSuppliers(c *gin.Context) {
+    suppliers, err := sc.stockService.GetSuppliers(c.Query("actif") == "true")
+    if err != nil {
+        sc.respondSupplierError(c, err, "Erreur lors de la récupération des fournisseurs")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Fournisseurs récupérés avec succès",
+        "data": suppliers,
+        "count": len(suppliers),
+    })
+}
+
+// GetSupplier récupère un fournisseur
+// @Summary Récupérer un fournisseur
+// @Description Retourne les détails d'un fournisseur; ses pièces sont listées par GET /stock?fournisseur_id=
+// @Tags Fournisseurs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param supplier_id path string true "ID du fournisseur"
+// @Success 200 {object} map[string]interface{} "Détails du fournisseur"
+// @Failure 404 {object} map[string]interface{} "Fournisseur non trouvé"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/suppliers/{supplier_id} [get]
+func (sc *StockController) GetSupplier(c *gin.Context) {
+    supplier, err := sc.stockService.GetSupplier(c.Param("supplier_id"))
+    if err != nil {
+        sc.respondSupplierError(c, err, "Erreur lors de la récupération du fournisseur")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Fournisseur trouvé",
+        "data": supplier,
+    })
+}
+
+// UpdateSupplier met à jour un fournisseur
+// @Summary Mettre à jour un fournisseur
+// @Description Modifie les informations d'un fournisseur; un nouveau nom est reporté sur les pièces dont il est le fournisseur préféré; une nouvelle devise convertit les prix d'achat des pièces liées
+// @Tags Fournisseurs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param supplier_id path string true "ID du fournisseur"
+// @Param fournisseur body models.UpdateSupplierRequest true "Données à mettre à jour"
+// @Success 200 {object} map[string]interface{} "Fournisseur mis à jour"
+// @Failure 400 {object} map[string]interface{} "Données invalides"
+// @Failure 403 {object} map[string]interface{} "Rôle insuffisant"
+// @Failure 404 {object} map[string]interface{} "Fournisseur non trouvé"
+// @Failure 409 {object} map[string]interface{} "Nom déjà utilisé"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/suppliers/{supplier_id} [put]
+func (sc *StockController) UpdateSupplier(c *gin.Context) {
+    var req models.UpdateSupplierRequest
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        sc.logger.Warn("Données invalides pour mise à jour de fournisseur", zap.Error(err))
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    supplier, err := sc.stockService.UpdateSupplier(c.Param("supplier_id"), &req)
+    if err != nil {
+        sc.respondSupplierError(c, err, "Erreur lors de la mise à jour du fournisseur")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Fournisseur mis à jour avec succès",
+        "data": supplier,
+    })
+}
+
+// respondSupplierError traduit les erreurs du référentiel fournisseurs en réponse HTTP
+func (sc *StockController) respondSupplierError(c *gin.Context, err error, message string) {
+    msg := err.Error()
+
+    switch {
+    case strings.HasPrefix(msg, "fournisseur non trouvé"):
+        c.JSON(http.StatusNotFound, gin.H{
+            "error": "Fournisseur non trouvé",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "fournisseur existant"):
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "Fournisseur existant",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "fournisseur invalide"),
+        strings.HasPrefix(msg, "devise invalide"):
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": msg,
+        })
+    default:
+        sc.logger.Error(message, zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": message,
+            "details": msg,
+        })
+    }
+}
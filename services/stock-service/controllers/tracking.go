@@ -0,0 +1,48 @@
+package controllers
+
+import (
+    "net/http"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+)
+
+// TraceSerial retrace le parcours d'un numéro de série
+// @Summary Traçabilité d'un numéro de série
+// @Description Retourne tous les mouvements portant sur un numéro de série, toutes pièces confondues, et indique s'il est encore en stock
+// @Tags Stock
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param serial path string true "Numéro de série"
+// @Success 200 {object} map[string]interface{} "Parcours du numéro de série"
+// @Failure 404 {object} map[string]interface{} "Numéro de série inconnu"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/serials/{serial} [get]
+func (sc *StockController) TraceSerial(c *gin.Context) {
+    serial := c.Param("serial")
+
+    trace, err := sc.stockService.TraceSerial(serial)
+    if err != nil {
+        if strings.HasPrefix(err.Error(), "numéro de série non trouvé") {
+            c.JSON(http.StatusNotFound, gin.H{
+                "error": "Numéro de série non trouvé",
+                "numero_serie": serial,
+            })
+            return
+        }
+
+        sc.logger.Error("Erreur lors de la traçabilité du numéro de série", zap.String("serial", serial), zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "Erreur lors de la traçabilité du numéro de série",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Parcours du numéro de série récupéré",
+        "data": trace,
+    })
+}
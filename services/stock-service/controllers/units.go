@@ -0,0 +1,27 @@
+package controllers
+
+import (
+    "net/http"
+    "stock-service/models"
+
+    "github.com/gin-gonic/gin"
+)
+
+// GetUnits récupère le catalogue des unités de mesure
+// @Summary Catalogue des unités de mesure
+// @Description Retourne les unités acceptées pour l'unité de stock, les conversions des pièces et la saisie des mouvements
+// @Tags Unités
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Catalogue des unités"
+// @Router /stock/units [get]
+func (sc *StockController) GetUnits(c *gin.Context) {
+    units := models.UnitCatalog()
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Unités de mesure récupérées avec succès",
+        "data": units,
+        "count": len(units),
+    })
+}
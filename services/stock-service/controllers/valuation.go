@@ -0,0 +1,78 @@
+package controllers
+
+import (
+    "net/http"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+)
+
+// GetValuation calcule la valorisation du stock
+// @Summary Valorisation du stock
+// @Description Valorise le stock selon la méthode configurée (prix moyen pondéré ou FIFO), avec les totaux par catégorie et par emplacement
+// @Tags Valorisation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param emplacement query string false "Emplacement de stockage"
+// @Success 200 {object} map[string]interface{} "Valorisation du stock"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/valuation [get]
+func (sc *StockController) GetValuation(c *gin.Context) {
+    report, err := sc.stockService.GetValuation(pieceFilterFromQuery(c))
+    if err != nil {
+        sc.logger.Error("Erreur lors de la valorisation du stock", zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "Erreur lors de la valorisation du stock",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Valorisation du stock calculée",
+        "data": report,
+    })
+}
+
+// GetCostHistory récupère l'historique du coût d'une pièce
+// @Summary Historique du coût d'une pièce
+// @Description Retourne les évolutions du prix moyen pondéré d'une pièce, de la plus récente à la plus ancienne
+// @Tags Valorisation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "ID de la pièce"
+// @Success 200 {object} map[string]interface{} "Historique des coûts"
+// @Failure 404 {object} map[string]interface{} "Pièce non trouvée"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/{id}/costs [get]
+func (sc *StockController) GetCostHistory(c *gin.Context) {
+    id := c.Param("id")
+
+    history, err := sc.stockService.GetCostHistory(id)
+    if err != nil {
+        if strings.HasPrefix(err.Error(), "pièce non trouvée") {
+            c.JSON(http.StatusNotFound, gin.H{
+                "error": "Pièce non trouvée",
+                "piece_id": id,
+            })
+            return
+        }
+
+        sc.logger.Error("Erreur lors de la récupération de l'historique des coûts", zap.String("id", id), zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "Erreur lors de la récupération de l'historique des coûts",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Historique des coûts récupéré",
+        "piece_id": id,
+        "data": history,
+        "count": len(history),
+    })
+}
@@ -0,0 +1,253 @@
+package controllers
+
+import (
+    "net/http"
+    "stock-service/models"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+)
+
+// CreateWebhook crée un abonnement webhook
+// @Summary Créer un abonnement webhook
+// @Description Abonne une adresse aux franchissements de seuil des pièces (stock.faible, stock.critique, stock.retabli; tous si la liste est vide). Chaque envoi est un POST JSON signé: X-Webhook-Signature vaut "sha256=" suivi du HMAC-SHA256 hexadécimal, avec le secret, de "<X-Webhook-Timestamp>.<corps>". Les envois en échec sont retentés avec un délai croissant puis placés en liste des échecs. Les adresses internes (boucle locale, lien local, réseaux privés) sont refusées sauf si WEBHOOK_ALLOW_PRIVATE est activé.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook body models.CreateWebhookRequest true "Adresse, événements et secret"
+// @Success 201 {object} map[string]interface{} "Abonnement créé"
+// @Failure 400 {object} map[string]interface{} "Données invalides"
+// @Failure 403 {object} map[string]interface{} "Rôle insuffisant"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/webhooks [post]
+func (sc *StockController) CreateWebhook(c *gin.Context) {
+    var req models.CreateWebhookRequest
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        sc.logger.Warn("Données invalides pour création d'abonnement webhook", zap.Error(err))
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    subscription, err := sc.stockService.CreateWebhook(&req, currentUserID(c))
+    if err != nil {
+        sc.respondWebhookError(c, err, "Erreur lors de la création de l'abonnement")
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "message": "Abonnement webhook créé avec succès",
+        "data": subscription.Masked(),
+    })
+}
+
+// GetWebhooks récupère les abonnements webhook
+// @Summary Lister les abonnements webhook
+// @Description Retourne les abonnements par date de création, sans leurs secrets
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Liste des abonnements"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/webhooks [get]
+func (sc *StockController) GetWebhooks(c *gin.Context) {
+    subscriptions, err := sc.stockService.GetWebhooks()
+    if err != nil {
+        sc.respondWebhookError(c, err, "Erreur lors de la récupération des abonnements")
+        return
+    }
+
+    masked := make([]models.WebhookSubscription, 0, len(subscriptions))
+    for _, subscription := range subscriptions {
+        masked = append(masked, subscription.Masked())
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Abonnements récupérés avec succès",
+        "data": masked,
+        "count": len(masked),
+    })
+}
+
+// GetWebhook récupère un abonnement webhook
+// @Summary Récupérer un abonnement webhook
+// @Description Retourne un abonnement, sans son secret
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook_id path string true "ID de l'abonnement"
+// @Success 200 {object} map[string]interface{} "Abonnement"
+// @Failure 404 {object} map[string]interface{} "Abonnement non trouvé"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/webhooks/{webhook_id} [get]
+func (sc *StockController) GetWebhook(c *gin.Context) {
+    subscription, err := sc.stockService.GetWebhook(c.Param("webhook_id"))
+    if err != nil {
+        sc.respondWebhookError(c, err, "Erreur lors de la récupération de l'abonnement")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Abonnement trouvé",
+        "data": subscription.Masked(),
+    })
+}
+
+// UpdateWebhook met à jour un abonnement webhook
+// @Summary Mettre à jour un abonnement webhook
+// @Description Modifie l'adresse, les événements, le secret ou l'activation d'un abonnement; les livraisons en attente d'un abonnement désactivé sont abandonnées
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook_id path string true "ID de l'abonnement"
+// @Param webhook body models.UpdateWebhookRequest true "Champs à modifier"
+// @Success 200 {object} map[string]interface{} "Abonnement mis à jour"
+// @Failure 400 {object} map[string]interface{} "Données invalides"
+// @Failure 403 {object} map[string]interface{} "Rôle insuffisant"
+// @Failure 404 {object} map[string]interface{} "Abonnement non trouvé"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/webhooks/{webhook_id} [put]
+func (sc *StockController) UpdateWebhook(c *gin.Context) {
+    var req models.UpdateWebhookRequest
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        sc.logger.Warn("Données invalides pour mise à jour d'abonnement webhook", zap.Error(err))
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    subscription, err := sc.stockService.UpdateWebhook(c.Param("webhook_id"), &req)
+    if err != nil {
+        sc.respondWebhookError(c, err, "Erreur lors de la mise à jour de l'abonnement")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Abonnement mis à jour avec succès",
+        "data": subscription.Masked(),
+    })
+}
+
+// DeleteWebhook supprime un abonnement webhook
+// @Summary Supprimer un abonnement webhook
+// @Description Supprime un abonnement; ses livraisons en attente sont abandonnées
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook_id path string true "ID de l'abonnement"
+// @Success 200 {object} map[string]interface{} "Abonnement supprimé"
+// @Failure 403 {object} map[string]interface{} "Rôle insuffisant"
+// @Failure 404 {object} map[string]interface{} "Abonnement non trouvé"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/webhooks/{webhook_id} [delete]
+func (sc *StockController) DeleteWebhook(c *gin.Context) {
+    id := c.Param("webhook_id")
+
+    if err := sc.stockService.DeleteWebhook(id); err != nil {
+        sc.respondWebhookError(c, err, "Erreur lors de la suppression de l'abonnement")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Abonnement supprimé avec succès",
+        "webhook_id": id,
+    })
+}
+
+// GetWebhookDeadLetters récupère les livraisons en échec
+// @Summary Lister les livraisons webhook en échec
+// @Description Retourne les livraisons abandonnées après épuisement des tentatives, des plus récentes aux plus anciennes, avec la dernière erreur rencontrée
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook_id query string false "ID de l'abonnement"
+// @Success 200 {object} map[string]interface{} "Livraisons en échec"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/webhooks/dead-letters [get]
+func (sc *StockController) GetWebhookDeadLetters(c *gin.Context) {
+    deliveries, err := sc.stockService.GetDeadLetters(c.Query("webhook_id"))
+    if err != nil {
+        sc.respondWebhookError(c, err, "Erreur lors de la récupération des livraisons en échec")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Livraisons en échec récupérées",
+        "data": deliveries,
+        "count": len(deliveries),
+    })
+}
+
+// ReplayWebhookDeadLetter rejoue une livraison en échec
+// @Summary Rejouer une livraison webhook
+// @Description Replanifie immédiatement une livraison en échec avec un nouveau cycle de tentatives
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param delivery_id path string true "ID de la livraison"
+// @Success 200 {object} map[string]interface{} "Livraison replanifiée"
+// @Failure 403 {object} map[string]interface{} "Rôle insuffisant"
+// @Failure 404 {object} map[string]interface{} "Livraison non trouvée"
+// @Failure 409 {object} map[string]interface{} "Livraison non rejouable"
+// @Failure 500 {object} map[string]interface{} "Erreur interne"
+// @Router /stock/webhooks/dead-letters/{delivery_id}/replay [post]
+func (sc *StockController) ReplayWebhookDeadLetter(c *gin.Context) {
+    delivery, err := sc.stockService.ReplayDeadLetter(c.Param("delivery_id"), currentUserID(c))
+    if err != nil {
+        sc.respondWebhookError(c, err, "Erreur lors de la replanification de la livraison")
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Livraison replanifiée",
+        "data": delivery,
+    })
+}
+
+// respondWebhookError traduit les erreurs des webhooks en réponse HTTP
+func (sc *StockController) respondWebhookError(c *gin.Context, err error, message string) {
+    msg := err.Error()
+
+    switch {
+    case strings.HasPrefix(msg, "webhook non trouvé"):
+        c.JSON(http.StatusNotFound, gin.H{
+            "error": "Abonnement non trouvé",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "livraison non trouvée"):
+        c.JSON(http.StatusNotFound, gin.H{
+            "error": "Livraison non trouvée",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "livraison non rejouable"):
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "Livraison non rejouable",
+            "details": msg,
+        })
+    case strings.HasPrefix(msg, "webhook invalide"):
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error": "Données invalides",
+            "details": msg,
+        })
+    default:
+        sc.logger.Error(message, zap.Error(err))
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": message,
+            "details": msg,
+        })
+    }
+}
@@ -15,6 +15,8 @@ import (
 
     "github.com/gin-contrib/cors"
     "github.com/gin-gonic/gin"
+    "github.com/gin-gonic/gin/binding"
+    "github.com/go-playground/validator/v10"
     swaggerFiles "github.com/swaggo/files"
     ginSwagger "github.com/swaggo/gin-swagger"
     "go.uber.org/zap"
@@ -61,7 +63,31 @@ func main() {
     logger.Info("✅ Connexion Redis établie")
 
     // Initialisation des services
-    stockService := services.NewStockService(redisClient, logger)
+    stockService := services.NewStockService(redisClient, cfg, logger)
+
+    // Migrations du schéma: quantités décimales, référentiel des
+    // fournisseurs, dates de dernier mouvement
+    if err := stockService.RunMigrations(); err != nil {
+        logger.Error("Erreur lors de la migration du schéma", zap.Error(err))
+    }
+
+    // Groupes de consommateurs des événements de domaine
+    if err := stockService.EnsureDomainEventGroups(cfg.DomainEventGroups); err != nil {
+        logger.Error("Erreur lors de la création des groupes de consommateurs", zap.Error(err))
+    }
+    
+    // Recalcul planifié de la classification ABC/XYZ
+    scheduleCtx, stopSchedule := context.WithCancel(context.Background())
+    defer stopSchedule()
+    if cfg.ClassificationIntervalHours > 0 {
+        go stockService.RunClassificationSchedule(scheduleCtx, time.Duration(cfg.ClassificationIntervalHours)*time.Hour)
+    }
+
+    // Envoi des webhooks de franchissement de seuil
+    go stockService.RunWebhookDispatcher(scheduleCtx)
+
+    // Relais du flux temps réel des pièces entre les instances
+    go stockService.RunEventStream(scheduleCtx)
     
     // Insertion de données de test
     if err := insertTestData(stockService); err != nil {
@@ -74,6 +100,13 @@ func main() {
     }
     
     router := gin.New()
+
+    // Règles de validation propres au service (unités de mesure)
+    if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+        if err := models.RegisterValidations(v); err != nil {
+            logger.Fatal("Échec d'enregistrement des règles de validation", zap.Error(err))
+        }
+    }
     
     // Middlewares globaux
     router.Use(gin.Recovery())
@@ -84,8 +117,8 @@ func main() {
     router.Use(cors.New(cors.Config{
         AllowOrigins:     []string{"*"}, // En production: spécifier les domaines
         AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
-        AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-        ExposeHeaders:    []string{"Content-Length"},
+        AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", middleware.IDEMPOTENCY_HEADER},
+        ExposeHeaders:    []string{"Content-Length", "Idempotent-Replayed"},
         AllowCredentials: true,
         MaxAge:           12 * time.Hour,
     }))
@@ -135,22 +168,87 @@ func main() {
     stockController := controllers.NewStockController(stockService, logger)
 
 
+    // Rejeu sans effet des mutations portant un en-tête Idempotency-Key
+    idempotent := middleware.IdempotencyMiddleware(redisClient, time.Duration(cfg.IdempotencyTTLHours)*time.Hour, logger)
+
     // Routes API avec authentification
     apiRoutes := router.Group("/api")
-    apiRoutes.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+    apiRoutes.Use(middleware.WebSocketTokenMiddleware(), middleware.AuthMiddleware(cfg.JWTSecret))
     {
         // Routes pour les pièces détachées
         stock := apiRoutes.Group("/stock")
         {
             stock.GET("", stockController.GetAllPieces)
-            stock.POST("", stockController.CreatePiece)
+            stock.POST("", idempotent, stockController.CreatePiece)
             stock.GET("/:id", stockController.GetPiece)
             stock.PUT("/:id", stockController.UpdatePiece)
             stock.DELETE("/:id", stockController.DeletePiece)
-            stock.POST("/:id/increment", stockController.IncrementStock)
-            stock.POST("/:id/decrement", stockController.DecrementStock)
+            stock.POST("/:id/increment", idempotent, stockController.IncrementStock)
+            stock.POST("/:id/decrement", idempotent, stockController.DecrementStock)
+            stock.POST("/:id/transfer", stockController.TransferStock)
+            stock.GET("/:id/movements", stockController.GetMovements)
+            stock.GET("/:id/fefo", stockController.SuggestFEFO)
+            stock.GET("/:id/costs", stockController.GetCostHistory)
+            stock.GET("/:id/forecast", stockController.ForecastConsumption)
+            stock.POST("/:id/reservations", stockController.ReserveStock)
+            stock.GET("/:id/reservations", stockController.GetPieceReservations)
             stock.GET("/alerts", stockController.GetLowStockAlerts)
             stock.GET("/search", stockController.SearchPieces)
+            stock.GET("/serials/:serial", stockController.TraceSerial)
+            stock.GET("/expiring", stockController.GetExpiringLots)
+            stock.POST("/movements/batch", idempotent, stockController.ApplyMovementBatch)
+            stock.GET("/valuation", stockController.GetValuation)
+            stock.GET("/dormant", stockController.GetDormantStock)
+            stock.GET("/kpis", stockController.GetStockKPIs)
+            stock.GET("/stream", stockController.StreamEvents)
+            stock.GET("/ws", stockController.StreamEventsWebSocket)
+            stock.GET("/events/schema", stockController.GetDomainEventSchema)
+            stock.GET("/units", stockController.GetUnits)
+            stock.GET("/reservations", stockController.GetInterventionReservations)
+            stock.GET("/reservations/:reservation_id", stockController.GetReservation)
+            stock.POST("/reservations/:reservation_id/release", stockController.ReleaseReservation)
+            stock.POST("/reservations/:reservation_id/consume", stockController.ConsumeReservation)
+
+            // Sessions d'inventaire physique
+            stock.POST("/inventories", stockController.OpenInventory)
+            stock.GET("/inventories", stockController.GetInventories)
+            stock.GET("/inventories/:session_id", stockController.GetInventory)
+            stock.POST("/inventories/:session_id/counts", stockController.SubmitInventoryCounts)
+            stock.POST("/inventories/:session_id/validate", middleware.RequireRole("manager"), stockController.ValidateInventory)
+            stock.POST("/inventories/:session_id/cancel", middleware.RequireRole("manager"), stockController.CancelInventory)
+            stock.GET("/inventories/:session_id/report", middleware.RequireRole("manager"), stockController.GetInventoryReport)
+
+            // Référentiel des fournisseurs
+            stock.POST("/suppliers", middleware.RequireRole("manager"), stockController.CreateSupplier)
+            stock.GET("/suppliers", stockController.GetSuppliers)
+            stock.GET("/suppliers/:supplier_id", stockController.GetSupplier)
+            stock.PUT("/suppliers/:supplier_id", middleware.RequireRole("manager"), stockController.UpdateSupplier)
+
+            // Commandes fournisseur
+            stock.POST("/purchase-orders/generate", stockController.GeneratePurchaseOrders)
+            stock.GET("/purchase-orders", stockController.GetPurchaseOrders)
+            stock.GET("/purchase-orders/:order_id", stockController.GetPurchaseOrder)
+            stock.PUT("/purchase-orders/:order_id", stockController.UpdatePurchaseOrder)
+            stock.POST("/purchase-orders/:order_id/send", middleware.RequireRole("manager"), stockController.SendPurchaseOrder)
+            stock.POST("/purchase-orders/:order_id/cancel", middleware.RequireRole("manager"), stockController.CancelPurchaseOrder)
+            stock.POST("/purchase-orders/:order_id/receipts", idempotent, stockController.ReceivePurchaseOrder)
+
+            // Points de commande calculés depuis la consommation
+            stock.GET("/reorder-points", stockController.GetReorderPoints)
+            stock.POST("/reorder-points/apply", middleware.RequireRole("manager"), stockController.ApplyReorderPoints)
+
+            // Classification ABC/XYZ du catalogue
+            stock.GET("/classification", stockController.GetClassificationReport)
+            stock.POST("/classification", middleware.RequireRole("manager"), stockController.ClassifyPieces)
+
+            // Abonnements webhook aux franchissements de seuil
+            stock.POST("/webhooks", middleware.RequireRole("manager"), stockController.CreateWebhook)
+            stock.GET("/webhooks", stockController.GetWebhooks)
+            stock.GET("/webhooks/dead-letters", stockController.GetWebhookDeadLetters)
+            stock.POST("/webhooks/dead-letters/:delivery_id/replay", middleware.RequireRole("manager"), stockController.ReplayWebhookDeadLetter)
+            stock.GET("/webhooks/:webhook_id", stockController.GetWebhook)
+            stock.PUT("/webhooks/:webhook_id", middleware.RequireRole("manager"), stockController.UpdateWebhook)
+            stock.DELETE("/webhooks/:webhook_id", middleware.RequireRole("manager"), stockController.DeleteWebhook)
         }
     }
 
@@ -180,6 +278,7 @@ func main() {
     signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
     <-quit
     logger.Info("Arrêt du serveur en cours...")
+    stopSchedule()
 
     // Arrêt gracieux du serveur
     ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
@@ -201,7 +300,7 @@ func insertTestData(stockService *services.StockService) error {
             Description:     "Roulement à billes standard pour moteurs électriques",
             Quantite:        25,
             SeuilMin:        5,
-            PrixUnitaire:    45.50,
+            PrixUnitaire:    models.DecimalFromFloat(45.50),
             Fournisseur:     "SKF Sénégal",
             Emplacement:     "A1-B2-C3",
             CodeEAN:         "3276000123456",
@@ -214,7 +313,7 @@ func insertTestData(stockService *services.StockService) error {
             Description:     "Courroie trapézoïdale pour transmission de puissance",
             Quantite:        8,
             SeuilMin:        10,
-            PrixUnitaire:    22.75,
+            PrixUnitaire:    models.DecimalFromFloat(22.75),
             Fournisseur:     "Gates Dakar",
             Emplacement:     "A2-B1-C4",
             CodeEAN:         "3276000234567",
@@ -227,12 +326,13 @@ func insertTestData(stockService *services.StockService) error {
             Description:     "Huile hydraulique pour systèmes industriels",
             Quantite:        120,
             SeuilMin:        30,
-            PrixUnitaire:    8.90,
+            PrixUnitaire:    models.DecimalFromFloat(8.90),
             Fournisseur:     "Total Sénégal",
             Emplacement:     "B1-A3-C2",
             CodeEAN:         "3276000345678",
             Categorie:       "Lubrifiants",
             UniteStock:      "litre",
+            Conversions:     map[string]float64{"fût": 208, "bidon": 20},
         },
         {
             ID:              "piece-004",
@@ -240,7 +340,7 @@ func insertTestData(stockService *services.StockService) error {
             Description:     "Contacteur triphasé 18A pour commande moteur",
             Quantite:        3,
             SeuilMin:        8,
-            PrixUnitaire:    125.00,
+            PrixUnitaire:    models.DecimalFromFloat(125.00),
             Fournisseur:     "Schneider Electric",
             Emplacement:     "C1-A2-B1",
             CodeEAN:         "3276000456789",
@@ -253,17 +353,18 @@ func insertTestData(stockService *services.StockService) error {
             Description:     "Joint d'étanchéité en caoutchouc nitrile",
             Quantite:        150,
             SeuilMin:        25,
-            PrixUnitaire:    2.30,
+            PrixUnitaire:    models.DecimalFromFloat(2.30),
             Fournisseur:     "Parker Hannifin",
             Emplacement:     "A3-B3-C1",
             CodeEAN:         "3276000567890",
             Categorie:       "Joints",
             UniteStock:      "pièce",
+            Conversions:     map[string]float64{"sachet": 50},
         },
     }
 
     for _, piece := range testPieces {
-        if err := stockService.CreatePiece(&piece); err != nil {
+        if err := stockService.CreatePiece(&piece, ""); err != nil {
             return err
         }
     }
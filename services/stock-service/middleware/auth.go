@@ -70,6 +70,22 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
     }
 }
 
+// WebSocketTokenMiddleware reporte dans l'en-tête Authorization le token
+// d'une connexion WebSocket: les navigateurs ne permettent pas d'envoyer cet
+// en-tête à l'ouverture, le token est alors transmis comme sous-protocole
+// ("Sec-WebSocket-Protocol: bearer, <token>") et validé par AuthMiddleware
+func WebSocketTokenMiddleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if c.GetHeader("Authorization") == "" && strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+            protocols := strings.Split(c.GetHeader("Sec-WebSocket-Protocol"), ",")
+            if len(protocols) == 2 && strings.TrimSpace(protocols[0]) == "bearer" {
+                c.Request.Header.Set("Authorization", "Bearer "+strings.TrimSpace(protocols[1]))
+            }
+        }
+        c.Next()
+    }
+}
+
 // RequireRole vérifie que l'utilisateur a le rôle requis
 func RequireRole(allowedRoles ...string) gin.HandlerFunc {
     return func(c *gin.Context) {
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/go-redis/redis/v8"
+    "go.uber.org/zap"
+)
+
+const (
+    IDEMPOTENCY_HEADER     = "Idempotency-Key"
+    IDEMPOTENCY_KEY_PREFIX = "stock:idempotency:"
+    IDEMPOTENCY_MAX_LENGTH = 255
+
+    // IDEMPOTENCY_PENDING_LEASE borne la réservation d'une clé pendant le
+    // traitement de la requête d'origine, au-delà du délai d'écriture du
+    // serveur: si le processus s'arrête en cours de traitement, la clé se
+    // libère d'elle-même et le client peut réessayer
+    IDEMPOTENCY_PENDING_LEASE = 30 * time.Second
+)
+
+// idempotencyRecord représente la réponse mémorisée pour une clé d'idempotence.
+// Tant que la requête d'origine est en cours, Status vaut 0.
+type idempotencyRecord struct {
+    RequestHash string          `json:"request_hash"`
+    Status      int             `json:"status"`
+    Body        json.RawMessage `json:"body,omitempty"`
+}
+
+// responseRecorder conserve une copie du corps de la réponse
+type responseRecorder struct {
+    gin.ResponseWriter
+    body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+    w.body.Write(data)
+    return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+    w.body.WriteString(s)
+    return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware rend rejouable sans effet une requête portant
+// l'en-tête Idempotency-Key. La première réponse est conservée dans Redis
+// pendant ttl; une requête répétée avec la même clé reçoit cette réponse sans
+// être retraitée. La même clé avec une requête différente est refusée (422),
+// de même qu'une répétition pendant que l'originale est en cours (409).
+// Les réponses 5xx ne sont pas conservées afin que le client puisse réessayer;
+// la réservation de la clé pendant le traitement est de courte durée.
+func IdempotencyMiddleware(redisClient *redis.Client, ttl time.Duration, logger *zap.Logger) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        key := c.GetHeader(IDEMPOTENCY_HEADER)
+        if key == "" {
+            c.Next()
+            return
+        }
+
+        if len(key) > IDEMPOTENCY_MAX_LENGTH {
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "En-tête Idempotency-Key trop long",
+            })
+            c.Abort()
+            return
+        }
+
+        body, err := io.ReadAll(c.Request.Body)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Lecture de la requête impossible",
+                "details": err.Error(),
+            })
+            c.Abort()
+            return
+        }
+        c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+        // La clé est propre à chaque utilisateur; l'empreinte couvre la route
+        // et le corps pour détecter une réutilisation sur une autre requête
+        userID, _ := c.Get("user_id")
+        redisKey := fmt.Sprintf("%s%v:%s", IDEMPOTENCY_KEY_PREFIX, userID, key)
+        hash := sha256.New()
+        hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+        hash.Write(body)
+        requestHash := hex.EncodeToString(hash.Sum(nil))
+
+        ctx := context.Background()
+        pending, _ := json.Marshal(idempotencyRecord{RequestHash: requestHash})
+        acquired, err := redisClient.SetNX(ctx, redisKey, pending, IDEMPOTENCY_PENDING_LEASE).Result()
+        if err != nil {
+            logger.Error("Erreur lors de la vérification d'idempotence", zap.String("key", key), zap.Error(err))
+            c.JSON(http.StatusInternalServerError, gin.H{
+                "error": "Erreur lors de la vérification d'idempotence",
+                "details": err.Error(),
+            })
+            c.Abort()
+            return
+        }
+
+        if !acquired {
+            replayIdempotentResponse(c, redisClient, redisKey, requestHash)
+            return
+        }
+
+        // Une panique du traitement libère la clé avant d'être remontée au
+        // middleware de récupération
+        defer func() {
+            if r := recover(); r != nil {
+                redisClient.Del(ctx, redisKey)
+                panic(r)
+            }
+        }()
+
+        recorder := &responseRecorder{ResponseWriter: c.Writer}
+        c.Writer = recorder
+        c.Next()
+
+        status := recorder.Status()
+        if status >= http.StatusInternalServerError {
+            redisClient.Del(ctx, redisKey)
+            return
+        }
+
+        record, err := json.Marshal(idempotencyRecord{
+            RequestHash: requestHash,
+            Status:      status,
+            Body:        json.RawMessage(recorder.body.Bytes()),
+        })
+        if err == nil {
+            err = redisClient.Set(ctx, redisKey, record, ttl).Err()
+        }
+        if err != nil {
+            logger.Error("Erreur lors de l'enregistrement de la réponse idempotente", zap.String("key", key), zap.Error(err))
+        }
+    }
+}
+
+// replayIdempotentResponse renvoie la réponse mémorisée pour une clé déjà utilisée
+func replayIdempotentResponse(c *gin.Context, redisClient *redis.Client, redisKey string, requestHash string) {
+    defer c.Abort()
+
+    data, err := redisClient.Get(context.Background(), redisKey).Bytes()
+    if err != nil {
+        // La clé a expiré ou a été libérée entre-temps
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "Requête idempotente en cours de traitement, réessayez",
+        })
+        return
+    }
+
+    var record idempotencyRecord
+    if err := json.Unmarshal(data, &record); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error": "Réponse idempotente illisible",
+            "details": err.Error(),
+        })
+        return
+    }
+
+    if record.RequestHash != requestHash {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{
+            "error": "Clé d'idempotence déjà utilisée pour une requête différente",
+        })
+        return
+    }
+
+    if record.Status == 0 {
+        c.JSON(http.StatusConflict, gin.H{
+            "error": "Requête idempotente en cours de traitement, réessayez",
+        })
+        return
+    }
+
+    c.Header("Idempotent-Replayed", "true")
+    c.Data(record.Status, "application/json; charset=utf-8", record.Body)
+}
@@ -0,0 +1,34 @@
+package models
+
+// Statuts d'une ligne de mouvement groupé
+const (
+    BATCH_LINE_APPLIED  = "applique"
+    BATCH_LINE_REJECTED = "rejete"
+    BATCH_LINE_SKIPPED  = "annule"
+)
+
+// BatchMovementLine représente une ligne d'un mouvement groupé. Les champs du
+// mouvement et leur validation sont ceux de StockMovementRequest.
+type BatchMovementLine struct {
+    PieceID string `json:"piece_id" binding:"required"`
+    Type    string `json:"type" binding:"required,oneof=increment decrement"`
+    StockMovementRequest
+}
+
+// BatchMovementRequest représente un lot de mouvements appliqués en tout ou rien
+type BatchMovementRequest struct {
+    Lignes []BatchMovementLine `json:"lignes" binding:"required,min=1,max=100,dive"`
+}
+
+// BatchMovementResult représente le résultat d'une ligne d'un mouvement groupé
+type BatchMovementResult struct {
+    Index         int     `json:"index"`
+    PieceID       string  `json:"piece_id"`
+    Type          string  `json:"type"`
+    Quantite      float64 `json:"quantite"`
+    Statut        string  `json:"statut"` // "applique", "rejete", "annule"
+    Erreur        string  `json:"erreur,omitempty"`
+    MouvementID   string  `json:"mouvement_id,omitempty"`
+    QuantiteAvant float64 `json:"quantite_avant"`
+    QuantiteApres float64 `json:"quantite_apres"`
+}
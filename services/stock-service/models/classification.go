@@ -0,0 +1,100 @@
+package models
+
+import (
+    "encoding/json"
+    "strings"
+    "time"
+)
+
+// Classes ABC (poids dans la valeur consommée) et XYZ (régularité de la
+// demande)
+const (
+    ABC_CLASS_A = "A"
+    ABC_CLASS_B = "B"
+    ABC_CLASS_C = "C"
+
+    XYZ_CLASS_X = "X"
+    XYZ_CLASS_Y = "Y"
+    XYZ_CLASS_Z = "Z"
+)
+
+// Seuils de classification: les pièces cumulant les 80 premiers pourcents de
+// la valeur consommée sont en A, les 15 suivants en B; une demande dont le
+// coefficient de variation hebdomadaire reste sous 0.5 est régulière (X),
+// sous 1 variable (Y), au-delà erratique (Z)
+const (
+    ABC_SHARE_A = 0.80
+    ABC_SHARE_B = 0.95
+
+    XYZ_VARIATION_X = 0.5
+    XYZ_VARIATION_Y = 1.0
+)
+
+// PieceClassification représente le classement ABC/XYZ d'une pièce
+type PieceClassification struct {
+    ABC                  string    `json:"abc"`
+    XYZ                  string    `json:"xyz"`
+    ValeurConsommation   Decimal   `json:"valeur_consommation" swaggertype:"number"` // annuelle, devise du service
+    Devise               string    `json:"devise"`
+    CoefficientVariation float64   `json:"coefficient_variation"` // de la consommation hebdomadaire
+    CalculeeLe           time.Time `json:"calculee_le"`
+}
+
+// ClassificationReport représente le résultat d'un calcul de classification
+type ClassificationReport struct {
+    PeriodeJours int            `json:"periode_jours"`
+    NombrePieces int            `json:"nombre_pieces"`
+    ValeurTotale Decimal        `json:"valeur_totale" swaggertype:"number"`
+    Devise       string         `json:"devise"`
+    Repartition  map[string]int `json:"repartition"` // nombre de pièces par classe combinée ("AX", "CZ"...)
+    UserID       string         `json:"user_id,omitempty"`
+    GeneratedAt  time.Time      `json:"generated_at"`
+}
+
+// Class retourne la classe combinée de la pièce ("AX", "BZ"...), vide si la
+// pièce n'est pas classée
+func (c *PieceClassification) Class() string {
+    if c == nil {
+        return ""
+    }
+    return c.ABC + c.XYZ
+}
+
+// MatchesClass indique si une classe figure dans une liste de classes
+// séparées par des virgules ("A" ou "A,B"), sans tenir compte de la casse;
+// une liste vide accepte toutes les classes
+func MatchesClass(class, list string) bool {
+    if strings.TrimSpace(list) == "" {
+        return true
+    }
+    for _, candidate := range strings.Split(list, ",") {
+        if class != "" && strings.EqualFold(strings.TrimSpace(candidate), class) {
+            return true
+        }
+    }
+    return false
+}
+
+// ClassRank retourne le rang de priorité d'une classe ABC: A d'abord, les
+// pièces non classées en dernier
+func ClassRank(class string) int {
+    switch class {
+    case ABC_CLASS_A:
+        return 0
+    case ABC_CLASS_B:
+        return 1
+    case ABC_CLASS_C:
+        return 2
+    }
+    return 3
+}
+
+// ToJSON convertit le rapport en JSON
+func (r *ClassificationReport) ToJSON() ([]byte, error) {
+    return json.Marshal(r)
+}
+
+// FromJSON crée un rapport depuis du JSON
+func (r *ClassificationReport) FromJSON(data []byte) error {
+    return json.Unmarshal(data, r)
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+    _ "embed"
+    "encoding/json"
+    "time"
+)
+
+// DOMAIN_EVENT_VERSION est la version du schéma des événements de domaine.
+// Elle change uniquement lors d'une modification incompatible: suppression ou
+// renommage d'un champ, changement de type ou de sens. L'ajout d'un champ ou
+// d'un type d'événement ne la modifie pas.
+const DOMAIN_EVENT_VERSION = 1
+
+// DOMAIN_EVENT_SOURCE identifie le service émetteur des événements
+const DOMAIN_EVENT_SOURCE = "stock-service"
+
+// Types d'événements de domaine publiés pour les autres services
+const (
+    DOMAIN_EVENT_PIECE_CREATED     = "piece.created"
+    DOMAIN_EVENT_PIECE_UPDATED     = "piece.updated"
+    DOMAIN_EVENT_PIECE_DELETED     = "piece.deleted"
+    DOMAIN_EVENT_STOCK_INCREMENTED = "stock.incremented"
+    DOMAIN_EVENT_STOCK_DECREMENTED = "stock.decremented"
+    DOMAIN_EVENT_STOCK_TRANSFERRED = "stock.transferred"
+    DOMAIN_EVENT_STOCK_ADJUSTED    = "stock.adjusted"
+)
+
+// DomainEventSchema est le schéma JSON des événements de domaine, livré avec
+// le service
+//
+//go:embed schemas/domain-events.v1.json
+var DomainEventSchema []byte
+
+// DomainEvent représente un événement de domaine publié sur le flux Redis.
+// Son contenu est un contrat entre services, indépendant de la
+// représentation interne des pièces.
+type DomainEvent struct {
+    ID         string               `json:"id"`
+    Type       string               `json:"type"`    // "piece.created", "stock.decremented"...
+    Version    int                  `json:"version"` // version du schéma
+    Source     string               `json:"source"`
+    PieceID    string               `json:"piece_id"`
+    OccurredAt time.Time            `json:"occurred_at"`
+    Piece      DomainEventPiece     `json:"piece"`               // état après le changement, ou dernier état connu pour une suppression
+    Mouvement  *DomainEventMovement `json:"mouvement,omitempty"` // événements "stock.*"
+}
+
+// DomainEventPiece représente l'état d'une pièce dans un événement de domaine
+type DomainEventPiece struct {
+    ID                 string             `json:"id"`
+    Nom                string             `json:"nom"`
+    Categorie          string             `json:"categorie"`
+    CodeEAN            string             `json:"code_ean"`
+    UniteStock         string             `json:"unite_stock"`
+    Quantite           float64            `json:"quantite"`
+    QuantiteReservee   float64            `json:"quantite_reservee"`
+    QuantiteDisponible float64            `json:"quantite_disponible"`
+    SeuilMin           float64            `json:"seuil_min"`
+    PrixUnitaire       Decimal            `json:"prix_unitaire" swaggertype:"number"`
+    Devise             string             `json:"devise"`
+    Emplacements       map[string]float64 `json:"emplacements"`
+    UpdatedAt          time.Time          `json:"updated_at"`
+}
+
+// DomainEventMovement représente le mouvement de stock d'un événement de
+// domaine; les quantités sont exprimées en unité de stock
+type DomainEventMovement struct {
+    ID                     string             `json:"id"`
+    Type                   string             `json:"type"` // "increment", "decrement", "transfert", "ajustement"
+    Quantite               float64            `json:"quantite"`
+    QuantiteAvant          float64            `json:"quantite_avant"`
+    QuantiteApres          float64            `json:"quantite_apres"`
+    Motif                  string             `json:"motif"`
+    Emplacements           map[string]float64 `json:"emplacements,omitempty"`
+    EmplacementSource      string             `json:"emplacement_source,omitempty"`
+    EmplacementDestination string             `json:"emplacement_destination,omitempty"`
+    ReservationID          string             `json:"reservation_id,omitempty"`
+    CommandeID             string             `json:"commande_id,omitempty"`
+    InventaireID           string             `json:"inventaire_id,omitempty"`
+    UserID                 string             `json:"user_id"`
+}
+
+// NewDomainEventPiece extrait d'une pièce son état publié
+func NewDomainEventPiece(piece *Piece) DomainEventPiece {
+    return DomainEventPiece{
+        ID:                 piece.ID,
+        Nom:                piece.Nom,
+        Categorie:          piece.Categorie,
+        CodeEAN:            piece.CodeEAN,
+        UniteStock:         piece.UniteStock,
+        Quantite:           piece.Quantite,
+        QuantiteReservee:   piece.QuantiteReservee,
+        QuantiteDisponible: piece.QuantiteDisponible,
+        SeuilMin:           piece.SeuilMin,
+        PrixUnitaire:       piece.PrixUnitaire,
+        Devise:             piece.Devise,
+        Emplacements:       piece.Emplacements,
+        UpdatedAt:          piece.UpdatedAt,
+    }
+}
+
+// NewDomainEventMovement extrait d'un mouvement son contenu publié
+func NewDomainEventMovement(movement *StockMovement) *DomainEventMovement {
+    return &DomainEventMovement{
+        ID:                     movement.ID,
+        Type:                   movement.Type,
+        Quantite:               movement.Quantite,
+        QuantiteAvant:          movement.QuantiteAvant,
+        QuantiteApres:          movement.QuantiteApres,
+        Motif:                  movement.Motif,
+        Emplacements:           movement.Emplacements,
+        EmplacementSource:      movement.EmplacementSource,
+        EmplacementDestination: movement.EmplacementDestination,
+        ReservationID:          movement.ReservationID,
+        CommandeID:             movement.CommandeID,
+        InventaireID:           movement.InventaireID,
+        UserID:                 movement.UserID,
+    }
+}
+
+// DomainEventType retourne le type d'événement d'un mouvement de stock
+func DomainEventType(movement *StockMovement) string {
+    switch movement.Type {
+    case MOVEMENT_TYPE_INCREMENT:
+        return DOMAIN_EVENT_STOCK_INCREMENTED
+    case MOVEMENT_TYPE_DECREMENT:
+        return DOMAIN_EVENT_STOCK_DECREMENTED
+    case MOVEMENT_TYPE_TRANSFER:
+        return DOMAIN_EVENT_STOCK_TRANSFERRED
+    default:
+        return DOMAIN_EVENT_STOCK_ADJUSTED
+    }
+}
+
+// ToJSON convertit l'événement en JSON
+func (e *DomainEvent) ToJSON() ([]byte, error) {
+    return json.Marshal(e)
+}
+
+// FromJSON crée un événement depuis du JSON
+func (e *DomainEvent) FromJSON(data []byte) error {
+    return json.Unmarshal(data, e)
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+    "time"
+)
+
+// Période par défaut et bornes du rapport de stock dormant, en mois
+const (
+    DEFAULT_DORMANT_MONTHS = 12
+    MAX_DORMANT_MONTHS     = 120
+)
+
+// DormantPiece représente une pièce en stock sans sortie sur la période
+type DormantPiece struct {
+    PieceID         string             `json:"piece_id"`
+    Nom             string             `json:"nom"`
+    Categorie       string             `json:"categorie"`
+    Emplacements    map[string]float64 `json:"emplacements"`
+    Quantite        float64            `json:"quantite"`
+    Unite           string             `json:"unite"`
+    DerniereSortie  *time.Time         `json:"derniere_sortie,omitempty"`
+    DerniereEntree  *time.Time         `json:"derniere_entree,omitempty"`
+    JoursSansSortie int                `json:"jours_sans_sortie"` // depuis la dernière sortie, ou la création
+    CoutUnitaire    Decimal            `json:"cout_unitaire" swaggertype:"number"`
+    Valeur          Decimal            `json:"valeur" swaggertype:"number"`
+}
+
+// DormantStockReport représente le stock immobilisé dans les pièces sans
+// consommation depuis un nombre de mois donné
+type DormantStockReport struct {
+    Mois           int             `json:"mois"`
+    DateLimite     time.Time       `json:"date_limite"` // aucune sortie depuis cette date
+    Methode        string          `json:"methode"`
+    NombrePieces   int             `json:"nombre_pieces"`
+    ValeurTotale   Decimal         `json:"valeur_totale" swaggertype:"number"`
+    Devise         string          `json:"devise"`
+    ParCategorie   []ValuationLine `json:"par_categorie"`
+    ParEmplacement []ValuationLine `json:"par_emplacement"`
+    Pieces         []DormantPiece  `json:"pieces"` // par valeur décroissante
+    GeneratedAt    time.Time       `json:"generated_at"`
+}
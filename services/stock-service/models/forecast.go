@@ -0,0 +1,69 @@
+package models
+
+import (
+    "time"
+)
+
+// Méthodes de prévision de la consommation
+const (
+    FORECAST_METHOD_MOVING_AVERAGE = "moyenne_mobile"
+    FORECAST_METHOD_SMOOTHING      = "lissage_exponentiel"
+)
+
+// Paramètres par défaut et bornes des prévisions
+const (
+    DEFAULT_FORECAST_HISTORY = 12 // mois d'historique
+    DEFAULT_FORECAST_HORIZON = 3  // mois prévus
+    DEFAULT_FORECAST_WINDOW  = 3  // mois de la moyenne mobile
+    MAX_FORECAST_HISTORY     = 60
+    MAX_FORECAST_HORIZON     = 24
+)
+
+// ForecastParams représente les paramètres d'une prévision; les valeurs
+// nulles reprennent les valeurs par défaut. Sans coefficient de lissage, le
+// coefficient qui minimise l'erreur sur l'historique est retenu.
+type ForecastParams struct {
+    Historique int
+    Horizon    int
+    Fenetre    int
+    Alpha      float64
+}
+
+// MonthlyQuantity représente une quantité consommée, ou prévue, sur un mois
+type MonthlyQuantity struct {
+    Mois     string  `json:"mois"` // "2006-01"
+    Quantite float64 `json:"quantite"`
+}
+
+// ForecastErrors représente les erreurs des prévisions à un mois calculées
+// sur l'historique: erreur absolue moyenne (MAE), racine de l'erreur
+// quadratique moyenne (RMSE), erreur absolue moyenne en pourcentage (MAPE,
+// sur les mois consommés) et biais (prévu - réel, en moyenne)
+type ForecastErrors struct {
+    Points int      `json:"points"`
+    MAE    float64  `json:"mae"`
+    RMSE   float64  `json:"rmse"`
+    MAPE   *float64 `json:"mape,omitempty"`
+    Biais  float64  `json:"biais"`
+}
+
+// ForecastModel représente la prévision d'une méthode
+type ForecastModel struct {
+    Methode   string            `json:"methode"`   // "moyenne_mobile", "lissage_exponentiel"
+    Parametre float64           `json:"parametre"` // fenêtre en mois, ou coefficient de lissage
+    Prevision []MonthlyQuantity `json:"prevision"`
+    Total     float64           `json:"total"` // sur l'horizon
+    Erreurs   ForecastErrors    `json:"erreurs"`
+}
+
+// ConsumptionForecast représente la prévision de consommation d'une pièce,
+// établie à partir de ses sorties mensuelles
+type ConsumptionForecast struct {
+    PieceID        string            `json:"piece_id"`
+    Nom            string            `json:"nom"`
+    Unite          string            `json:"unite"`
+    Historique     []MonthlyQuantity `json:"historique"`
+    Modeles        []ForecastModel   `json:"modeles"`
+    MeilleurModele string            `json:"meilleur_modele,omitempty"` // plus faible MAE
+    GeneratedAt    time.Time         `json:"generated_at"`
+}
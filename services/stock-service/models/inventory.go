@@ -0,0 +1,119 @@
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// Statuts d'une session d'inventaire
+const (
+    INVENTORY_STATUS_OPEN      = "ouverte"
+    INVENTORY_STATUS_VALIDATED = "validee"
+    INVENTORY_STATUS_CANCELLED = "annulee"
+
+    INVENTORY_MOTIF = "inventaire"
+)
+
+// InventoryScope représente le périmètre d'une session d'inventaire.
+// Les critères renseignés se cumulent.
+type InventoryScope struct {
+    PieceIDs    []string `json:"piece_ids,omitempty"`
+    Categorie   string   `json:"categorie,omitempty"`
+    Emplacement string   `json:"emplacement,omitempty"`
+    ClasseABC   string   `json:"classe_abc,omitempty"`
+}
+
+// InventoryLine représente le comptage d'une pièce à un emplacement
+type InventoryLine struct {
+    PieceID          string     `json:"piece_id"`
+    Nom              string     `json:"nom"`
+    Emplacement      string     `json:"emplacement"`
+    QuantiteAttendue *float64   `json:"quantite_attendue,omitempty"` // figée à l'ouverture
+    QuantiteComptee  *float64   `json:"quantite_comptee"`
+    QuantiteEnStock  *float64   `json:"quantite_en_stock,omitempty"` // en stock au moment du comptage
+    Ecart            *float64   `json:"ecart,omitempty"`             // quantité comptée - quantité en stock au comptage
+    ComptePar        string     `json:"compte_par,omitempty"`
+    CompteLe         *time.Time `json:"compte_le,omitempty"`
+    MouvementID      string     `json:"mouvement_id,omitempty"`
+}
+
+// InventorySession représente une session d'inventaire physique
+type InventorySession struct {
+    ID          string          `json:"id"`
+    Libelle     string          `json:"libelle"`
+    Statut      string          `json:"statut"` // "ouverte", "validee", "annulee"
+    Aveugle     bool            `json:"aveugle"`
+    Perimetre   InventoryScope  `json:"perimetre"`
+    Lignes      []InventoryLine `json:"lignes"`
+    CreatedBy   string          `json:"created_by"`
+    ValidatedBy string          `json:"validated_by,omitempty"`
+    CreatedAt   time.Time       `json:"created_at"`
+    UpdatedAt   time.Time       `json:"updated_at"`
+    ValidatedAt *time.Time      `json:"validated_at,omitempty"`
+}
+
+// OpenInventoryRequest représente une requête d'ouverture de session d'inventaire
+type OpenInventoryRequest struct {
+    Libelle     string   `json:"libelle" binding:"required,max=200"`
+    PieceIDs    []string `json:"piece_ids,omitempty" binding:"omitempty,dive,required"`
+    Categorie   string   `json:"categorie,omitempty" binding:"max=100"`
+    Emplacement string   `json:"emplacement,omitempty" binding:"max=50"`
+    ClasseABC   string   `json:"classe_abc,omitempty" binding:"omitempty,max=5"` // ex: "A" ou "A,B" pour les comptages tournants
+    Aveugle     bool     `json:"aveugle"`
+}
+
+// InventoryCount représente la quantité comptée pour une pièce
+type InventoryCount struct {
+    PieceID         string   `json:"piece_id" binding:"required"`
+    Emplacement     string   `json:"emplacement,omitempty" binding:"max=50"`
+    QuantiteComptee *float64 `json:"quantite_comptee" binding:"required,min=0"`
+}
+
+// InventoryCountRequest représente une saisie de comptages
+type InventoryCountRequest struct {
+    Comptages []InventoryCount `json:"comptages" binding:"required,min=1,dive"`
+}
+
+// InventoryReport représente le rapport d'écarts d'une session d'inventaire
+type InventoryReport struct {
+    SessionID       string          `json:"session_id"`
+    Libelle         string          `json:"libelle"`
+    Statut          string          `json:"statut"`
+    LignesTotal     int             `json:"lignes_total"`
+    LignesComptees  int             `json:"lignes_comptees"`
+    LignesAvecEcart int             `json:"lignes_avec_ecart"`
+    EcartPositif    float64         `json:"ecart_positif"`
+    EcartNegatif    float64         `json:"ecart_negatif"`
+    ValeurEcart     Decimal         `json:"valeur_ecart" swaggertype:"number"`
+    Devise          string          `json:"devise"`
+    Lignes          []InventoryLine `json:"lignes"`
+}
+
+// IsOpen indique si la session accepte encore des comptages
+func (s *InventorySession) IsOpen() bool {
+    return s.Statut == INVENTORY_STATUS_OPEN
+}
+
+// Blind retourne une copie de la session sans les quantités attendues ni
+// les écarts, pour les compteurs d'une session en aveugle
+func (s *InventorySession) Blind() *InventorySession {
+    blind := *s
+    blind.Lignes = make([]InventoryLine, len(s.Lignes))
+    for i, line := range s.Lignes {
+        line.QuantiteAttendue = nil
+        line.QuantiteEnStock = nil
+        line.Ecart = nil
+        blind.Lignes[i] = line
+    }
+    return &blind
+}
+
+// ToJSON convertit la session en JSON
+func (s *InventorySession) ToJSON() ([]byte, error) {
+    return json.Marshal(s)
+}
+
+// FromJSON crée une session depuis du JSON
+func (s *InventorySession) FromJSON(data []byte) error {
+    return json.Unmarshal(data, s)
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// DEFAULT_KPI_PERIOD_DAYS est la période par défaut des indicateurs de stock
+const DEFAULT_KPI_PERIOD_DAYS = 365
+
+// StockDenial représente une demande de sortie refusée faute de stock
+type StockDenial struct {
+    ID               string    `json:"id"`
+    PieceID          string    `json:"piece_id"`
+    QuantiteDemandee float64   `json:"quantite_demandee"`
+    Unite            string    `json:"unite,omitempty"` // unité saisie, si autre que l'unité de stock
+    Emplacement      string    `json:"emplacement,omitempty"`
+    Motif            string    `json:"motif,omitempty"`
+    Erreur           string    `json:"erreur"`
+    UserID           string    `json:"user_id"`
+    CreatedAt        time.Time `json:"created_at"`
+}
+
+// KPIValues représente les indicateurs de stock d'un périmètre. La rotation
+// rapporte le coût des sorties à la valeur moyenne du stock; la couverture
+// est le nombre de jours de sorties que représente le stock de fin de
+// période au rythme de la période; le taux de service est la part des
+// demandes de sortie servies sans refus pour stock insuffisant. Les stocks
+// sont valorisés au coût unitaire courant des pièces.
+type KPIValues struct {
+    NombrePieces       int      `json:"nombre_pieces"`
+    ValeurSorties      Decimal  `json:"valeur_sorties" swaggertype:"number"`
+    ValeurStockMoyenne Decimal  `json:"valeur_stock_moyenne" swaggertype:"number"`
+    ValeurStockFin     Decimal  `json:"valeur_stock_fin" swaggertype:"number"`
+    Rotation           *float64 `json:"rotation,omitempty"`            // sur la période
+    RotationAnnualisee *float64 `json:"rotation_annualisee,omitempty"` // ramenée à 365 jours
+    JoursCouverture    *float64 `json:"jours_couverture,omitempty"`    // absent sans sortie sur la période
+    DemandesServies    int      `json:"demandes_servies"`
+    DemandesRefusees   int      `json:"demandes_refusees"`
+    TauxService        *float64 `json:"taux_service,omitempty"` // en pourcentage
+}
+
+// CategoryKPIs représente les indicateurs de stock d'une catégorie
+type CategoryKPIs struct {
+    Categorie string `json:"categorie"`
+    KPIValues
+}
+
+// StockKPIs représente les indicateurs de stock sur une période, calculés
+// depuis l'historique des mouvements et des refus de sortie
+type StockKPIs struct {
+    Debut   time.Time `json:"debut"`
+    Fin     time.Time `json:"fin"`
+    Jours   float64   `json:"jours"`
+    Methode string    `json:"methode"`
+    Devise  string    `json:"devise"`
+    KPIValues
+    ParCategorie []CategoryKPIs `json:"par_categorie"`
+    GeneratedAt  time.Time      `json:"generated_at"`
+}
+
+// ToJSON convertit le refus en JSON
+func (d *StockDenial) ToJSON() ([]byte, error) {
+    return json.Marshal(d)
+}
+
+// FromJSON crée un refus depuis du JSON
+func (d *StockDenial) FromJSON(data []byte) error {
+    return json.Unmarshal(data, d)
+}
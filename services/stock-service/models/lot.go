@@ -0,0 +1,102 @@
+package models
+
+import (
+    "time"
+)
+
+// Modes de suivi d'une pièce
+const (
+    TRACKING_NONE   = ""
+    TRACKING_LOT    = "lot"
+    TRACKING_SERIAL = "serie"
+)
+
+// StockLot représente un lot, ou un numéro de série, présent en stock.
+// Pour le suivi par numéro de série, chaque entrée a une quantité de 1.
+type StockLot struct {
+    Numero         string     `json:"numero"`
+    Quantite       float64    `json:"quantite"`
+    DateReception  time.Time  `json:"date_reception"`
+    DatePeremption *time.Time `json:"date_peremption,omitempty"`
+}
+
+// LotQuantity représente une quantité reçue pour un numéro de lot
+type LotQuantity struct {
+    Numero         string     `json:"numero" binding:"required,max=100"`
+    Quantite       float64    `json:"quantite" binding:"required,gt=0"`
+    DatePeremption *time.Time `json:"date_peremption,omitempty"`
+}
+
+// ExpiringLot représente un lot périmé ou proche de la péremption
+type ExpiringLot struct {
+    PieceID        string    `json:"piece_id"`
+    Nom            string    `json:"nom"`
+    Categorie      string    `json:"categorie"`
+    Numero         string    `json:"numero"`
+    Quantite       float64   `json:"quantite"`
+    DatePeremption time.Time `json:"date_peremption"`
+    JoursRestants  int       `json:"jours_restants"`
+    Perime         bool      `json:"perime"`
+    Valeur         Decimal   `json:"valeur" swaggertype:"number"`
+    Devise         string    `json:"devise"`
+}
+
+// SerialTrace représente le parcours d'un numéro de série dans le stock
+type SerialTrace struct {
+    NumeroSerie string          `json:"numero_serie"`
+    PieceID     string          `json:"piece_id"`
+    EnStock     bool            `json:"en_stock"`
+    Mouvements  []StockMovement `json:"mouvements"`
+}
+
+// BuildLots construit les lots initiaux d'une pièce suivie. La date de
+// péremption commune s'applique aux numéros de série et aux lots qui n'en
+// précisent pas.
+func BuildLots(lots []LotQuantity, serials []string, expiresAt *time.Time, receivedAt time.Time) []StockLot {
+    result := make([]StockLot, 0, len(lots)+len(serials))
+    for _, lot := range lots {
+        expiry := lot.DatePeremption
+        if expiry == nil {
+            expiry = expiresAt
+        }
+        result = append(result, StockLot{Numero: lot.Numero, Quantite: lot.Quantite, DateReception: receivedAt, DatePeremption: expiry})
+    }
+    for _, serial := range serials {
+        result = append(result, StockLot{Numero: serial, Quantite: 1, DateReception: receivedAt, DatePeremption: expiresAt})
+    }
+    return result
+}
+
+// IsExpired indique si le lot est périmé à la date donnée
+func (l *StockLot) IsExpired(now time.Time) bool {
+    return l.DatePeremption != nil && !l.DatePeremption.After(now)
+}
+
+// ExpiresBefore indique si le lot périme avant la date limite donnée
+func (l *StockLot) ExpiresBefore(limit time.Time) bool {
+    return l.DatePeremption != nil && !l.DatePeremption.After(limit)
+}
+
+// IsTracked indique si la pièce est suivie par lot ou par numéro de série
+func (p *Piece) IsTracked() bool {
+    return p.Suivi == TRACKING_LOT || p.Suivi == TRACKING_SERIAL
+}
+
+// FindLot retourne l'index d'un lot ou numéro de série en stock, -1 s'il est absent
+func (p *Piece) FindLot(numero string) int {
+    for i, lot := range p.Lots {
+        if lot.Numero == numero {
+            return i
+        }
+    }
+    return -1
+}
+
+// LotsQuantity retourne la quantité totale portée par les lots
+func (p *Piece) LotsQuantity() float64 {
+    total := 0.0
+    for _, lot := range p.Lots {
+        total += lot.Quantite
+    }
+    return p.RoundQuantity(total)
+}
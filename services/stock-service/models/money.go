@@ -0,0 +1,209 @@
+package models
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "strconv"
+    "strings"
+
+    "github.com/go-playground/validator/v10"
+)
+
+// Devises des montants
+const (
+    CURRENCY_XOF = "XOF"
+    CURRENCY_EUR = "EUR"
+
+    // DEFAULT_CURRENCY est la devise des prix enregistrés avant la gestion
+    // des devises
+    DEFAULT_CURRENCY = CURRENCY_XOF
+)
+
+// DECIMAL_PLACES est le nombre de décimales conservées par un Decimal
+const DECIMAL_PLACES = 4
+
+// decimalScale vaut 10^DECIMAL_PLACES
+const decimalScale = 10000
+
+// Decimal représente un montant décimal exact à quatre décimales, stocké en
+// dix-millièmes. Il se sérialise en nombre JSON, comme les anciens prix en
+// virgule flottante, et accepte aussi une chaîne ("45.50") en entrée.
+type Decimal int64
+
+// Currency représente une devise et sa règle d'arrondi
+type Currency struct {
+    Code      string `json:"code"`
+    Libelle   string `json:"libelle"`
+    Decimales int    `json:"decimales"` // décimales des totaux calculés
+}
+
+// currencyCatalog est le catalogue des devises reconnues
+var currencyCatalog = map[string]Currency{
+    CURRENCY_XOF: {Code: CURRENCY_XOF, Libelle: "Franc CFA (BCEAO)", Decimales: 0},
+    CURRENCY_EUR: {Code: CURRENCY_EUR, Libelle: "Euro", Decimales: 2},
+}
+
+// LookupCurrency recherche une devise par code, sans tenir compte de la casse
+func LookupCurrency(code string) (Currency, bool) {
+    currency, ok := currencyCatalog[strings.ToUpper(strings.TrimSpace(code))]
+    return currency, ok
+}
+
+// CanonicalCurrency retourne le code de référence d'une devise; une devise
+// vide correspond à la devise par défaut
+func CanonicalCurrency(code string) string {
+    if strings.TrimSpace(code) == "" {
+        return DEFAULT_CURRENCY
+    }
+    if currency, ok := LookupCurrency(code); ok {
+        return currency.Code
+    }
+    return code
+}
+
+// ValidateCurrency implémente la règle de validation "devise": la devise
+// doit figurer au catalogue
+func ValidateCurrency(fl validator.FieldLevel) bool {
+    _, ok := LookupCurrency(fl.Field().String())
+    return ok
+}
+
+// RoundMoney arrondit un total selon la règle de sa devise: à l'unité pour
+// le franc CFA, au centime pour l'euro
+func RoundMoney(amount Decimal, currency string) Decimal {
+    decimales := 2
+    if c, ok := LookupCurrency(currency); ok {
+        decimales = c.Decimales
+    }
+    return amount.Round(decimales)
+}
+
+// DecimalFromFloat convertit un nombre en virgule flottante, arrondi à quatre décimales
+func DecimalFromFloat(value float64) Decimal {
+    return Decimal(math.Round(value * decimalScale))
+}
+
+// ParseDecimal lit un nombre décimal écrit en base 10, arrondi à quatre décimales
+func ParseDecimal(text string) (Decimal, error) {
+    rat, ok := new(big.Rat).SetString(strings.TrimSpace(text))
+    if !ok {
+        return 0, fmt.Errorf("montant invalide: %q", text)
+    }
+    rat.Mul(rat, new(big.Rat).SetInt64(decimalScale))
+    return roundRat(rat)
+}
+
+// Float64 retourne la valeur approchée du montant
+func (d Decimal) Float64() float64 {
+    return float64(d) / decimalScale
+}
+
+// String formate le montant sans zéros superflus
+func (d Decimal) String() string {
+    sign := ""
+    units := int64(d)
+    if units < 0 {
+        sign = "-"
+        units = -units
+    }
+    integer := units / decimalScale
+    fraction := units % decimalScale
+    if fraction == 0 {
+        return sign + strconv.FormatInt(integer, 10)
+    }
+    digits := strings.TrimRight(fmt.Sprintf("%04d", fraction), "0")
+    return sign + strconv.FormatInt(integer, 10) + "." + digits
+}
+
+// Add retourne la somme de deux montants
+func (d Decimal) Add(other Decimal) Decimal {
+    return d + other
+}
+
+// Sub retourne la différence de deux montants
+func (d Decimal) Sub(other Decimal) Decimal {
+    return d - other
+}
+
+// Mul retourne le produit de deux décimaux, arrondi à quatre décimales
+func (d Decimal) Mul(other Decimal) Decimal {
+    return mulDiv(int64(d), int64(other), decimalScale)
+}
+
+// Div retourne le quotient de deux décimaux, arrondi à quatre décimales;
+// zéro si le diviseur est nul
+func (d Decimal) Div(other Decimal) Decimal {
+    if other == 0 {
+        return 0
+    }
+    return mulDiv(int64(d), decimalScale, int64(other))
+}
+
+// MulQuantity retourne le montant multiplié par une quantité
+func (d Decimal) MulQuantity(quantite float64) Decimal {
+    return d.Mul(DecimalFromFloat(quantite))
+}
+
+// DivQuantity retourne le montant divisé par une quantité, par exemple le
+// coût unitaire d'une sortie à partir de son coût total
+func (d Decimal) DivQuantity(quantite float64) Decimal {
+    return d.Div(DecimalFromFloat(quantite))
+}
+
+// Round arrondit le montant au nombre de décimales donné, au plus proche et
+// à l'écart de zéro pour les demis
+func (d Decimal) Round(decimales int) Decimal {
+    if decimales >= DECIMAL_PLACES {
+        return d
+    }
+    step := int64(math.Pow10(DECIMAL_PLACES - decimales))
+    return mulDiv(int64(d), 1, step) * Decimal(step)
+}
+
+// MarshalJSON sérialise le montant en nombre JSON
+func (d Decimal) MarshalJSON() ([]byte, error) {
+    return []byte(d.String()), nil
+}
+
+// UnmarshalJSON lit un montant depuis un nombre ou une chaîne JSON
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+    text := string(data)
+    if text == "null" {
+        return nil
+    }
+    if unquoted, err := strconv.Unquote(text); err == nil {
+        text = unquoted
+    }
+    value, err := ParseDecimal(text)
+    if err != nil {
+        return err
+    }
+    *d = value
+    return nil
+}
+
+// mulDiv calcule a*b/c sans débordement, arrondi au plus proche
+func mulDiv(a, b, c int64) Decimal {
+    rat := new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(a), big.NewInt(b)), big.NewInt(c))
+    value, _ := roundRat(rat)
+    return value
+}
+
+// roundRat arrondit un rationnel à l'entier le plus proche, à l'écart de zéro
+// pour les demis
+func roundRat(rat *big.Rat) (Decimal, error) {
+    num := new(big.Int).Abs(rat.Num())
+    den := rat.Denom()
+    quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+    if remainder.Lsh(remainder, 1).Cmp(den) >= 0 {
+        quotient.Add(quotient, big.NewInt(1))
+    }
+    if rat.Sign() < 0 {
+        quotient.Neg(quotient)
+    }
+    if !quotient.IsInt64() {
+        return 0, fmt.Errorf("montant invalide: dépassement de capacité")
+    }
+    return Decimal(quotient.Int64()), nil
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+    "encoding/json"
+    "testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+    tests := []struct {
+        text    string
+        want    Decimal
+        wantErr bool
+    }{
+        {"45.50", 455000, false},
+        {" 12 ", 120000, false},
+        {"0.00005", 1, false},   // demi dix-millième arrondi à l'écart de zéro
+        {"0.00004", 0, false},
+        {"-0.00005", -1, false},
+        {"1e3", 10000000, false},
+        {"3/4", 7500, false},
+        {"abc", 0, true},
+        {"", 0, true},
+        {"99999999999999999999", 0, true}, // dépasse un int64 en dix-millièmes
+    }
+
+    for _, tt := range tests {
+        got, err := ParseDecimal(tt.text)
+        if (err != nil) != tt.wantErr {
+            t.Errorf("ParseDecimal(%q): erreur = %v, attendu une erreur: %v", tt.text, err, tt.wantErr)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("ParseDecimal(%q) = %d, attendu %d", tt.text, got, tt.want)
+        }
+    }
+}
+
+func TestDecimalString(t *testing.T) {
+    tests := []struct {
+        value Decimal
+        want  string
+    }{
+        {0, "0"},
+        {455000, "45.5"},
+        {1, "0.0001"},
+        {-1, "-0.0001"},
+        {-125000, "-12.5"},
+        {10000000, "1000"},
+    }
+
+    for _, tt := range tests {
+        if got := tt.value.String(); got != tt.want {
+            t.Errorf("Decimal(%d).String() = %q, attendu %q", int64(tt.value), got, tt.want)
+        }
+    }
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+    tests := []struct {
+        name string
+        got  Decimal
+        want string
+    }{
+        {"produit arrondi", DecimalFromFloat(1.2345).Mul(DecimalFromFloat(1.5)), "1.8518"},
+        {"quotient arrondi", DecimalFromFloat(10).Div(DecimalFromFloat(3)), "3.3333"},
+        {"quotient négatif", DecimalFromFloat(-2).Div(DecimalFromFloat(3)), "-0.6667"},
+        {"diviseur nul", DecimalFromFloat(10).Div(0), "0"},
+        {"coût unitaire", DecimalFromFloat(100).DivQuantity(3), "33.3333"},
+        {"coût total", DecimalFromFloat(33.3333).MulQuantity(3), "99.9999"},
+    }
+
+    for _, tt := range tests {
+        if got := tt.got.String(); got != tt.want {
+            t.Errorf("%s = %s, attendu %s", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestRoundMoney(t *testing.T) {
+    tests := []struct {
+        amount   float64
+        currency string
+        want     string
+    }{
+        {1234.5, CURRENCY_XOF, "1235"},
+        {1234.4999, CURRENCY_XOF, "1234"},
+        {-1234.5, CURRENCY_XOF, "-1235"},
+        {12.345, CURRENCY_EUR, "12.35"},
+        {12.3449, CURRENCY_EUR, "12.34"},
+        {-0.005, CURRENCY_EUR, "-0.01"},
+        {12.345, "eur", "12.35"},
+        {12.345, "USD", "12.35"}, // devise hors catalogue: au centième
+    }
+
+    for _, tt := range tests {
+        got := RoundMoney(DecimalFromFloat(tt.amount), tt.currency)
+        if got.String() != tt.want {
+            t.Errorf("RoundMoney(%g, %s) = %s, attendu %s", tt.amount, tt.currency, got, tt.want)
+        }
+    }
+}
+
+func TestDecimalJSON(t *testing.T) {
+    tests := []struct {
+        json    string
+        want    Decimal
+        wantErr bool
+    }{
+        {`45.5`, 455000, false},
+        {`"45.50"`, 455000, false},
+        {`12`, 120000, false}, // ancien prix entier
+        {`"douze"`, 0, true},
+    }
+
+    for _, tt := range tests {
+        var got Decimal
+        err := json.Unmarshal([]byte(tt.json), &got)
+        if (err != nil) != tt.wantErr {
+            t.Errorf("Unmarshal(%s): erreur = %v, attendu une erreur: %v", tt.json, err, tt.wantErr)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("Unmarshal(%s) = %d, attendu %d", tt.json, got, tt.want)
+        }
+    }
+
+    data, err := json.Marshal(struct {
+        Prix Decimal `json:"prix"`
+    }{DecimalFromFloat(45.5)})
+    if err != nil || string(data) != `{"prix":45.5}` {
+        t.Errorf("Marshal = %s (%v), attendu {\"prix\":45.5}", data, err)
+    }
+}
+
+func TestCanonicalCurrency(t *testing.T) {
+    tests := []struct {
+        code string
+        want string
+    }{
+        {"", DEFAULT_CURRENCY},
+        {"  ", DEFAULT_CURRENCY},
+        {"eur", CURRENCY_EUR},
+        {" xof ", CURRENCY_XOF},
+        {"USD", "USD"},
+    }
+
+    for _, tt := range tests {
+        if got := CanonicalCurrency(tt.code); got != tt.want {
+            t.Errorf("CanonicalCurrency(%q) = %q, attendu %q", tt.code, got, tt.want)
+        }
+    }
+}
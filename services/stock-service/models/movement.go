@@ -0,0 +1,60 @@
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// Types de mouvements de stock
+const (
+    MOVEMENT_TYPE_INCREMENT  = "increment"
+    MOVEMENT_TYPE_DECREMENT  = "decrement"
+    MOVEMENT_TYPE_TRANSFER   = "transfert"
+    MOVEMENT_TYPE_ADJUSTMENT = "ajustement"
+
+    MOTIF_STOCK_INITIAL = "stock initial"
+)
+
+// StockMovement représente un mouvement de stock historisé
+type StockMovement struct {
+    ID                     string             `json:"id"`
+    PieceID                string             `json:"piece_id"`
+    Type                   string             `json:"type"` // "increment", "decrement", "transfert", "ajustement"
+    Quantite               float64            `json:"quantite"`
+    QuantiteSaisie         float64            `json:"quantite_saisie,omitempty"`
+    Unite                  string             `json:"unite,omitempty"`
+    QuantiteAvant          float64            `json:"quantite_avant"`
+    QuantiteApres          float64            `json:"quantite_apres"`
+    Motif                  string             `json:"motif"`
+    ReservationID          string             `json:"reservation_id,omitempty"`
+    InventaireID           string             `json:"inventaire_id,omitempty"`
+    CommandeID             string             `json:"commande_id,omitempty"`
+    Emplacements           map[string]float64 `json:"emplacements,omitempty"`
+    EmplacementSource      string             `json:"emplacement_source,omitempty"`
+    EmplacementDestination string             `json:"emplacement_destination,omitempty"`
+    Lots                   map[string]float64 `json:"lots,omitempty"`
+    NumerosSerie           []string           `json:"numeros_serie,omitempty"`
+    CoutUnitaire           Decimal            `json:"cout_unitaire,omitempty" swaggertype:"number"`
+    CoutSortie             Decimal            `json:"cout_sortie,omitempty" swaggertype:"number"`
+    Devise                 string             `json:"devise,omitempty"`
+    UserID                 string             `json:"user_id"`
+    CreatedAt              time.Time          `json:"created_at"`
+}
+
+// MovementFilter représente les critères de consultation de l'historique
+type MovementFilter struct {
+    From  *time.Time
+    To    *time.Time
+    Page  int
+    Limit int
+}
+
+// ToJSON convertit le mouvement en JSON
+func (m *StockMovement) ToJSON() ([]byte, error) {
+    return json.Marshal(m)
+}
+
+// FromJSON crée un mouvement depuis du JSON
+func (m *StockMovement) FromJSON(data []byte) error {
+    return json.Unmarshal(data, m)
+}
@@ -2,94 +2,307 @@ package models
 
 import (
     "encoding/json"
+    "math"
+    "strings"
     "time"
 )
 
+// DEFAULT_EMPLACEMENT désigne le stock d'une pièce sans emplacement renseigné
+const DEFAULT_EMPLACEMENT = "non-affecte"
+
 // Piece représente une pièce détachée en stock
 type Piece struct {
-    ID           string    `json:"id" redis:"id"`
-    Nom          string    `json:"nom" redis:"nom" binding:"required"`
-    Description  string    `json:"description" redis:"description"`
-    Quantite     int       `json:"quantite" redis:"quantite" binding:"required,min=0"`
-    SeuilMin     int       `json:"seuil_min" redis:"seuil_min" binding:"required,min=1"`
-    PrixUnitaire float64   `json:"prix_unitaire" redis:"prix_unitaire" binding:"required,gt=0"`
-    Fournisseur  string    `json:"fournisseur" redis:"fournisseur"`
-    Emplacement  string    `json:"emplacement" redis:"emplacement"`
-    CodeEAN      string    `json:"code_ean" redis:"code_ean"`
-    Categorie    string    `json:"categorie" redis:"categorie"`
-    UniteStock   string    `json:"unite_stock" redis:"unite_stock" binding:"required"`
-    CreatedAt    time.Time `json:"created_at" redis:"created_at"`
-    UpdatedAt    time.Time `json:"updated_at" redis:"updated_at"`
+    ID                 string               `json:"id" redis:"id"`
+    Nom                string               `json:"nom" redis:"nom" binding:"required"`
+    Description        string               `json:"description" redis:"description"`
+    Quantite           float64              `json:"quantite" redis:"quantite" binding:"required,min=0"`
+    QuantiteReservee   float64              `json:"quantite_reservee" redis:"quantite_reservee"`
+    QuantiteDisponible float64              `json:"quantite_disponible" redis:"quantite_disponible"`
+    SeuilMin           float64              `json:"seuil_min" redis:"seuil_min" binding:"required,gt=0"`
+    PrixUnitaire       Decimal              `json:"prix_unitaire" redis:"prix_unitaire" binding:"required,gt=0" swaggertype:"number"`
+    Devise             string               `json:"devise" redis:"devise"`
+    PrixMoyenPondere   Decimal              `json:"prix_moyen_pondere" redis:"prix_moyen_pondere" swaggertype:"number"`
+    CoutFIFO           Decimal              `json:"cout_fifo" redis:"cout_fifo" swaggertype:"number"`
+    CouchesCout        []CostLayer          `json:"couches_cout,omitempty" redis:"couches_cout"`
+    Fournisseur        string               `json:"fournisseur" redis:"fournisseur"` // nom du fournisseur préféré
+    Fournisseurs       []SupplierLink       `json:"fournisseurs,omitempty" redis:"fournisseurs"`
+    PolitiqueReappro   *ReplenishmentPolicy `json:"politique_reappro,omitempty" redis:"politique_reappro"`
+    Classification     *PieceClassification `json:"classification,omitempty" redis:"classification"`
+    Emplacement        string               `json:"emplacement" redis:"emplacement"`
+    Emplacements       map[string]float64   `json:"emplacements" redis:"emplacements"`
+    Suivi              string               `json:"suivi,omitempty" redis:"suivi"` // "", "lot", "serie"
+    Lots               []StockLot           `json:"lots,omitempty" redis:"lots"`
+    CodeEAN            string               `json:"code_ean" redis:"code_ean"`
+    Categorie          string               `json:"categorie" redis:"categorie"`
+    UniteStock         string               `json:"unite_stock" redis:"unite_stock" binding:"required"`
+    Conversions        map[string]float64   `json:"conversions,omitempty" redis:"conversions"` // unités de stock par unité
+    DerniereEntree     *time.Time           `json:"derniere_entree,omitempty" redis:"derniere_entree"`
+    DerniereSortie     *time.Time           `json:"derniere_sortie,omitempty" redis:"derniere_sortie"`
+    CreatedAt          time.Time            `json:"created_at" redis:"created_at"`
+    UpdatedAt          time.Time            `json:"updated_at" redis:"updated_at"`
 }
 
 // CreatePieceRequest représente une requête de création de pièce
 type CreatePieceRequest struct {
-    Nom          string  `json:"nom" binding:"required,min=3,max=200"`
-    Description  string  `json:"description" binding:"max=1000"`
-    Quantite     int     `json:"quantite" binding:"required,min=0"`
-    SeuilMin     int     `json:"seuil_min" binding:"required,min=1"`
-    PrixUnitaire float64 `json:"prix_unitaire" binding:"required,gt=0"`
-    Fournisseur  string  `json:"fournisseur" binding:"max=200"`
-    Emplacement  string  `json:"emplacement" binding:"max=50"`
-    CodeEAN      string  `json:"code_ean" binding:"max=50"`
-    Categorie    string  `json:"categorie" binding:"required,max=100"`
-    UniteStock   string  `json:"unite_stock" binding:"required,max=20"`
+    Nom              string               `json:"nom" binding:"required,min=3,max=200"`
+    Description      string               `json:"description" binding:"max=1000"`
+    Quantite         float64              `json:"quantite" binding:"required,min=0"`
+    SeuilMin         float64              `json:"seuil_min" binding:"required,gt=0"`
+    PrixUnitaire     Decimal              `json:"prix_unitaire" binding:"required,gt=0" swaggertype:"number"`
+    Devise           string               `json:"devise,omitempty" binding:"omitempty,devise"`
+    Fournisseur      string               `json:"fournisseur" binding:"max=200"`
+    Fournisseurs     []SupplierLink       `json:"fournisseurs,omitempty" binding:"omitempty,dive"`
+    PolitiqueReappro *ReplenishmentPolicy `json:"politique_reappro,omitempty"`
+    Emplacement      string               `json:"emplacement" binding:"max=50"`
+    Emplacements     map[string]float64   `json:"emplacements,omitempty" binding:"omitempty,dive,keys,min=1,max=50,endkeys,min=0"`
+    Suivi            string               `json:"suivi,omitempty" binding:"omitempty,oneof=lot serie"`
+    Lots             []LotQuantity        `json:"lots,omitempty" binding:"omitempty,dive"`
+    NumerosSerie     []string             `json:"numeros_serie,omitempty" binding:"omitempty,dive,required,max=100"`
+    DatePeremption   *time.Time           `json:"date_peremption,omitempty"`
+    CodeEAN          string               `json:"code_ean" binding:"max=50"`
+    Categorie        string               `json:"categorie" binding:"required,max=100"`
+    UniteStock       string               `json:"unite_stock" binding:"required,max=20,unite"`
+    Conversions      map[string]float64   `json:"conversions,omitempty" binding:"omitempty,dive,keys,unite,endkeys,gt=0"`
 }
 
 // UpdatePieceRequest représente une requête de mise à jour de pièce
 type UpdatePieceRequest struct {
-    Nom          *string  `json:"nom,omitempty" binding:"omitempty,min=3,max=200"`
-    Description  *string  `json:"description,omitempty" binding:"omitempty,max=1000"`
-    SeuilMin     *int     `json:"seuil_min,omitempty" binding:"omitempty,min=1"`
-    PrixUnitaire *float64 `json:"prix_unitaire,omitempty" binding:"omitempty,gt=0"`
-    Fournisseur  *string  `json:"fournisseur,omitempty" binding:"omitempty,max=200"`
-    Emplacement  *string  `json:"emplacement,omitempty" binding:"omitempty,max=50"`
-    CodeEAN      *string  `json:"code_ean,omitempty" binding:"omitempty,max=50"`
-    Categorie    *string  `json:"categorie,omitempty" binding:"omitempty,max=100"`
-    UniteStock   *string  `json:"unite_stock,omitempty" binding:"omitempty,max=20"`
-}
-
-// StockMovementRequest représente une requête de mouvement de stock
+    Nom              *string              `json:"nom,omitempty" binding:"omitempty,min=3,max=200"`
+    Description      *string              `json:"description,omitempty" binding:"omitempty,max=1000"`
+    SeuilMin         *float64             `json:"seuil_min,omitempty" binding:"omitempty,gt=0"`
+    PrixUnitaire     *Decimal             `json:"prix_unitaire,omitempty" binding:"omitempty,gt=0" swaggertype:"number"`
+    Fournisseur      *string              `json:"fournisseur,omitempty" binding:"omitempty,max=200"`
+    Fournisseurs     []SupplierLink       `json:"fournisseurs,omitempty" binding:"omitempty,dive"`
+    PolitiqueReappro *ReplenishmentPolicy `json:"politique_reappro,omitempty"` // type "aucune" pour la retirer
+    Emplacement      *string              `json:"emplacement,omitempty" binding:"omitempty,max=50"`
+    CodeEAN          *string              `json:"code_ean,omitempty" binding:"omitempty,max=50"`
+    Categorie        *string              `json:"categorie,omitempty" binding:"omitempty,max=100"`
+    UniteStock       *string              `json:"unite_stock,omitempty" binding:"omitempty,max=20,unite"`
+    Conversions      map[string]float64   `json:"conversions,omitempty" binding:"omitempty,dive,keys,unite,endkeys,gt=0"`
+    Suivi            *string              `json:"suivi,omitempty" binding:"omitempty,oneof=aucun lot serie"`
+}
+
+// StockMovementRequest représente une requête de mouvement de stock.
+// Sans emplacement, une entrée va à l'emplacement principal et une sortie
+// puise d'abord dans l'emplacement principal puis dans les autres.
+// Pour une pièce suivie par numéro de série, les numéros sont obligatoires;
+// pour une pièce suivie par lot, le lot est obligatoire en entrée et, en
+// sortie, choisi par ordre de péremption (FEFO) s'il est omis. Le coût
+// unitaire d'une entrée met à jour le prix moyen pondéré de la pièce.
+// Une unité autre que l'unité de stock est convertie avec les conversions
+// de la pièce; la quantité et le coût unitaire s'entendent dans cette unité.
+// La quantité peut être décimale dans la limite de la précision de l'unité
+// de stock (au dixième pour le litre, entière pour la pièce).
 type StockMovementRequest struct {
-    Quantite int    `json:"quantite" binding:"required,gt=0"`
-    Motif    string `json:"motif,omitempty" binding:"max=500"`
+    Quantite       float64    `json:"quantite" binding:"required,gt=0"`
+    Motif          string     `json:"motif,omitempty" binding:"max=500"`
+    Emplacement    string     `json:"emplacement,omitempty" binding:"max=50"`
+    Lot            string     `json:"lot,omitempty" binding:"max=100"`
+    NumerosSerie   []string   `json:"numeros_serie,omitempty" binding:"omitempty,dive,required,max=100"`
+    DatePeremption *time.Time `json:"date_peremption,omitempty"`
+    CoutUnitaire   *Decimal   `json:"cout_unitaire,omitempty" binding:"omitempty,gt=0" swaggertype:"number"`
+    Devise         string     `json:"devise,omitempty" binding:"omitempty,devise"`
+    Unite          string     `json:"unite,omitempty" binding:"omitempty,unite"`
+}
+
+// TransferRequest représente un transfert de stock entre deux emplacements
+type TransferRequest struct {
+    Source      string  `json:"source" binding:"required,max=50"`
+    Destination string  `json:"destination" binding:"required,max=50,nefield=Source"`
+    Quantite    float64 `json:"quantite" binding:"required,gt=0"`
+    Motif       string  `json:"motif,omitempty" binding:"max=500"`
+}
+
+// PieceFilter représente les critères de filtrage des listes de pièces
+type PieceFilter struct {
+    Categorie     string
+    Emplacement   string
+    FournisseurID string
+    ClasseABC     string // une ou plusieurs classes séparées par des virgules
+    ClasseXYZ     string
 }
 
-// AlerteStock représente une alerte de stock faible
+// Types d'alerte de stock
+const (
+    ALERT_TYPE_LOW_STOCK = "stock_faible"
+    ALERT_TYPE_EXPIRY    = "peremption"
+)
+
+// AlerteStock représente une alerte de stock faible ou de péremption
 type AlerteStock struct {
-    PieceID      string  `json:"piece_id"`
-    Nom          string  `json:"nom"`
-    Quantite     int     `json:"quantite"`
-    SeuilMin     int     `json:"seuil_min"`
-    Severite     string  `json:"severite"` // "critique", "attention"
-    PourcentageStock float64 `json:"pourcentage_stock"`
+    PieceID                string             `json:"piece_id"`
+    Nom                    string             `json:"nom"`
+    Type                   string             `json:"type"` // "stock_faible", "peremption"
+    Quantite               float64            `json:"quantite"`
+    QuantiteReservee       float64            `json:"quantite_reservee"`
+    QuantiteDisponible     float64            `json:"quantite_disponible"`
+    SeuilMin               float64            `json:"seuil_min"`
+    Emplacements           map[string]float64 `json:"emplacements"`
+    Severite               string             `json:"severite"` // "critique", "attention"
+    PourcentageStock       float64            `json:"pourcentage_stock"`
+    QuantiteEnCommande     float64            `json:"quantite_en_commande,omitempty"` // restant à livrer sur les commandes envoyées
+    QuantiteSuggeree       float64            `json:"quantite_suggeree,omitempty"`    // à commander selon la politique de réapprovisionnement
+    Classe                 string             `json:"classe,omitempty"`               // classement ABC/XYZ de la pièce
+    QuantitePerimee        float64            `json:"quantite_perimee,omitempty"`
+    QuantiteBientotPerimee float64            `json:"quantite_bientot_perimee,omitempty"`
+    ProchainePeremption    *time.Time         `json:"prochaine_peremption,omitempty"`
 }
 
 // ToJSON convertit la pièce en JSON
 func (p *Piece) ToJSON() ([]byte, error) {
+    p.RefreshDisponible()
+    p.refreshCost()
     return json.Marshal(p)
 }
 
 // FromJSON crée une pièce depuis du JSON
 func (p *Piece) FromJSON(data []byte) error {
-    return json.Unmarshal(data, p)
+    if err := json.Unmarshal(data, p); err != nil {
+        return err
+    }
+    p.normalizeEmplacements()
+    p.normalizeQuantities()
+    p.normalizeCost()
+    p.RefreshDisponible()
+    return nil
+}
+
+// normalizeCost initialise le prix moyen pondéré et les couches de coût des
+// pièces enregistrées avant leur mise en place: le stock existant forme une
+// couche unique valorisée au prix unitaire. Les prix enregistrés avant la
+// gestion des devises sont exprimés dans la devise par défaut.
+func (p *Piece) normalizeCost() {
+    p.Devise = CanonicalCurrency(p.Devise)
+    if p.PrixMoyenPondere == 0 {
+        p.PrixMoyenPondere = p.PrixUnitaire
+    }
+    if p.CouchesCout == nil && p.Quantite > 0 {
+        p.CouchesCout = []CostLayer{{Quantite: p.Quantite, CoutUnitaire: p.PrixMoyenPondere, DateReception: p.CreatedAt}}
+    }
+    p.refreshCost()
+}
+
+// RefreshDisponible recalcule la quantité disponible (en stock moins réservée)
+func (p *Piece) RefreshDisponible() {
+    p.QuantiteDisponible = p.RoundQuantity(p.Quantite - p.QuantiteReservee)
+}
+
+// DefaultLocation retourne l'emplacement principal de la pièce
+func (p *Piece) DefaultLocation() string {
+    if p.Emplacement == "" {
+        return DEFAULT_EMPLACEMENT
+    }
+    return p.Emplacement
+}
+
+// normalizeEmplacements reprend les pièces enregistrées avant le suivi
+// multi-emplacements: tout le stock est affecté à l'emplacement principal
+func (p *Piece) normalizeEmplacements() {
+    if p.Emplacements != nil {
+        return
+    }
+    p.Emplacements = map[string]float64{p.DefaultLocation(): p.Quantite}
+}
+
+// InitLocations initialise la répartition du stock d'une nouvelle pièce;
+// la quantité devient l'agrégat des emplacements fournis
+func (p *Piece) InitLocations(emplacements map[string]float64) {
+    if len(emplacements) == 0 {
+        p.Emplacements = map[string]float64{p.DefaultLocation(): p.Quantite}
+        return
+    }
+
+    p.Emplacements = make(map[string]float64, len(emplacements))
+    p.Quantite = 0
+    for location, quantite := range emplacements {
+        p.Emplacements[location] = quantite
+        p.Quantite += quantite
+    }
+    p.Quantite = p.RoundQuantity(p.Quantite)
+    if p.Emplacement == "" {
+        for location := range emplacements {
+            if p.Emplacement == "" || location < p.Emplacement {
+                p.Emplacement = location
+            }
+        }
+    }
+}
+
+// ResolveLocation retrouve le nom exact d'un emplacement existant, sans tenir
+// compte de la casse; un emplacement inconnu est retourné tel quel
+func (p *Piece) ResolveLocation(location string) string {
+    if _, ok := p.Emplacements[location]; ok {
+        return location
+    }
+    for existing := range p.Emplacements {
+        if strings.EqualFold(existing, location) {
+            return existing
+        }
+    }
+    return location
+}
+
+// HasLocation indique si la pièce est stockée à l'emplacement donné
+func (p *Piece) HasLocation(location string) bool {
+    _, ok := p.Emplacements[p.ResolveLocation(location)]
+    return ok || strings.EqualFold(p.DefaultLocation(), location)
+}
+
+// LocationQuantity retourne la quantité stockée à un emplacement
+func (p *Piece) LocationQuantity(location string) float64 {
+    return p.Emplacements[p.ResolveLocation(location)]
+}
+
+// AdjustLocation modifie la quantité d'un emplacement, l'agrégat de la pièce
+// et la quantité disponible. Un emplacement secondaire vidé est retiré de la
+// répartition. Les résultats sont arrondis à la précision de l'unité de stock.
+func (p *Piece) AdjustLocation(location string, delta float64) {
+    if p.Emplacements == nil {
+        p.Emplacements = make(map[string]float64)
+    }
+
+    location = p.ResolveLocation(location)
+    p.Emplacements[location] = p.RoundQuantity(p.Emplacements[location] + delta)
+    p.Quantite = p.RoundQuantity(p.Quantite + delta)
+    p.RefreshDisponible()
+
+    if p.Emplacements[location] == 0 && location != p.DefaultLocation() {
+        delete(p.Emplacements, location)
+    }
 }
 
 // IsLowStock vérifie si la pièce est en stock faible
 func (p *Piece) IsLowStock() bool {
-    return p.Quantite <= p.SeuilMin
+    return p.QuantiteDisponible <= p.SeuilMin
 }
 
 // IsCriticalStock vérifie si la pièce est en stock critique
 func (p *Piece) IsCriticalStock() bool {
-    return p.Quantite <= (p.SeuilMin / 2)
+    return p.QuantiteDisponible <= (p.SeuilMin / 2)
 }
 
-// GetStockPercentage calcule le pourcentage de stock par rapport au seuil
+// GetStockPercentage calcule le pourcentage de stock disponible par rapport au seuil
 func (p *Piece) GetStockPercentage() float64 {
     if p.SeuilMin == 0 {
         return 100.0
     }
-    return (float64(p.Quantite) / float64(p.SeuilMin)) * 100.0
+    return math.Round(p.QuantiteDisponible/p.SeuilMin*10000) / 100
 }
 
+
+// RecordMovement met à jour les dates de dernière entrée et de dernière
+// sortie de la pièce; les transferts et ajustements d'inventaire ne
+// comptent ni comme entrée ni comme consommation
+func (p *Piece) RecordMovement(movement *StockMovement) {
+    at := movement.CreatedAt
+    switch movement.Type {
+    case MOVEMENT_TYPE_INCREMENT:
+        if p.DerniereEntree == nil || at.After(*p.DerniereEntree) {
+            p.DerniereEntree = &at
+        }
+    case MOVEMENT_TYPE_DECREMENT:
+        if p.DerniereSortie == nil || at.After(*p.DerniereSortie) {
+            p.DerniereSortie = &at
+        }
+    }
+}
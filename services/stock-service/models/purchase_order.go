@@ -0,0 +1,137 @@
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// Statuts d'une commande fournisseur
+const (
+    PURCHASE_ORDER_STATUS_DRAFT     = "brouillon"
+    PURCHASE_ORDER_STATUS_SENT      = "envoyee"
+    PURCHASE_ORDER_STATUS_PARTIAL   = "partiellement_recue"
+    PURCHASE_ORDER_STATUS_RECEIVED  = "recue"
+    PURCHASE_ORDER_STATUS_CANCELLED = "annulee"
+
+    PURCHASE_ORDER_MOTIF = "réception commande"
+)
+
+// PurchaseOrderLine représente une ligne de commande fournisseur, en unités
+// de stock de la pièce
+type PurchaseOrderLine struct {
+    PieceID       string  `json:"piece_id"`
+    Nom           string  `json:"nom"`
+    Unite         string  `json:"unite"`
+    Quantite      float64 `json:"quantite"`
+    QuantiteRecue float64 `json:"quantite_recue"`
+    PrixUnitaire  Decimal `json:"prix_unitaire" swaggertype:"number"`
+    Montant       Decimal `json:"montant" swaggertype:"number"`
+}
+
+// PurchaseOrder représente une commande fournisseur
+type PurchaseOrder struct {
+    ID            string              `json:"id"`
+    Numero        string              `json:"numero"`
+    FournisseurID string              `json:"fournisseur_id,omitempty"`
+    Fournisseur   string              `json:"fournisseur"`
+    Statut        string              `json:"statut"` // "brouillon", "envoyee", "partiellement_recue", "recue", "annulee"
+    Devise        string              `json:"devise"`
+    Lignes        []PurchaseOrderLine `json:"lignes"`
+    MontantTotal  Decimal             `json:"montant_total" swaggertype:"number"`
+    Notes         string              `json:"notes,omitempty"`
+    CreatedBy     string              `json:"created_by"`
+    CreatedAt     time.Time           `json:"created_at"`
+    UpdatedAt     time.Time           `json:"updated_at"`
+    SentAt        *time.Time          `json:"sent_at,omitempty"`
+    ReceivedAt    *time.Time          `json:"received_at,omitempty"`
+    CancelledAt   *time.Time          `json:"cancelled_at,omitempty"`
+}
+
+// PurchaseOrderLineRequest représente une ligne saisie lors de la
+// modification d'une commande en brouillon
+type PurchaseOrderLineRequest struct {
+    PieceID      string   `json:"piece_id" binding:"required"`
+    Quantite     float64  `json:"quantite" binding:"required,gt=0"`
+    PrixUnitaire *Decimal `json:"prix_unitaire,omitempty" binding:"omitempty,gt=0" swaggertype:"number"`
+}
+
+// UpdatePurchaseOrderRequest représente une modification de commande en
+// brouillon; les lignes fournies remplacent les lignes existantes. Un
+// changement de fournisseur convertit les prix dans la devise du fournisseur.
+type UpdatePurchaseOrderRequest struct {
+    FournisseurID *string                    `json:"fournisseur_id,omitempty" binding:"omitempty,min=1"`
+    Notes         *string                    `json:"notes,omitempty" binding:"omitempty,max=1000"`
+    Lignes        []PurchaseOrderLineRequest `json:"lignes,omitempty" binding:"omitempty,min=1,dive"`
+}
+
+// PurchaseOrderReceipt représente la réception d'une ligne de commande
+type PurchaseOrderReceipt struct {
+    PieceID        string     `json:"piece_id" binding:"required"`
+    Quantite       float64    `json:"quantite" binding:"required,gt=0"`
+    Emplacement    string     `json:"emplacement,omitempty" binding:"max=50"`
+    Lot            string     `json:"lot,omitempty" binding:"max=100"`
+    NumerosSerie   []string   `json:"numeros_serie,omitempty" binding:"omitempty,dive,required,max=100"`
+    DatePeremption *time.Time `json:"date_peremption,omitempty"`
+}
+
+// ReceivePurchaseOrderRequest représente une réception, totale ou partielle,
+// d'une commande envoyée
+type ReceivePurchaseOrderRequest struct {
+    Receptions []PurchaseOrderReceipt `json:"receptions" binding:"required,min=1,dive"`
+}
+
+// PurchaseOrderReceiptResult représente le résultat de la réception d'une commande
+type PurchaseOrderReceiptResult struct {
+    Commande   *PurchaseOrder  `json:"commande"`
+    Mouvements []StockMovement `json:"mouvements"`
+}
+
+// IsOpen indique si la commande attend encore des livraisons
+func (o *PurchaseOrder) IsOpen() bool {
+    return o.Statut == PURCHASE_ORDER_STATUS_SENT || o.Statut == PURCHASE_ORDER_STATUS_PARTIAL
+}
+
+// IsClosed indique si la commande est soldée ou annulée
+func (o *PurchaseOrder) IsClosed() bool {
+    return o.Statut == PURCHASE_ORDER_STATUS_RECEIVED || o.Statut == PURCHASE_ORDER_STATUS_CANCELLED
+}
+
+// FindLine retourne l'index de la ligne d'une pièce, -1 si absente
+func (o *PurchaseOrder) FindLine(pieceID string) int {
+    for i, line := range o.Lignes {
+        if line.PieceID == pieceID {
+            return i
+        }
+    }
+    return -1
+}
+
+// Remaining retourne la quantité restant à livrer sur la ligne
+func (l *PurchaseOrderLine) Remaining() float64 {
+    remaining := l.Quantite - l.QuantiteRecue
+    if remaining < QUANTITY_EPSILON {
+        return 0
+    }
+    return remaining
+}
+
+// RefreshAmounts recalcule le montant de chaque ligne et le total de la
+// commande, arrondis selon sa devise
+func (o *PurchaseOrder) RefreshAmounts() {
+    o.MontantTotal = 0
+    for i := range o.Lignes {
+        line := &o.Lignes[i]
+        line.Montant = RoundMoney(line.PrixUnitaire.MulQuantity(line.Quantite), o.Devise)
+        o.MontantTotal = o.MontantTotal.Add(line.Montant)
+    }
+}
+
+// ToJSON convertit la commande en JSON
+func (o *PurchaseOrder) ToJSON() ([]byte, error) {
+    return json.Marshal(o)
+}
+
+// FromJSON crée une commande depuis du JSON
+func (o *PurchaseOrder) FromJSON(data []byte) error {
+    return json.Unmarshal(data, o)
+}
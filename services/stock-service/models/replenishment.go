@@ -0,0 +1,115 @@
+package models
+
+import (
+    "fmt"
+)
+
+// Politiques de réapprovisionnement
+const (
+    REPLENISHMENT_POLICY_MIN_MAX = "min_max"
+    REPLENISHMENT_POLICY_EOQ     = "qec"
+    REPLENISHMENT_POLICY_NONE    = "aucune" // retire la politique de la pièce
+)
+
+// ReplenishmentPolicy représente la politique de réapprovisionnement d'une
+// pièce. En min/max, le stock passé sous le seuil minimum est recomplété
+// jusqu'au stock maximum. En point de commande avec quantité économique de
+// commande (QEC, formule de Wilson), la quantité commandée équilibre le coût
+// de passation des commandes et le coût de possession du stock. Sans
+// politique, le stock est recomplété jusqu'au double du seuil minimum.
+type ReplenishmentPolicy struct {
+    Type            string  `json:"type" binding:"required,oneof=min_max qec aucune"`
+    StockMax        float64 `json:"stock_max,omitempty" binding:"omitempty,gt=0"`
+    CoutPassation   Decimal `json:"cout_passation,omitempty" binding:"omitempty,gt=0" swaggertype:"number"` // par commande, dans la devise de la pièce
+    TauxPossession  float64 `json:"taux_possession,omitempty" binding:"omitempty,gt=0,lte=1"`               // coût annuel de possession, en fraction de la valeur
+    Conditionnement float64 `json:"conditionnement,omitempty" binding:"omitempty,gt=0"`                     // multiple de commande, en unités de stock
+}
+
+// MIN_REORDER_ISSUES est le nombre minimal de sorties sur la période
+// analysée pour qu'un point de commande calculé puisse remplacer le seuil
+const MIN_REORDER_ISSUES = 3
+
+// ConsumptionStats représente la consommation journalière d'une pièce sur une
+// période, établie à partir des sorties de stock
+type ConsumptionStats struct {
+    JoursObserves int     `json:"jours_observes"`
+    NombreSorties int     `json:"nombre_sorties"`
+    Total         float64 `json:"total"`
+    Moyenne       float64 `json:"moyenne"`    // par jour
+    EcartType     float64 `json:"ecart_type"` // de la consommation journalière
+}
+
+// ReorderPoint représente le point de commande recommandé pour une pièce:
+// la consommation attendue pendant le délai de livraison, plus un stock de
+// sécurité couvrant sa variabilité au niveau de service visé
+type ReorderPoint struct {
+    PieceID             string           `json:"piece_id"`
+    Nom                 string           `json:"nom"`
+    Categorie           string           `json:"categorie"`
+    Unite               string           `json:"unite"`
+    SeuilActuel         float64          `json:"seuil_actuel"`
+    Consommation        ConsumptionStats `json:"consommation"`
+    FournisseurID       string           `json:"fournisseur_id,omitempty"`
+    DelaiLivraisonJours int              `json:"delai_livraison_jours"`
+    NiveauService       float64          `json:"niveau_service"`
+    StockSecurite       float64          `json:"stock_securite"`
+    PointCommande       float64          `json:"point_commande"`
+    Ecart               float64          `json:"ecart"`  // point de commande - seuil actuel
+    Fiable              bool             `json:"fiable"` // historique suffisant pour appliquer le point de commande
+    Raison              string           `json:"raison,omitempty"` // motif d'une pièce ignorée à l'application
+}
+
+// ReorderPointParams représente les paramètres du calcul des points de
+// commande; les valeurs nulles reprennent la configuration du service
+type ReorderPointParams struct {
+    NiveauService float64
+    PeriodeJours  int
+}
+
+// ApplyReorderPointsRequest représente l'application en masse des points de
+// commande recommandés au seuil minimum des pièces. Sans liste de pièces,
+// toutes les pièces du filtre sont concernées.
+type ApplyReorderPointsRequest struct {
+    PieceIDs      []string `json:"piece_ids,omitempty" binding:"omitempty,dive,required"`
+    NiveauService float64  `json:"niveau_service,omitempty" binding:"omitempty,gte=0.5,lt=1"`
+    PeriodeJours  int      `json:"periode_jours,omitempty" binding:"omitempty,min=7,max=730"`
+}
+
+// ReorderPointsResult représente le résultat d'une application en masse
+type ReorderPointsResult struct {
+    Appliques []ReorderPoint `json:"appliques"`
+    Ignores   []ReorderPoint `json:"ignores"` // historique insuffisant, seuil déjà à jour ou seuil refusé
+}
+
+// ValidatePolicy vérifie la cohérence de la politique de réapprovisionnement
+// avec le seuil minimum et la précision de l'unité de stock
+func (p *Piece) ValidatePolicy() error {
+    policy := p.PolitiqueReappro
+    if policy == nil {
+        return nil
+    }
+
+    switch policy.Type {
+    case REPLENISHMENT_POLICY_MIN_MAX:
+        if policy.StockMax <= p.SeuilMin {
+            return fmt.Errorf("politique invalide: le stock maximum (%s) doit dépasser le seuil minimum (%s)",
+                FormatQuantity(policy.StockMax), FormatQuantity(p.SeuilMin))
+        }
+        if err := p.ValidateQuantity(policy.StockMax); err != nil {
+            return err
+        }
+    case REPLENISHMENT_POLICY_EOQ:
+        if policy.CoutPassation <= 0 || policy.TauxPossession <= 0 {
+            return fmt.Errorf("politique invalide: la QEC requiert un coût de passation et un taux de possession")
+        }
+    default:
+        return fmt.Errorf("politique invalide: type %s inconnu", policy.Type)
+    }
+
+    if policy.Conditionnement > 0 {
+        if err := p.ValidateQuantity(policy.Conditionnement); err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// Statuts d'une réservation de stock
+const (
+    RESERVATION_STATUS_ACTIVE   = "active"
+    RESERVATION_STATUS_CONSUMED = "consommee"
+    RESERVATION_STATUS_RELEASED = "liberee"
+)
+
+// Reservation représente des unités d'une pièce réservées pour une intervention
+type Reservation struct {
+    ID                string    `json:"id"`
+    PieceID           string    `json:"piece_id"`
+    InterventionID    string    `json:"intervention_id"`
+    Quantite          float64   `json:"quantite"`
+    QuantiteConsommee float64   `json:"quantite_consommee"`
+    Statut            string    `json:"statut"` // "active", "consommee", "liberee"
+    Motif             string    `json:"motif,omitempty"`
+    UserID            string    `json:"user_id"`
+    CreatedAt         time.Time `json:"created_at"`
+    UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// ReservationRequest représente une requête de réservation de stock
+type ReservationRequest struct {
+    InterventionID string  `json:"intervention_id" binding:"required,max=100"`
+    Quantite       float64 `json:"quantite" binding:"required,gt=0"`
+    Motif          string  `json:"motif,omitempty" binding:"max=500"`
+}
+
+// ConsumeReservationRequest représente la consommation d'une réservation.
+// Sans quantité, toute la réservation est consommée; avec une quantité
+// inférieure, le reliquat est libéré. Le lot ou les numéros de série
+// consommés sont obligatoires pour une pièce suivie.
+type ConsumeReservationRequest struct {
+    Quantite     *float64 `json:"quantite,omitempty" binding:"omitempty,gt=0"`
+    Motif        string   `json:"motif,omitempty" binding:"max=500"`
+    Lot          string   `json:"lot,omitempty" binding:"max=100"`
+    NumerosSerie []string `json:"numeros_serie,omitempty" binding:"omitempty,dive,required,max=100"`
+}
+
+// IsActive indique si la réservation bloque encore du stock
+func (r *Reservation) IsActive() bool {
+    return r.Statut == RESERVATION_STATUS_ACTIVE
+}
+
+// ToJSON convertit la réservation en JSON
+func (r *Reservation) ToJSON() ([]byte, error) {
+    return json.Marshal(r)
+}
+
+// FromJSON crée une réservation depuis du JSON
+func (r *Reservation) FromJSON(data []byte) error {
+    return json.Unmarshal(data, r)
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+    "encoding/json"
+    "strings"
+    "time"
+)
+
+// Types d'événements du flux temps réel des pièces
+const (
+    STOCK_EVENT_CREATED  = "piece.creee"
+    STOCK_EVENT_UPDATED  = "piece.modifiee"
+    STOCK_EVENT_DELETED  = "piece.supprimee"
+    STOCK_EVENT_MOVEMENT = "piece.mouvement"
+)
+
+// StockEvent représente un changement d'une pièce diffusé aux clients du
+// flux: l'état de la pièce après le changement (avant la suppression pour
+// une pièce supprimée) et, pour un mouvement, le mouvement historisé
+type StockEvent struct {
+    ID           string         `json:"id"`
+    Type         string         `json:"type"` // "piece.creee", "piece.modifiee", "piece.supprimee", "piece.mouvement"
+    PieceID      string         `json:"piece_id"`
+    Categorie    string         `json:"categorie"`
+    Emplacements []string       `json:"emplacements"` // emplacements concernés
+    Piece        *Piece         `json:"piece,omitempty"`
+    Mouvement    *StockMovement `json:"mouvement,omitempty"`
+    CreatedAt    time.Time      `json:"created_at"`
+}
+
+// StockEventFilter représente les critères d'abonnement au flux; un critère
+// vide accepte tous les événements
+type StockEventFilter struct {
+    PieceIDs    []string
+    Categorie   string
+    Emplacement string
+}
+
+// Matches indique si un événement satisfait les critères du filtre
+func (f StockEventFilter) Matches(event *StockEvent) bool {
+    if len(f.PieceIDs) > 0 {
+        found := false
+        for _, id := range f.PieceIDs {
+            if id == event.PieceID {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return false
+        }
+    }
+    if f.Categorie != "" && !strings.EqualFold(f.Categorie, event.Categorie) {
+        return false
+    }
+    if f.Emplacement != "" {
+        for _, location := range event.Emplacements {
+            if strings.EqualFold(f.Emplacement, location) {
+                return true
+            }
+        }
+        return false
+    }
+    return true
+}
+
+// ToJSON convertit l'événement en JSON
+func (e *StockEvent) ToJSON() ([]byte, error) {
+    return json.Marshal(e)
+}
+
+// FromJSON crée un événement depuis du JSON
+func (e *StockEvent) FromJSON(data []byte) error {
+    return json.Unmarshal(data, e)
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+    "encoding/json"
+    "strings"
+    "time"
+)
+
+// SupplierContact représente les coordonnées d'un fournisseur
+type SupplierContact struct {
+    Nom       string `json:"nom,omitempty" binding:"max=200"`
+    Email     string `json:"email,omitempty" binding:"omitempty,email,max=200"`
+    Telephone string `json:"telephone,omitempty" binding:"max=50"`
+    Adresse   string `json:"adresse,omitempty" binding:"max=500"`
+}
+
+// Supplier représente un fournisseur de pièces détachées
+type Supplier struct {
+    ID                  string          `json:"id"`
+    Nom                 string          `json:"nom"`
+    Contact             SupplierContact `json:"contact"`
+    DelaiLivraisonJours int             `json:"delai_livraison_jours"` // délai de livraison par défaut
+    Devise              string          `json:"devise"`
+    Actif               bool            `json:"actif"`
+    CreatedAt           time.Time       `json:"created_at"`
+    UpdatedAt           time.Time       `json:"updated_at"`
+}
+
+// SupplierLink rattache une pièce à un fournisseur, avec la référence et les
+// conditions d'achat propres à ce fournisseur. Le prix d'achat est exprimé
+// dans la devise du fournisseur; omis, il reprend le prix unitaire de la pièce.
+type SupplierLink struct {
+    FournisseurID   string  `json:"fournisseur_id" binding:"required"`
+    Reference       string  `json:"reference,omitempty" binding:"max=100"`
+    PrixAchat       Decimal `json:"prix_achat" binding:"omitempty,gt=0" swaggertype:"number"`
+    QuantiteMinimum float64 `json:"quantite_minimum" binding:"min=0"` // minimum de commande, en unités de stock
+    Prefere         bool    `json:"prefere"`
+}
+
+// CreateSupplierRequest représente une requête de création de fournisseur
+type CreateSupplierRequest struct {
+    Nom                 string          `json:"nom" binding:"required,min=2,max=200"`
+    Contact             SupplierContact `json:"contact"`
+    DelaiLivraisonJours *int            `json:"delai_livraison_jours,omitempty" binding:"omitempty,min=0,max=365"`
+    Devise              string          `json:"devise,omitempty" binding:"omitempty,devise"`
+}
+
+// UpdateSupplierRequest représente une requête de mise à jour de fournisseur
+type UpdateSupplierRequest struct {
+    Nom                 *string          `json:"nom,omitempty" binding:"omitempty,min=2,max=200"`
+    Contact             *SupplierContact `json:"contact,omitempty"`
+    DelaiLivraisonJours *int             `json:"delai_livraison_jours,omitempty" binding:"omitempty,min=0,max=365"`
+    Devise              *string          `json:"devise,omitempty" binding:"omitempty,devise"`
+    Actif               *bool            `json:"actif,omitempty"`
+}
+
+// supplierNameReplacer retire les accents courants d'un nom de fournisseur
+var supplierNameReplacer = strings.NewReplacer(
+    "à", "a", "â", "a", "ä", "a", "á", "a",
+    "ç", "c",
+    "é", "e", "è", "e", "ê", "e", "ë", "e",
+    "î", "i", "ï", "i", "í", "i",
+    "ô", "o", "ö", "o", "ó", "o",
+    "ù", "u", "û", "u", "ü", "u", "ú", "u",
+    "ÿ", "y",
+)
+
+// SupplierKey normalise un nom de fournisseur pour la détection des
+// doublons: "SKF Sénégal", "skf  senegal" et "SKF SENEGAL" ont la même clé
+func SupplierKey(name string) string {
+    name = supplierNameReplacer.Replace(strings.ToLower(name))
+    return strings.Join(strings.Fields(name), " ")
+}
+
+// PreferredSupplier retourne le lien du fournisseur préféré de la pièce, à
+// défaut le premier lien; nil si la pièce n'a pas de fournisseur
+func (p *Piece) PreferredSupplier() *SupplierLink {
+    for i := range p.Fournisseurs {
+        if p.Fournisseurs[i].Prefere {
+            return &p.Fournisseurs[i]
+        }
+    }
+    if len(p.Fournisseurs) > 0 {
+        return &p.Fournisseurs[0]
+    }
+    return nil
+}
+
+// SupplierLinkFor retourne le lien de la pièce vers un fournisseur, nil si absent
+func (p *Piece) SupplierLinkFor(supplierID string) *SupplierLink {
+    for i := range p.Fournisseurs {
+        if p.Fournisseurs[i].FournisseurID == supplierID {
+            return &p.Fournisseurs[i]
+        }
+    }
+    return nil
+}
+
+// ToJSON convertit le fournisseur en JSON
+func (s *Supplier) ToJSON() ([]byte, error) {
+    return json.Marshal(s)
+}
+
+// FromJSON crée un fournisseur depuis du JSON
+func (s *Supplier) FromJSON(data []byte) error {
+    return json.Unmarshal(data, s)
+}
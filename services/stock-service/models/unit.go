@@ -0,0 +1,259 @@
+package models
+
+import (
+    "fmt"
+    "math"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/go-playground/validator/v10"
+)
+
+// Dimensions des unités de mesure. Les unités d'une même dimension physique
+// se convertissent entre elles; les conditionnements n'ont de sens que par
+// pièce et nécessitent un facteur de conversion propre à la pièce.
+const (
+    UNIT_DIMENSION_COUNT     = "quantite"
+    UNIT_DIMENSION_VOLUME    = "volume"
+    UNIT_DIMENSION_MASS      = "masse"
+    UNIT_DIMENSION_LENGTH    = "longueur"
+    UNIT_DIMENSION_PACKAGING = "conditionnement"
+)
+
+// UnitOfMeasure représente une unité du catalogue
+type UnitOfMeasure struct {
+    Code      string   `json:"code"`
+    Libelle   string   `json:"libelle"`
+    Dimension string   `json:"dimension"`
+    Facteur   float64  `json:"facteur,omitempty"` // en unité de base de la dimension
+    Decimales int      `json:"decimales"`         // décimales admises pour une quantité
+    Alias     []string `json:"alias,omitempty"`
+}
+
+// unitCatalog est le catalogue des unités de mesure reconnues
+var unitCatalog = []UnitOfMeasure{
+    {Code: "pièce", Libelle: "Pièce", Dimension: UNIT_DIMENSION_COUNT, Facteur: 1, Alias: []string{"piece", "pce", "pc", "u", "unité", "unite"}},
+    {Code: "paire", Libelle: "Paire", Dimension: UNIT_DIMENSION_COUNT, Facteur: 2},
+    {Code: "douzaine", Libelle: "Douzaine", Dimension: UNIT_DIMENSION_COUNT, Facteur: 12},
+    {Code: "litre", Libelle: "Litre", Dimension: UNIT_DIMENSION_VOLUME, Facteur: 1, Decimales: 1, Alias: []string{"l", "litres"}},
+    {Code: "millilitre", Libelle: "Millilitre", Dimension: UNIT_DIMENSION_VOLUME, Facteur: 0.001, Alias: []string{"ml"}},
+    {Code: "m3", Libelle: "Mètre cube", Dimension: UNIT_DIMENSION_VOLUME, Facteur: 1000, Decimales: 3, Alias: []string{"mètre cube", "metre cube"}},
+    {Code: "kg", Libelle: "Kilogramme", Dimension: UNIT_DIMENSION_MASS, Facteur: 1, Decimales: 3, Alias: []string{"kilogramme", "kilo"}},
+    {Code: "g", Libelle: "Gramme", Dimension: UNIT_DIMENSION_MASS, Facteur: 0.001, Alias: []string{"gramme"}},
+    {Code: "tonne", Libelle: "Tonne", Dimension: UNIT_DIMENSION_MASS, Facteur: 1000, Decimales: 3, Alias: []string{"t"}},
+    {Code: "mètre", Libelle: "Mètre", Dimension: UNIT_DIMENSION_LENGTH, Facteur: 1, Decimales: 2, Alias: []string{"m", "metre", "mètres", "metres"}},
+    {Code: "cm", Libelle: "Centimètre", Dimension: UNIT_DIMENSION_LENGTH, Facteur: 0.01, Decimales: 1, Alias: []string{"centimètre", "centimetre"}},
+    {Code: "mm", Libelle: "Millimètre", Dimension: UNIT_DIMENSION_LENGTH, Facteur: 0.001, Alias: []string{"millimètre", "millimetre"}},
+    {Code: "boîte", Libelle: "Boîte", Dimension: UNIT_DIMENSION_PACKAGING, Alias: []string{"boite", "bte"}},
+    {Code: "carton", Libelle: "Carton", Dimension: UNIT_DIMENSION_PACKAGING},
+    {Code: "sachet", Libelle: "Sachet", Dimension: UNIT_DIMENSION_PACKAGING},
+    {Code: "fût", Libelle: "Fût", Dimension: UNIT_DIMENSION_PACKAGING, Alias: []string{"fut", "fûts", "futs"}},
+    {Code: "bidon", Libelle: "Bidon", Dimension: UNIT_DIMENSION_PACKAGING},
+    {Code: "rouleau", Libelle: "Rouleau", Dimension: UNIT_DIMENSION_PACKAGING},
+    {Code: "kit", Libelle: "Kit", Dimension: UNIT_DIMENSION_PACKAGING, Alias: []string{"jeu"}},
+    {Code: "palette", Libelle: "Palette", Dimension: UNIT_DIMENSION_PACKAGING},
+}
+
+// unitIndex associe chaque code et alias, en minuscules, à son unité
+var unitIndex = buildUnitIndex()
+
+func buildUnitIndex() map[string]*UnitOfMeasure {
+    index := make(map[string]*UnitOfMeasure)
+    for i := range unitCatalog {
+        unit := &unitCatalog[i]
+        index[strings.ToLower(unit.Code)] = unit
+        for _, alias := range unit.Alias {
+            index[strings.ToLower(alias)] = unit
+        }
+    }
+    return index
+}
+
+// UnitCatalog retourne le catalogue des unités de mesure, trié par dimension
+func UnitCatalog() []UnitOfMeasure {
+    units := make([]UnitOfMeasure, len(unitCatalog))
+    copy(units, unitCatalog)
+    sort.SliceStable(units, func(i, j int) bool {
+        return units[i].Dimension < units[j].Dimension
+    })
+    return units
+}
+
+// LookupUnit recherche une unité par code ou alias, sans tenir compte de la casse
+func LookupUnit(code string) (*UnitOfMeasure, bool) {
+    unit, ok := unitIndex[strings.ToLower(strings.TrimSpace(code))]
+    return unit, ok
+}
+
+// CanonicalUnit retourne le code de référence d'une unité, ou le code tel
+// quel s'il est inconnu
+func CanonicalUnit(code string) string {
+    if unit, ok := LookupUnit(code); ok {
+        return unit.Code
+    }
+    return code
+}
+
+// ValidateUnit implémente la règle de validation "unite": l'unité doit
+// figurer au catalogue
+func ValidateUnit(fl validator.FieldLevel) bool {
+    _, ok := LookupUnit(fl.Field().String())
+    return ok
+}
+
+// RegisterValidations enregistre les règles de validation propres au service
+func RegisterValidations(v *validator.Validate) error {
+    if err := v.RegisterValidation("unite", ValidateUnit); err != nil {
+        return err
+    }
+    return v.RegisterValidation("devise", ValidateCurrency)
+}
+
+// NormalizeUnits ramène l'unité de stock et les unités des conversions à
+// leur code de référence
+func (p *Piece) NormalizeUnits() {
+    p.UniteStock = CanonicalUnit(p.UniteStock)
+    if len(p.Conversions) == 0 {
+        p.Conversions = nil
+        return
+    }
+    conversions := make(map[string]float64, len(p.Conversions))
+    for unit, factor := range p.Conversions {
+        conversions[CanonicalUnit(unit)] = factor
+    }
+    p.Conversions = conversions
+}
+
+// ConversionFactor retourne le nombre d'unités de stock contenues dans une
+// unité donnée: 1 pour l'unité de stock, le facteur propre à la pièce s'il
+// existe, sinon le rapport entre deux unités d'une même dimension physique
+func (p *Piece) ConversionFactor(unit string) (float64, error) {
+    from, ok := LookupUnit(unit)
+    if !ok {
+        return 0, fmt.Errorf("unité invalide: %s inconnue", unit)
+    }
+    if from.Code == CanonicalUnit(p.UniteStock) {
+        return 1, nil
+    }
+    if factor, ok := p.Conversions[from.Code]; ok {
+        return factor, nil
+    }
+
+    to, ok := LookupUnit(p.UniteStock)
+    if ok && from.Dimension == to.Dimension && from.Dimension != UNIT_DIMENSION_PACKAGING {
+        return from.Facteur / to.Facteur, nil
+    }
+
+    return 0, fmt.Errorf("unité invalide: aucune conversion de %s vers %s pour la pièce %s", from.Code, p.UniteStock, p.ID)
+}
+
+// ConvertQuantity convertit une quantité exprimée dans une unité donnée en
+// unités de stock. Le résultat doit respecter la précision de l'unité de
+// stock: 500 ml font 0.5 litre, mais 3 mm ne font pas un nombre de mètres
+// au centième près.
+func (p *Piece) ConvertQuantity(quantite float64, unit string) (float64, float64, error) {
+    if unit == "" {
+        if err := p.ValidateQuantity(quantite); err != nil {
+            return 0, 0, err
+        }
+        return quantite, 1, nil
+    }
+
+    factor, err := p.ConversionFactor(unit)
+    if err != nil {
+        return 0, 0, err
+    }
+
+    converted := quantite * factor
+    rounded := p.RoundQuantity(converted)
+    if math.Abs(converted-rounded) > QUANTITY_EPSILON || rounded <= 0 {
+        return 0, 0, fmt.Errorf("unité invalide: %s %s ne correspond pas à une quantité de %s à %d décimale(s)",
+            FormatQuantity(quantite), unit, p.UniteStock, p.QuantityDecimals())
+    }
+    return rounded, factor, nil
+}
+
+// QUANTITY_EPSILON absorbe les erreurs d'arrondi des calculs en virgule flottante
+const QUANTITY_EPSILON = 1e-9
+
+// RoundQuantity arrondit une quantité au nombre de décimales donné
+func RoundQuantity(quantite float64, decimales int) float64 {
+    scale := math.Pow(10, float64(decimales))
+    return math.Round(quantite*scale) / scale
+}
+
+// FormatQuantity formate une quantité sans décimales superflues
+func FormatQuantity(quantite float64) string {
+    return strconv.FormatFloat(quantite, 'f', -1, 64)
+}
+
+// QuantityDecimals retourne le nombre de décimales admis par l'unité de
+// stock de la pièce; une unité hors catalogue n'admet que des entiers
+func (p *Piece) QuantityDecimals() int {
+    if unit, ok := LookupUnit(p.UniteStock); ok {
+        return unit.Decimales
+    }
+    return 0
+}
+
+// RoundQuantity arrondit une quantité à la précision de l'unité de stock
+func (p *Piece) RoundQuantity(quantite float64) float64 {
+    return RoundQuantity(quantite, p.QuantityDecimals())
+}
+
+// CeilQuantity arrondit une quantité à la précision de l'unité de stock, par
+// excès
+func (p *Piece) CeilQuantity(quantite float64) float64 {
+    scale := math.Pow(10, float64(p.QuantityDecimals()))
+    ceiled := math.Ceil(quantite*scale-QUANTITY_EPSILON) / scale
+    if ceiled == 0 {
+        return 0 // pas de zéro négatif
+    }
+    return ceiled
+}
+
+// ValidateQuantity vérifie qu'une quantité en unités de stock respecte la
+// précision de l'unité: 2.5 litres est admis, 2.5 pièces ne l'est pas
+func (p *Piece) ValidateQuantity(quantite float64) error {
+    if math.Abs(quantite-p.RoundQuantity(quantite)) > QUANTITY_EPSILON {
+        return fmt.Errorf("quantité invalide: %s %s dépasse la précision de l'unité (%d décimale(s))",
+            FormatQuantity(quantite), p.UniteStock, p.QuantityDecimals())
+    }
+    return nil
+}
+
+// ValidateQuantities vérifie que le stock, le seuil et la répartition par
+// emplacement de la pièce respectent la précision de l'unité de stock
+func (p *Piece) ValidateQuantities() error {
+    if err := p.ValidateQuantity(p.Quantite); err != nil {
+        return err
+    }
+    if err := p.ValidateQuantity(p.SeuilMin); err != nil {
+        return err
+    }
+    for _, quantite := range p.Emplacements {
+        if err := p.ValidateQuantity(quantite); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// normalizeQuantities arrondit les quantités enregistrées à la précision de
+// l'unité de stock. Les enregistrements antérieurs aux quantités décimales
+// contiennent des entiers, relus tels quels.
+func (p *Piece) normalizeQuantities() {
+    p.Quantite = p.RoundQuantity(p.Quantite)
+    p.QuantiteReservee = p.RoundQuantity(p.QuantiteReservee)
+    p.SeuilMin = p.RoundQuantity(p.SeuilMin)
+    for location, quantite := range p.Emplacements {
+        p.Emplacements[location] = p.RoundQuantity(quantite)
+    }
+    for i := range p.Lots {
+        p.Lots[i].Quantite = p.RoundQuantity(p.Lots[i].Quantite)
+    }
+    for i := range p.CouchesCout {
+        p.CouchesCout[i].Quantite = p.RoundQuantity(p.CouchesCout[i].Quantite)
+    }
+}
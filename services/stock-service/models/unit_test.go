@@ -0,0 +1,103 @@
+package models
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestCanonicalUnit(t *testing.T) {
+    tests := []struct {
+        code string
+        want string
+    }{
+        {"L", "litre"},
+        {" litres ", "litre"},
+        {"pce", "pièce"},
+        {"Metre", "mètre"},
+        {"fut", "fût"},
+        {"gallon", "gallon"}, // hors catalogue: conservée
+    }
+
+    for _, tt := range tests {
+        if got := CanonicalUnit(tt.code); got != tt.want {
+            t.Errorf("CanonicalUnit(%q) = %q, attendu %q", tt.code, got, tt.want)
+        }
+    }
+}
+
+func TestConvertQuantity(t *testing.T) {
+    tests := []struct {
+        name        string
+        stock       string
+        conversions map[string]float64
+        quantite    float64
+        unit        string
+        want        float64
+        wantErr     string
+    }{
+        {"unité de stock", "litre", nil, 2.5, "", 2.5, ""},
+        {"alias de l'unité de stock", "litre", nil, 2.5, "l", 2.5, ""},
+        {"même dimension", "litre", nil, 500, "ml", 0.5, ""},
+        {"vers une unité plus petite", "kg", nil, 1.5, "tonne", 1500, ""},
+        {"comptage", "pièce", nil, 2, "douzaine", 24, ""},
+        {"conditionnement propre à la pièce", "pièce", map[string]float64{"boîte": 50}, 3, "boite", 150, ""},
+        {"conditionnement sans facteur", "pièce", nil, 1, "carton", 0, "unité invalide: aucune conversion"},
+        {"dimensions différentes", "litre", nil, 1, "kg", 0, "unité invalide: aucune conversion"},
+        {"unité inconnue", "litre", nil, 1, "gallon", 0, "unité invalide: gallon inconnue"},
+        {"précision de l'unité de stock", "mètre", nil, 3, "mm", 0, "unité invalide: 3 mm"},
+        {"quantité fractionnaire de pièces", "pièce", nil, 1.5, "", 0, "quantité invalide"},
+        {"arrondi en virgule flottante", "litre", nil, 300, "ml", 0.3, ""},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            piece := &Piece{ID: "P1", UniteStock: tt.stock, Conversions: tt.conversions}
+            piece.NormalizeUnits()
+
+            got, _, err := piece.ConvertQuantity(tt.quantite, tt.unit)
+            if tt.wantErr != "" {
+                if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+                    t.Fatalf("erreur = %v, attendu %q", err, tt.wantErr)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("erreur inattendue: %v", err)
+            }
+            if got != tt.want {
+                t.Errorf("quantité = %g, attendu %g", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestQuantityPrecision(t *testing.T) {
+    tests := []struct {
+        unit      string
+        quantite  float64
+        wantRound float64
+        wantCeil  float64
+        wantValid bool
+    }{
+        {"pièce", 2, 2, 2, true},
+        {"pièce", 2.4, 2, 3, false},
+        {"litre", 2.5, 2.5, 2.5, true},
+        {"litre", 2.45, 2.5, 2.5, false},
+        {"kg", 0.1 + 0.2, 0.3, 0.3, true}, // 0.30000000000000004
+        {"mètre", 1.005, 1, 1.01, false},
+        {"gallon", 1.5, 2, 2, false}, // hors catalogue: entiers seulement
+    }
+
+    for _, tt := range tests {
+        piece := &Piece{UniteStock: tt.unit}
+        if got := piece.RoundQuantity(tt.quantite); got != tt.wantRound {
+            t.Errorf("%s: RoundQuantity(%g) = %g, attendu %g", tt.unit, tt.quantite, got, tt.wantRound)
+        }
+        if got := piece.CeilQuantity(tt.quantite); got != tt.wantCeil {
+            t.Errorf("%s: CeilQuantity(%g) = %g, attendu %g", tt.unit, tt.quantite, got, tt.wantCeil)
+        }
+        if err := piece.ValidateQuantity(tt.quantite); (err == nil) != tt.wantValid {
+            t.Errorf("%s: ValidateQuantity(%g) = %v, attendu valide: %v", tt.unit, tt.quantite, err, tt.wantValid)
+        }
+    }
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// Méthodes de valorisation du stock
+const (
+    VALUATION_METHOD_AVERAGE = "pmp"
+    VALUATION_METHOD_FIFO    = "fifo"
+)
+
+// Origines d'un changement de coût moyen pondéré
+const (
+    COST_SOURCE_INITIAL = "initial"
+    COST_SOURCE_RECEIPT = "reception"
+)
+
+// CostChange représente une évolution du prix moyen pondéré (PMP) d'une pièce
+type CostChange struct {
+    PieceID       string    `json:"piece_id"`
+    Source        string    `json:"source"` // "initial", "reception"
+    AncienCout    Decimal   `json:"ancien_cout" swaggertype:"number"`
+    NouveauCout   Decimal   `json:"nouveau_cout" swaggertype:"number"`
+    QuantiteAvant float64   `json:"quantite_avant"`
+    QuantiteRecue float64   `json:"quantite_recue"`
+    CoutReception Decimal   `json:"cout_reception" swaggertype:"number"`
+    Devise        string    `json:"devise"`
+    MouvementID   string    `json:"mouvement_id,omitempty"`
+    UserID        string    `json:"user_id,omitempty"`
+    CreatedAt     time.Time `json:"created_at"`
+}
+
+// CostLayer représente une couche de coût FIFO: les unités d'une entrée
+// restant en stock et leur coût unitaire de réception
+type CostLayer struct {
+    Quantite      float64   `json:"quantite"`
+    CoutUnitaire  Decimal   `json:"cout_unitaire" swaggertype:"number"`
+    DateReception time.Time `json:"date_reception"`
+    MouvementID   string    `json:"mouvement_id,omitempty"`
+}
+
+// ValuationLine représente la valeur du stock d'un regroupement
+type ValuationLine struct {
+    Cle          string  `json:"cle"`
+    NombrePieces int     `json:"nombre_pieces"`
+    Quantite     float64 `json:"quantite"`
+    Valeur       Decimal `json:"valeur" swaggertype:"number"`
+}
+
+// ValuationReport représente la valorisation du stock
+type ValuationReport struct {
+    Methode        string          `json:"methode"`
+    NombrePieces   int             `json:"nombre_pieces"`
+    QuantiteTotale float64         `json:"quantite_totale"`
+    ValeurTotale   Decimal         `json:"valeur_totale" swaggertype:"number"`
+    Devise         string          `json:"devise"`
+    ParCategorie   []ValuationLine `json:"par_categorie"`
+    ParEmplacement []ValuationLine `json:"par_emplacement"`
+    GeneratedAt    time.Time       `json:"generated_at"`
+}
+
+// ToJSON convertit le changement de coût en JSON
+func (c *CostChange) ToJSON() ([]byte, error) {
+    return json.Marshal(c)
+}
+
+// FromJSON crée un changement de coût depuis du JSON
+func (c *CostChange) FromJSON(data []byte) error {
+    return json.Unmarshal(data, c)
+}
+
+// UnitCost retourne le coût unitaire de valorisation de la pièce selon la
+// méthode donnée ("fifo" ou, par défaut, prix moyen pondéré)
+func (p *Piece) UnitCost(method string) Decimal {
+    if method == VALUATION_METHOD_FIFO {
+        return p.CoutFIFO
+    }
+    return p.PrixMoyenPondere
+}
+
+// AddCostLayer ajoute une couche de coût pour des unités reçues
+func (p *Piece) AddCostLayer(quantite float64, cost Decimal, receivedAt time.Time, movementID string) {
+    p.CouchesCout = append(p.CouchesCout, CostLayer{
+        Quantite:      quantite,
+        CoutUnitaire:  cost,
+        DateReception: receivedAt,
+        MouvementID:   movementID,
+    })
+    p.refreshCost()
+}
+
+// ConsumeCostLayers retire des unités des couches les plus anciennes et
+// retourne leur coût exact, non arrondi. Les unités non couvertes par une
+// couche sont valorisées au prix moyen pondéré.
+func (p *Piece) ConsumeCostLayers(quantite float64) Decimal {
+    var total Decimal
+    remaining := quantite
+    for remaining > QUANTITY_EPSILON && len(p.CouchesCout) > 0 {
+        layer := &p.CouchesCout[0]
+        taken := layer.Quantite
+        if taken > remaining {
+            taken = remaining
+        }
+        total = total.Add(layer.CoutUnitaire.MulQuantity(taken))
+        layer.Quantite = p.RoundQuantity(layer.Quantite - taken)
+        remaining = p.RoundQuantity(remaining - taken)
+        if layer.Quantite <= 0 {
+            p.CouchesCout = p.CouchesCout[1:]
+        }
+    }
+    total = total.Add(p.PrixMoyenPondere.MulQuantity(remaining))
+    p.refreshCost()
+    return total
+}
+
+// refreshCost recalcule le coût unitaire moyen des couches FIFO restantes
+func (p *Piece) refreshCost() {
+    quantite := 0.0
+    var valeur Decimal
+    for _, layer := range p.CouchesCout {
+        quantite += layer.Quantite
+        valeur = valeur.Add(layer.CoutUnitaire.MulQuantity(layer.Quantite))
+    }
+    if quantite > QUANTITY_EPSILON {
+        p.CoutFIFO = valeur.DivQuantity(quantite)
+    } else {
+        p.CoutFIFO = p.PrixMoyenPondere
+    }
+}
+
+// ReceiveAtCost met à jour le prix moyen pondéré pour une entrée de quantite
+// unités au coût unitaire donné; retourne l'ancien PMP
+func (p *Piece) ReceiveAtCost(quantite float64, cost Decimal) Decimal {
+    old := p.PrixMoyenPondere
+    if p.Quantite <= 0 {
+        p.PrixMoyenPondere = cost
+        return old
+    }
+    valeur := old.MulQuantity(p.Quantite).Add(cost.MulQuantity(quantite))
+    p.PrixMoyenPondere = valeur.DivQuantity(p.Quantite + quantite)
+    return old
+}
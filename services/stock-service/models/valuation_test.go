@@ -0,0 +1,102 @@
+package models
+
+import (
+    "testing"
+    "time"
+)
+
+func TestConsumeCostLayers(t *testing.T) {
+    day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    layers := func() []CostLayer {
+        return []CostLayer{
+            {Quantite: 10, CoutUnitaire: DecimalFromFloat(5), DateReception: day},
+            {Quantite: 5, CoutUnitaire: DecimalFromFloat(8), DateReception: day.AddDate(0, 0, 1)},
+        }
+    }
+
+    tests := []struct {
+        name       string
+        unit       string
+        quantite   float64
+        want       string
+        wantLayers []float64
+        wantFIFO   string
+    }{
+        {"dans la première couche", "pièce", 4, "20", []float64{6, 5}, "6.3636"},
+        {"première couche épuisée", "pièce", 10, "50", []float64{5}, "8"},
+        {"à cheval sur deux couches", "pièce", 12, "66", []float64{3}, "8"},
+        {"toutes les couches", "pièce", 15, "90", nil, "6"},
+        {"au-delà des couches, au PMP", "pièce", 17, "102", nil, "6"},
+        {"quantité décimale", "litre", 10.5, "54", []float64{4.5}, "8"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            piece := &Piece{UniteStock: tt.unit, PrixMoyenPondere: DecimalFromFloat(6), CouchesCout: layers()}
+
+            got := piece.ConsumeCostLayers(tt.quantite)
+            if got.String() != tt.want {
+                t.Errorf("coût = %s, attendu %s", got, tt.want)
+            }
+            if len(piece.CouchesCout) != len(tt.wantLayers) {
+                t.Fatalf("couches restantes = %d, attendu %d", len(piece.CouchesCout), len(tt.wantLayers))
+            }
+            for i, quantite := range tt.wantLayers {
+                if piece.CouchesCout[i].Quantite != quantite {
+                    t.Errorf("couche %d: quantité = %g, attendu %g", i, piece.CouchesCout[i].Quantite, quantite)
+                }
+            }
+            if piece.CoutFIFO.String() != tt.wantFIFO {
+                t.Errorf("coût FIFO = %s, attendu %s", piece.CoutFIFO, tt.wantFIFO)
+            }
+        })
+    }
+}
+
+func TestReceiveAtCost(t *testing.T) {
+    tests := []struct {
+        name     string
+        quantite float64
+        pmp      float64
+        received float64
+        cost     float64
+        want     string
+    }{
+        {"moyenne pondérée", 10, 5, 10, 7, "6"},
+        {"arrondi à quatre décimales", 3, 1, 4, 2, "1.5714"},
+        {"stock vide: coût de la réception", 0, 5, 10, 7, "7"},
+        {"stock négatif: coût de la réception", -2, 5, 10, 7, "7"},
+        {"réception gratuite", 10, 5, 10, 0, "2.5"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            piece := &Piece{Quantite: tt.quantite, PrixMoyenPondere: DecimalFromFloat(tt.pmp)}
+            old := piece.ReceiveAtCost(tt.received, DecimalFromFloat(tt.cost))
+            if old != DecimalFromFloat(tt.pmp) {
+                t.Errorf("ancien PMP = %s, attendu %g", old, tt.pmp)
+            }
+            if piece.PrixMoyenPondere.String() != tt.want {
+                t.Errorf("PMP = %s, attendu %s", piece.PrixMoyenPondere, tt.want)
+            }
+        })
+    }
+}
+
+func TestUnitCost(t *testing.T) {
+    piece := &Piece{PrixMoyenPondere: DecimalFromFloat(6), CoutFIFO: DecimalFromFloat(8)}
+
+    tests := []struct {
+        method string
+        want   string
+    }{
+        {VALUATION_METHOD_FIFO, "8"},
+        {VALUATION_METHOD_AVERAGE, "6"},
+        {"", "6"},
+    }
+    for _, tt := range tests {
+        if got := piece.UnitCost(tt.method); got.String() != tt.want {
+            t.Errorf("UnitCost(%q) = %s, attendu %s", tt.method, got, tt.want)
+        }
+    }
+}
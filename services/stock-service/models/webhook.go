@@ -0,0 +1,129 @@
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// Événements notifiés aux abonnements webhook: passage sous le seuil
+// minimum, passage sous le seuil critique (moitié du seuil minimum) et retour
+// au-dessus du seuil minimum, évalués sur la quantité disponible
+const (
+    WEBHOOK_EVENT_LOW_STOCK = "stock.faible"
+    WEBHOOK_EVENT_CRITICAL  = "stock.critique"
+    WEBHOOK_EVENT_RESTORED  = "stock.retabli"
+)
+
+// Statuts d'une livraison webhook
+const (
+    WEBHOOK_DELIVERY_PENDING   = "en_attente"
+    WEBHOOK_DELIVERY_DELIVERED = "livre"
+    WEBHOOK_DELIVERY_FAILED    = "echec"
+)
+
+// WebhookSubscription représente un abonnement aux franchissements de seuil.
+// Le secret signe les envois (HMAC-SHA256) et n'est jamais retourné.
+type WebhookSubscription struct {
+    ID          string    `json:"id"`
+    URL         string    `json:"url"`
+    Evenements  []string  `json:"evenements"` // vide: tous les événements
+    Secret      string    `json:"secret,omitempty"`
+    Description string    `json:"description,omitempty"`
+    Actif       bool      `json:"actif"`
+    UserID      string    `json:"user_id,omitempty"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreateWebhookRequest représente une requête de création d'abonnement
+type CreateWebhookRequest struct {
+    URL         string   `json:"url" binding:"required,url,max=2000"`
+    Evenements  []string `json:"evenements,omitempty" binding:"omitempty,dive,oneof=stock.faible stock.critique stock.retabli"`
+    Secret      string   `json:"secret" binding:"required,min=16,max=200"`
+    Description string   `json:"description,omitempty" binding:"max=500"`
+}
+
+// UpdateWebhookRequest représente une requête de mise à jour d'abonnement
+type UpdateWebhookRequest struct {
+    URL         *string  `json:"url,omitempty" binding:"omitempty,url,max=2000"`
+    Evenements  []string `json:"evenements,omitempty" binding:"omitempty,dive,oneof=stock.faible stock.critique stock.retabli"`
+    Secret      *string  `json:"secret,omitempty" binding:"omitempty,min=16,max=200"`
+    Description *string  `json:"description,omitempty" binding:"omitempty,max=500"`
+    Actif       *bool    `json:"actif,omitempty"`
+}
+
+// WebhookEvent représente le contenu envoyé lors d'un franchissement de seuil
+type WebhookEvent struct {
+    ID                 string    `json:"id"`
+    Type               string    `json:"type"` // "stock.faible", "stock.critique", "stock.retabli"
+    PieceID            string    `json:"piece_id"`
+    Nom                string    `json:"nom"`
+    Categorie          string    `json:"categorie"`
+    Quantite           float64   `json:"quantite"`
+    QuantiteDisponible float64   `json:"quantite_disponible"`
+    SeuilMin           float64   `json:"seuil_min"`
+    SeuilCritique      float64   `json:"seuil_critique"`
+    Unite              string    `json:"unite"`
+    MouvementID        string    `json:"mouvement_id,omitempty"`
+    MouvementType      string    `json:"mouvement_type,omitempty"`
+    CreatedAt          time.Time `json:"created_at"`
+}
+
+// WebhookDelivery représente l'envoi d'un événement à un abonnement et le
+// suivi de ses tentatives
+type WebhookDelivery struct {
+    ID                 string       `json:"id"`
+    WebhookID          string       `json:"webhook_id"`
+    URL                string       `json:"url"` // au moment de la dernière tentative
+    Evenement          WebhookEvent `json:"evenement"`
+    Statut             string       `json:"statut"` // "en_attente", "livre", "echec"
+    Tentatives         int          `json:"tentatives"`
+    ProchaineTentative *time.Time   `json:"prochaine_tentative,omitempty"`
+    DernierStatutHTTP  int          `json:"dernier_statut_http,omitempty"`
+    DerniereErreur     string       `json:"derniere_erreur,omitempty"`
+    LivreLe            *time.Time   `json:"livre_le,omitempty"`
+    CreatedAt          time.Time    `json:"created_at"`
+    UpdatedAt          time.Time    `json:"updated_at"`
+}
+
+// Accepts indique si l'abonnement est actif et porte sur le type d'événement
+func (w *WebhookSubscription) Accepts(eventType string) bool {
+    if !w.Actif {
+        return false
+    }
+    if len(w.Evenements) == 0 {
+        return true
+    }
+    for _, candidate := range w.Evenements {
+        if candidate == eventType {
+            return true
+        }
+    }
+    return false
+}
+
+// Masked retourne une copie de l'abonnement sans son secret
+func (w WebhookSubscription) Masked() WebhookSubscription {
+    w.Secret = ""
+    return w
+}
+
+// ToJSON convertit l'abonnement en JSON
+func (w *WebhookSubscription) ToJSON() ([]byte, error) {
+    return json.Marshal(w)
+}
+
+// FromJSON crée un abonnement depuis du JSON
+func (w *WebhookSubscription) FromJSON(data []byte) error {
+    return json.Unmarshal(data, w)
+}
+
+// ToJSON convertit la livraison en JSON
+func (d *WebhookDelivery) ToJSON() ([]byte, error) {
+    return json.Marshal(d)
+}
+
+// FromJSON crée une livraison depuis du JSON
+func (d *WebhookDelivery) FromJSON(data []byte) error {
+    return json.Unmarshal(data, d)
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+    "fmt"
+    "stock-service/models"
+    "strings"
+
+    "go.uber.org/zap"
+)
+
+// ApplyMovementBatch applique plusieurs mouvements, éventuellement sur des
+// pièces différentes, dans une seule transaction: soit toutes les lignes sont
+// appliquées, soit aucune. Les lignes sont évaluées dans l'ordre, chacune sur
+// le stock résultant des précédentes. En cas de rejet, le résultat de chaque
+// ligne indique l'erreur rencontrée.
+func (s *StockService) ApplyMovementBatch(req *models.BatchMovementRequest, userID string) ([]models.BatchMovementResult, error) {
+    var results []models.BatchMovementResult
+    rejected := 0
+
+    err := s.runStockTx(func(t *stockTx) error {
+        results = make([]models.BatchMovementResult, len(req.Lignes))
+        rejected = 0
+
+        for i := range req.Lignes {
+            line := &req.Lignes[i]
+            result := &results[i]
+            result.Index = i
+            result.PieceID = line.PieceID
+            result.Type = line.Type
+            result.Quantite = line.Quantite
+
+            var movement *models.StockMovement
+            var err error
+            if line.Type == models.MOVEMENT_TYPE_INCREMENT {
+                _, movement, err = s.applyIncrement(t, line.PieceID, &line.StockMovementRequest, userID)
+            } else {
+                _, movement, err = s.applyDecrement(t, line.PieceID, &line.StockMovementRequest, userID)
+            }
+
+            if err != nil {
+                if !isMovementRejection(err) {
+                    return err
+                }
+                result.Statut = models.BATCH_LINE_REJECTED
+                result.Erreur = err.Error()
+                rejected++
+                continue
+            }
+
+            result.Statut = models.BATCH_LINE_APPLIED
+            result.MouvementID = movement.ID
+            result.QuantiteAvant = movement.QuantiteAvant
+            result.QuantiteApres = movement.QuantiteApres
+        }
+
+        if rejected > 0 {
+            return fmt.Errorf("lot de mouvements rejeté: %d ligne(s) en erreur sur %d", rejected, len(req.Lignes))
+        }
+        return nil
+    })
+
+    if err != nil {
+        if rejected > 0 {
+            // Rien n'a été écrit: les lignes valides sont annulées, les
+            // sorties refusées faute de stock sont historisées
+            for i := range results {
+                if results[i].Statut == models.BATCH_LINE_APPLIED {
+                    results[i].Statut = models.BATCH_LINE_SKIPPED
+                    results[i].MouvementID = ""
+                }
+                if results[i].Type == models.MOVEMENT_TYPE_DECREMENT && results[i].Statut == models.BATCH_LINE_REJECTED &&
+                    strings.HasPrefix(results[i].Erreur, "stock insuffisant") {
+                    s.recordDenial(results[i].PieceID, &req.Lignes[i].StockMovementRequest, results[i].Erreur, userID)
+                }
+            }
+            return results, err
+        }
+        return nil, err
+    }
+
+    s.logger.Info("Mouvements groupés appliqués",
+        zap.Int("lignes", len(results)),
+        zap.String("user_id", userID))
+
+    return results, nil
+}
+
+// isMovementRejection indique si l'erreur d'un mouvement relève des règles
+// de gestion (et non d'un incident technique)
+func isMovementRejection(err error) bool {
+    msg := err.Error()
+    for _, prefix := range []string{
+        "pièce non trouvée",
+        "stock insuffisant",
+        "suivi invalide",
+        "FEFO non respecté",
+        "unité invalide",
+        "quantité invalide",
+        "devise invalide",
+    } {
+        if strings.HasPrefix(msg, prefix) {
+            return true
+        }
+    }
+    return false
+}
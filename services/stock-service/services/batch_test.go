@@ -0,0 +1,75 @@
+package services
+
+import (
+    "strings"
+    "testing"
+
+    "stock-service/models"
+)
+
+func batchLine(pieceID, movementType string, quantite float64) models.BatchMovementLine {
+    return models.BatchMovementLine{
+        PieceID:              pieceID,
+        Type:                 movementType,
+        StockMovementRequest: models.StockMovementRequest{Quantite: quantite, Motif: "test"},
+    }
+}
+
+// TestBatchKeepsReservedStock vérifie que les sorties successives d'un lot
+// ne consomment pas les unités réservées
+func TestBatchKeepsReservedStock(t *testing.T) {
+    s, _ := newTestService(t)
+    piece := newTestPiece(t, s, 10)
+    if _, _, err := s.ReserveStock(piece.ID, &models.ReservationRequest{InterventionID: "INT-1", Quantite: 8}, "test"); err != nil {
+        t.Fatalf("réservation: %v", err)
+    }
+
+    results, err := s.ApplyMovementBatch(&models.BatchMovementRequest{Lignes: []models.BatchMovementLine{
+        batchLine(piece.ID, models.MOVEMENT_TYPE_DECREMENT, 2),
+        batchLine(piece.ID, models.MOVEMENT_TYPE_DECREMENT, 2),
+    }}, "test")
+    if err == nil {
+        t.Fatal("lot accepté, attendu un rejet pour stock insuffisant")
+    }
+    if results[0].Statut != models.BATCH_LINE_SKIPPED {
+        t.Errorf("ligne 0: statut = %s, attendu %s", results[0].Statut, models.BATCH_LINE_SKIPPED)
+    }
+    if results[1].Statut != models.BATCH_LINE_REJECTED || !strings.HasPrefix(results[1].Erreur, "stock insuffisant") {
+        t.Errorf("ligne 1: statut = %s (%s), attendu un rejet pour stock insuffisant", results[1].Statut, results[1].Erreur)
+    }
+
+    final, err := s.GetPiece(piece.ID)
+    if err != nil {
+        t.Fatalf("lecture de la pièce: %v", err)
+    }
+    if final.Quantite != 10 || final.QuantiteReservee != 8 || final.QuantiteDisponible != 2 {
+        t.Errorf("pièce: quantite=%g reservee=%g disponible=%g, attendu 10, 8 et 2",
+            final.Quantite, final.QuantiteReservee, final.QuantiteDisponible)
+    }
+}
+
+// TestBatchUsesPreviousLines vérifie qu'une sortie est évaluée sur le stock
+// laissé par les entrées qui la précèdent dans le lot
+func TestBatchUsesPreviousLines(t *testing.T) {
+    s, _ := newTestService(t)
+    piece := newTestPiece(t, s, 1)
+
+    results, err := s.ApplyMovementBatch(&models.BatchMovementRequest{Lignes: []models.BatchMovementLine{
+        batchLine(piece.ID, models.MOVEMENT_TYPE_INCREMENT, 5),
+        batchLine(piece.ID, models.MOVEMENT_TYPE_DECREMENT, 4),
+    }}, "test")
+    if err != nil {
+        t.Fatalf("lot rejeté: %v", err)
+    }
+    if results[1].QuantiteAvant != 6 || results[1].QuantiteApres != 2 {
+        t.Errorf("ligne 1: %g -> %g, attendu 6 -> 2", results[1].QuantiteAvant, results[1].QuantiteApres)
+    }
+
+    final, err := s.GetPiece(piece.ID)
+    if err != nil {
+        t.Fatalf("lecture de la pièce: %v", err)
+    }
+    if final.Quantite != 2 || final.QuantiteDisponible != 2 {
+        t.Errorf("pièce: quantite=%g disponible=%g, attendu 2 et 2", final.Quantite, final.QuantiteDisponible)
+    }
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "math"
+    "sort"
+    "stock-service/models"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+    "go.uber.org/zap"
+)
+
+const (
+    CLASSIFICATION_REPORT_KEY = "stock:classification:report"
+    CLASSIFICATION_LOCK_KEY   = "stock:classification:lock"
+
+    // CLASSIFICATION_PERIOD_DAYS est la période, en jours, sur laquelle la
+    // régularité de la demande est mesurée
+    CLASSIFICATION_PERIOD_DAYS = 7
+)
+
+// classifiedPiece porte les grandeurs calculées pour classer une pièce
+type classifiedPiece struct {
+    id        string
+    value     models.Decimal
+    variation float64
+    consumed  bool
+}
+
+// ClassifyPieces classe les pièces du catalogue selon la valeur annuelle de
+// leur consommation (ABC) et la régularité de leur demande hebdomadaire
+// (XYZ), sur la période d'historique configurée. Le classement est
+// enregistré sur chaque pièce; le rapport du dernier calcul est conservé.
+func (s *StockService) ClassifyPieces(userID string) (*models.ClassificationReport, error) {
+    ctx := context.Background()
+
+    pieces, err := s.GetAllPieces()
+    if err != nil {
+        return nil, err
+    }
+
+    to := time.Now()
+    from := to.AddDate(0, 0, -s.config.ConsumptionWindowDays)
+
+    report := &models.ClassificationReport{
+        PeriodeJours: s.config.ConsumptionWindowDays,
+        NombrePieces: len(pieces),
+        Devise:       s.config.Currency,
+        Repartition:  make(map[string]int),
+        UserID:       userID,
+        GeneratedAt:  to,
+    }
+
+    ranked := make([]classifiedPiece, 0, len(pieces))
+    for i := range pieces {
+        piece := &pieces[i]
+        issues, err := s.issueMovements(piece.ID, from, to)
+        if err != nil {
+            return nil, err
+        }
+
+        // Valeur annuelle: consommation journalière moyenne × 365, au coût
+        // unitaire courant converti dans la devise du service
+        stats := consumptionStats(piece, issues, from, to)
+        cost, err := s.convertMoney(s.unitCost(piece), piece.Devise, s.config.Currency)
+        if err != nil {
+            return nil, err
+        }
+        value := models.RoundMoney(cost.MulQuantity(stats.Moyenne*365), s.config.Currency)
+
+        mean, stddev := meanStdDev(consumptionSeries(piece, issues, from, to, CLASSIFICATION_PERIOD_DAYS))
+        variation := 0.0
+        if mean > 0 {
+            variation = stddev / mean
+        }
+
+        ranked = append(ranked, classifiedPiece{
+            id:        piece.ID,
+            value:     value,
+            variation: variation,
+            consumed:  stats.NombreSorties > 0,
+        })
+        report.ValeurTotale = report.ValeurTotale.Add(value)
+    }
+
+    sort.SliceStable(ranked, func(i, j int) bool {
+        return ranked[i].value > ranked[j].value
+    })
+
+    cumulated := models.Decimal(0)
+    for _, item := range ranked {
+        classification := &models.PieceClassification{
+            ABC:                  abcClass(cumulated, item.value, report.ValeurTotale),
+            XYZ:                  xyzClass(item.variation, item.consumed),
+            ValeurConsommation:   item.value,
+            Devise:               s.config.Currency,
+            CoefficientVariation: models.RoundQuantity(item.variation, 4),
+            CalculeeLe:           to,
+        }
+        cumulated = cumulated.Add(item.value)
+
+        err := s.runStockTx(func(t *stockTx) error {
+            piece, err := t.getPiece(item.id)
+            if err != nil {
+                return err
+            }
+            piece.Classification = classification
+            // Le recalcul planifié réécrit tout le catalogue: la classe
+            // seule ne donne lieu à aucun événement
+            t.silent = true
+            t.savePiece(piece)
+            return nil
+        })
+        if err != nil {
+            // Une pièce supprimée entre-temps n'interrompt pas le calcul
+            s.logger.Warn("Pièce non classée", zap.String("id", item.id), zap.Error(err))
+            continue
+        }
+        report.Repartition[classification.Class()]++
+    }
+
+    reportJSON, err := report.ToJSON()
+    if err != nil {
+        return nil, fmt.Errorf("erreur de sérialisation: %w", err)
+    }
+    if err := s.redis.Set(ctx, CLASSIFICATION_REPORT_KEY, reportJSON, 0).Err(); err != nil {
+        return nil, fmt.Errorf("erreur lors de l'enregistrement de la classification: %w", err)
+    }
+
+    s.logger.Info("Classification ABC/XYZ calculée",
+        zap.Int("pieces", report.NombrePieces),
+        zap.Any("repartition", report.Repartition),
+        zap.String("user_id", userID))
+
+    return report, nil
+}
+
+// abcClass retourne la classe ABC d'une pièce selon la part de la valeur
+// totale cumulée par les pièces qui la précèdent. Une pièce sans
+// consommation est toujours en C.
+func abcClass(cumulated, value, total models.Decimal) string {
+    if value <= 0 || total <= 0 {
+        return models.ABC_CLASS_C
+    }
+    share := cumulated.Float64() / total.Float64()
+    switch {
+    case share < models.ABC_SHARE_A:
+        return models.ABC_CLASS_A
+    case share < models.ABC_SHARE_B:
+        return models.ABC_CLASS_B
+    }
+    return models.ABC_CLASS_C
+}
+
+// xyzClass retourne la classe XYZ d'une pièce selon le coefficient de
+// variation de sa consommation; une pièce sans consommation est en Z
+func xyzClass(variation float64, consumed bool) string {
+    switch {
+    case !consumed || math.IsNaN(variation):
+        return models.XYZ_CLASS_Z
+    case variation <= models.XYZ_VARIATION_X:
+        return models.XYZ_CLASS_X
+    case variation <= models.XYZ_VARIATION_Y:
+        return models.XYZ_CLASS_Y
+    }
+    return models.XYZ_CLASS_Z
+}
+
+// GetClassificationReport retourne le rapport du dernier calcul de
+// classification
+func (s *StockService) GetClassificationReport() (*models.ClassificationReport, error) {
+    ctx := context.Background()
+
+    reportJSON, err := s.redis.Get(ctx, CLASSIFICATION_REPORT_KEY).Result()
+    if err == redis.Nil {
+        return nil, fmt.Errorf("classification non calculée")
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération de la classification: %w", err)
+    }
+
+    var report models.ClassificationReport
+    if err := report.FromJSON([]byte(reportJSON)); err != nil {
+        return nil, fmt.Errorf("erreur de désérialisation: %w", err)
+    }
+    return &report, nil
+}
+
+// RunClassificationSchedule recalcule la classification à intervalle
+// régulier jusqu'à l'annulation du contexte. Un verrou Redis garantit qu'une
+// seule instance du service effectue le calcul à chaque échéance.
+func (s *StockService) RunClassificationSchedule(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            acquired, err := s.redis.SetNX(ctx, CLASSIFICATION_LOCK_KEY, time.Now().Format(time.RFC3339), interval/2).Result()
+            if err != nil {
+                s.logger.Warn("Verrou de classification indisponible", zap.Error(err))
+                continue
+            }
+            if !acquired {
+                continue
+            }
+            if _, err := s.ClassifyPieces(""); err != nil {
+                s.logger.Error("Erreur lors de la classification planifiée", zap.Error(err))
+            }
+        }
+    }
+}
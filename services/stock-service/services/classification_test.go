@@ -0,0 +1,57 @@
+package services
+
+import (
+    "math"
+    "testing"
+
+    "stock-service/models"
+)
+
+func TestABCClass(t *testing.T) {
+    tests := []struct {
+        name      string
+        cumulated float64
+        value     float64
+        total     float64
+        want      string
+    }{
+        {"première pièce", 0, 700, 1000, models.ABC_CLASS_A},
+        {"juste avant 80 %", 799, 100, 1000, models.ABC_CLASS_A},
+        {"à 80 %", 800, 100, 1000, models.ABC_CLASS_B},
+        {"juste avant 95 %", 949, 30, 1000, models.ABC_CLASS_B},
+        {"à 95 %", 950, 30, 1000, models.ABC_CLASS_C},
+        {"sans consommation", 0, 0, 1000, models.ABC_CLASS_C},
+        {"catalogue sans consommation", 0, 0, 0, models.ABC_CLASS_C},
+    }
+
+    for _, tt := range tests {
+        got := abcClass(models.DecimalFromFloat(tt.cumulated), models.DecimalFromFloat(tt.value), models.DecimalFromFloat(tt.total))
+        if got != tt.want {
+            t.Errorf("%s: classe = %s, attendu %s", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestXYZClass(t *testing.T) {
+    tests := []struct {
+        name      string
+        variation float64
+        consumed  bool
+        want      string
+    }{
+        {"régulière", 0.2, true, models.XYZ_CLASS_X},
+        {"limite X", models.XYZ_VARIATION_X, true, models.XYZ_CLASS_X},
+        {"variable", 0.8, true, models.XYZ_CLASS_Y},
+        {"limite Y", models.XYZ_VARIATION_Y, true, models.XYZ_CLASS_Y},
+        {"erratique", 1.5, true, models.XYZ_CLASS_Z},
+        {"constante", 0, true, models.XYZ_CLASS_X},
+        {"sans consommation", 0, false, models.XYZ_CLASS_Z},
+        {"coefficient indéfini", math.NaN(), true, models.XYZ_CLASS_Z},
+    }
+
+    for _, tt := range tests {
+        if got := xyzClass(tt.variation, tt.consumed); got != tt.want {
+            t.Errorf("%s: classe = %s, attendu %s", tt.name, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "stock-service/models"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+    "github.com/google/uuid"
+    "go.uber.org/zap"
+)
+
+// DOMAIN_EVENTS_STREAM est le flux Redis des événements de domaine, lu par
+// les autres services au moyen de groupes de consommateurs
+const DOMAIN_EVENTS_STREAM = "stock:domain-events"
+
+// newDomainEvent construit l'événement de domaine d'une pièce et, pour un
+// événement "stock.*", du mouvement qui le provoque
+func newDomainEvent(eventType string, piece *models.Piece, movement *models.StockMovement) *models.DomainEvent {
+    event := &models.DomainEvent{
+        ID:         uuid.New().String(),
+        Type:       eventType,
+        Version:    models.DOMAIN_EVENT_VERSION,
+        Source:     models.DOMAIN_EVENT_SOURCE,
+        PieceID:    piece.ID,
+        OccurredAt: time.Now(),
+        Piece:      models.NewDomainEventPiece(piece),
+    }
+    if movement != nil {
+        event.Mouvement = models.NewDomainEventMovement(movement)
+        event.OccurredAt = movement.CreatedAt
+    }
+    return event
+}
+
+// queueDomainEvent met en file l'ajout d'un événement au flux. Le type et la
+// version sont repris en champs de l'entrée pour permettre aux consommateurs
+// de filtrer sans décoder le contenu.
+func (s *StockService) queueDomainEvent(ctx context.Context, pipe redis.Pipeliner, event *models.DomainEvent) error {
+    payload, err := event.ToJSON()
+    if err != nil {
+        return fmt.Errorf("erreur de sérialisation de l'événement: %w", err)
+    }
+
+    args := &redis.XAddArgs{
+        Stream: DOMAIN_EVENTS_STREAM,
+        Values: map[string]interface{}{
+            "id":       event.ID,
+            "type":     event.Type,
+            "version":  strconv.Itoa(event.Version),
+            "piece_id": event.PieceID,
+            "payload":  string(payload),
+        },
+    }
+    // Le flux est borné approximativement, ce qui laisse Redis supprimer
+    // les entrées par blocs entiers
+    if s.config.DomainEventsMaxLen > 0 {
+        args.MaxLen = int64(s.config.DomainEventsMaxLen)
+        args.Approx = true
+    }
+    pipe.XAdd(ctx, args)
+    return nil
+}
+
+// EnsureDomainEventGroups crée les groupes de consommateurs configurés qui
+// n'existent pas encore. Un groupe créé par le service lit le flux depuis son
+// début: les événements publiés avant le premier démarrage du consommateur
+// lui sont livrés.
+func (s *StockService) EnsureDomainEventGroups(groups []string) error {
+    ctx := context.Background()
+
+    for _, group := range groups {
+        err := s.redis.XGroupCreateMkStream(ctx, DOMAIN_EVENTS_STREAM, group, "0").Err()
+        if err != nil {
+            if strings.HasPrefix(err.Error(), "BUSYGROUP") {
+                continue
+            }
+            return fmt.Errorf("erreur lors de la création du groupe %s: %w", group, err)
+        }
+        s.logger.Info("Groupe de consommateurs des événements créé",
+            zap.String("stream", DOMAIN_EVENTS_STREAM),
+            zap.String("groupe", group))
+    }
+    return nil
+}
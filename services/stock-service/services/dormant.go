@@ -0,0 +1,105 @@
+package services
+
+import (
+    "fmt"
+    "sort"
+    "stock-service/models"
+    "time"
+)
+
+// GetDormantStock liste les pièces en stock sans sortie depuis months mois et
+// valorise le stock qu'elles immobilisent, avec les totaux par catégorie et
+// par emplacement. Une pièce jamais sortie est dormante si elle a été créée
+// avant la date limite. Les valeurs sont converties dans la devise configurée.
+func (s *StockService) GetDormantStock(months int, filter models.PieceFilter) (*models.DormantStockReport, error) {
+    if months == 0 {
+        months = models.DEFAULT_DORMANT_MONTHS
+    }
+    if months < 1 || months > models.MAX_DORMANT_MONTHS {
+        return nil, fmt.Errorf("période invalide: %d mois (attendu entre 1 et %d)", months, models.MAX_DORMANT_MONTHS)
+    }
+
+    pieces, err := s.ListPieces(filter)
+    if err != nil {
+        return nil, err
+    }
+
+    now := time.Now()
+    report := &models.DormantStockReport{
+        Mois:        months,
+        DateLimite:  now.AddDate(0, -months, 0),
+        Methode:     s.config.ValuationMethod,
+        Devise:      s.config.Currency,
+        Pieces:      make([]models.DormantPiece, 0),
+        GeneratedAt: now,
+    }
+    byCategory := make(map[string]*models.ValuationLine)
+    byLocation := make(map[string]*models.ValuationLine)
+
+    for i := range pieces {
+        piece := &pieces[i]
+        if piece.Quantite <= 0 {
+            continue
+        }
+
+        // Référence: dernière sortie, à défaut création de la pièce
+        since := piece.CreatedAt
+        if piece.DerniereSortie != nil {
+            since = *piece.DerniereSortie
+        }
+        if since.After(report.DateLimite) {
+            continue
+        }
+
+        cost, err := s.convertMoney(s.unitCost(piece), piece.Devise, s.config.Currency)
+        if err != nil {
+            return nil, err
+        }
+        value := models.RoundMoney(cost.MulQuantity(piece.Quantite), s.config.Currency)
+
+        dormant := models.DormantPiece{
+            PieceID:        piece.ID,
+            Nom:            piece.Nom,
+            Categorie:      piece.Categorie,
+            Emplacements:   make(map[string]float64),
+            Quantite:       piece.Quantite,
+            Unite:          piece.UniteStock,
+            DerniereSortie: piece.DerniereSortie,
+            DerniereEntree: piece.DerniereEntree,
+            CoutUnitaire:   models.RoundMoney(cost, s.config.Currency),
+            Valeur:         value,
+        }
+        if !since.IsZero() {
+            dormant.JoursSansSortie = int(now.Sub(since).Hours() / 24)
+        }
+
+        category := piece.Categorie
+        if category == "" {
+            category = "non-classe"
+        }
+        addValuation(byCategory, category, piece.Quantite, value)
+
+        for location, quantite := range piece.Emplacements {
+            if quantite == 0 {
+                continue
+            }
+            dormant.Emplacements[location] = quantite
+            addValuation(byLocation, location, quantite, models.RoundMoney(cost.MulQuantity(quantite), s.config.Currency))
+        }
+
+        report.Pieces = append(report.Pieces, dormant)
+        report.NombrePieces++
+        report.ValeurTotale = report.ValeurTotale.Add(value)
+    }
+
+    sort.SliceStable(report.Pieces, func(i, j int) bool {
+        if report.Pieces[i].Valeur != report.Pieces[j].Valeur {
+            return report.Pieces[i].Valeur > report.Pieces[j].Valeur
+        }
+        return report.Pieces[i].JoursSansSortie > report.Pieces[j].JoursSansSortie
+    })
+    report.ParCategorie = sortedValuation(byCategory)
+    report.ParEmplacement = sortedValuation(byLocation)
+
+    return report, nil
+}
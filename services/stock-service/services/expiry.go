@@ -0,0 +1,218 @@
+package services
+
+import (
+    "fmt"
+    "sort"
+    "stock-service/models"
+    "time"
+)
+
+// sortFEFO trie les lots dans l'ordre FEFO: d'abord par date de péremption
+// croissante, les lots sans date en dernier, puis par date de réception
+func sortFEFO(lots []models.StockLot) {
+    sort.SliceStable(lots, func(i, j int) bool {
+        if expiresEarlier(&lots[i], &lots[j]) {
+            return true
+        }
+        if expiresEarlier(&lots[j], &lots[i]) {
+            return false
+        }
+        return lots[i].DateReception.Before(lots[j].DateReception)
+    })
+}
+
+// expiresEarlier indique si le lot a périme strictement avant le lot b
+func expiresEarlier(a, b *models.StockLot) bool {
+    if a.DatePeremption == nil {
+        return false
+    }
+    return b.DatePeremption == nil || a.DatePeremption.Before(*b.DatePeremption)
+}
+
+// firstToExpire retourne le premier lot non périmé dans l'ordre FEFO
+func firstToExpire(piece *models.Piece, now time.Time) *models.StockLot {
+    var first *models.StockLot
+    for i := range piece.Lots {
+        lot := &piece.Lots[i]
+        if lot.Quantite <= 0 || lot.IsExpired(now) {
+            continue
+        }
+        if first == nil || expiresEarlier(lot, first) {
+            first = lot
+        }
+    }
+    return first
+}
+
+// planFEFO répartit une quantité sur les lots non périmés de la pièce dans
+// l'ordre FEFO, sans modifier les lots
+func planFEFO(piece *models.Piece, quantite float64, now time.Time) ([]models.StockLot, error) {
+    ordered := make([]models.StockLot, len(piece.Lots))
+    copy(ordered, piece.Lots)
+    sortFEFO(ordered)
+
+    plan := make([]models.StockLot, 0)
+    remaining := quantite
+    expired := 0.0
+    for _, lot := range ordered {
+        if lot.IsExpired(now) {
+            expired += lot.Quantite
+            continue
+        }
+        if remaining <= 0 {
+            continue
+        }
+        taken := lot.Quantite
+        if taken > remaining {
+            taken = remaining
+        }
+        lot.Quantite = taken
+        plan = append(plan, lot)
+        remaining = piece.RoundQuantity(remaining - taken)
+    }
+
+    if remaining > 0 {
+        return nil, fmt.Errorf("stock insuffisant en lots non périmés: manque=%s, périmé=%s",
+            models.FormatQuantity(remaining), models.FormatQuantity(piece.RoundQuantity(expired)))
+    }
+    return plan, nil
+}
+
+// issueFEFO retire une quantité des lots non périmés dans l'ordre FEFO
+func issueFEFO(piece *models.Piece, quantite float64, now time.Time) (map[string]float64, []string, error) {
+    plan, err := planFEFO(piece, quantite, now)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    lots := make(map[string]float64, len(plan))
+    for _, lot := range plan {
+        removeFromLot(piece, piece.FindLot(lot.Numero), lot.Quantite)
+        lots[lot.Numero] = lot.Quantite
+    }
+    return lots, nil, nil
+}
+
+// SuggestFEFO propose les lots ou numéros de série à sortir pour une quantité
+// donnée, dans l'ordre FEFO
+func (s *StockService) SuggestFEFO(id string, quantite float64) ([]models.StockLot, error) {
+    piece, err := s.GetPiece(id)
+    if err != nil {
+        return nil, err
+    }
+    if !piece.IsTracked() {
+        return nil, fmt.Errorf("suivi invalide: la pièce %s n'est suivie ni par lot ni par numéro de série", piece.ID)
+    }
+    if err := piece.ValidateQuantity(quantite); err != nil {
+        return nil, err
+    }
+    if quantite > piece.QuantiteDisponible {
+        return nil, fmt.Errorf("stock insuffisant: disponible=%s, demandé=%s",
+            models.FormatQuantity(piece.QuantiteDisponible), models.FormatQuantity(quantite))
+    }
+
+    return planFEFO(piece, quantite, time.Now())
+}
+
+// GetExpiringLots retourne les lots périmés ou périmant dans le délai donné,
+// du plus proche au plus lointain
+func (s *StockService) GetExpiringLots(within time.Duration, filter models.PieceFilter) ([]models.ExpiringLot, error) {
+    pieces, err := s.ListPieces(filter)
+    if err != nil {
+        return nil, err
+    }
+
+    now := time.Now()
+    limit := now.Add(within)
+    result := make([]models.ExpiringLot, 0)
+
+    for _, piece := range pieces {
+        for _, lot := range piece.Lots {
+            if lot.Quantite <= 0 || !lot.ExpiresBefore(limit) {
+                continue
+            }
+            result = append(result, models.ExpiringLot{
+                PieceID:        piece.ID,
+                Nom:            piece.Nom,
+                Categorie:      piece.Categorie,
+                Numero:         lot.Numero,
+                Quantite:       lot.Quantite,
+                DatePeremption: *lot.DatePeremption,
+                JoursRestants:  int(lot.DatePeremption.Sub(now).Hours() / 24),
+                Perime:         lot.IsExpired(now),
+                Valeur:         models.RoundMoney(s.unitCost(&piece).MulQuantity(lot.Quantite), piece.Devise),
+                Devise:         piece.Devise,
+            })
+        }
+    }
+
+    sort.SliceStable(result, func(i, j int) bool {
+        return result[i].DatePeremption.Before(result[j].DatePeremption)
+    })
+
+    return result, nil
+}
+
+// ExpiringValue totalise la valeur de lots à péremption dans la devise
+// configurée, arrondie selon ses règles; retourne le total et sa devise
+func (s *StockService) ExpiringValue(lots []models.ExpiringLot) (models.Decimal, string, error) {
+    var total models.Decimal
+    for _, lot := range lots {
+        value, err := s.convertMoney(lot.Valeur, lot.Devise, s.config.Currency)
+        if err != nil {
+            return 0, "", err
+        }
+        total = total.Add(value)
+    }
+    return models.RoundMoney(total, s.config.Currency), s.config.Currency, nil
+}
+
+// expiryAlert construit l'alerte de péremption d'une pièce, nil si aucun lot
+// n'est périmé ni ne périme avant l'horizon donné
+func expiryAlert(piece *models.Piece, now time.Time, horizon time.Duration) *models.AlerteStock {
+    limit := now.Add(horizon)
+    expired := 0.0
+    expiring := 0.0
+    var next *time.Time
+
+    for _, lot := range piece.Lots {
+        if lot.Quantite <= 0 || !lot.ExpiresBefore(limit) {
+            continue
+        }
+        if lot.IsExpired(now) {
+            expired += lot.Quantite
+            continue
+        }
+        expiring += lot.Quantite
+        if next == nil || lot.DatePeremption.Before(*next) {
+            next = lot.DatePeremption
+        }
+    }
+
+    if expired == 0 && expiring == 0 {
+        return nil
+    }
+    expired = piece.RoundQuantity(expired)
+    expiring = piece.RoundQuantity(expiring)
+
+    severite := "attention"
+    if expired > 0 {
+        severite = "critique"
+    }
+
+    return &models.AlerteStock{
+        PieceID:                piece.ID,
+        Nom:                    piece.Nom,
+        Type:                   models.ALERT_TYPE_EXPIRY,
+        Quantite:               piece.Quantite,
+        QuantiteReservee:       piece.QuantiteReservee,
+        QuantiteDisponible:     piece.QuantiteDisponible,
+        Emplacements:           piece.Emplacements,
+        SeuilMin:               piece.SeuilMin,
+        Severite:               severite,
+        PourcentageStock:       piece.GetStockPercentage(),
+        QuantitePerimee:        expired,
+        QuantiteBientotPerimee: expiring,
+        ProchainePeremption:    next,
+    }
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+    "fmt"
+    "math"
+    "stock-service/models"
+    "time"
+)
+
+// FORECAST_MONTH_FORMAT est le format des mois des séries de consommation
+const FORECAST_MONTH_FORMAT = "2006-01"
+
+// fittedSeries représente l'ajustement d'une méthode sur l'historique:
+// fitted[i] est la prévision du mois i faite à la fin du mois i-1, valable à
+// partir de l'indice start, et next la prévision des mois suivants
+type fittedSeries struct {
+    fitted []float64
+    start  int
+    next   float64
+}
+
+// forecastParams complète les paramètres de prévision avec les valeurs par
+// défaut et les valide
+func forecastParams(params models.ForecastParams) (models.ForecastParams, error) {
+    if params.Historique == 0 {
+        params.Historique = models.DEFAULT_FORECAST_HISTORY
+    }
+    if params.Horizon == 0 {
+        params.Horizon = models.DEFAULT_FORECAST_HORIZON
+    }
+    if params.Fenetre == 0 {
+        params.Fenetre = models.DEFAULT_FORECAST_WINDOW
+    }
+
+    switch {
+    case params.Historique < 2 || params.Historique > models.MAX_FORECAST_HISTORY:
+        return params, fmt.Errorf("prévision invalide: historique de %d mois (attendu entre 2 et %d)", params.Historique, models.MAX_FORECAST_HISTORY)
+    case params.Horizon < 1 || params.Horizon > models.MAX_FORECAST_HORIZON:
+        return params, fmt.Errorf("prévision invalide: horizon de %d mois (attendu entre 1 et %d)", params.Horizon, models.MAX_FORECAST_HORIZON)
+    case params.Fenetre < 1 || params.Fenetre >= params.Historique:
+        return params, fmt.Errorf("prévision invalide: fenêtre de %d mois (attendu entre 1 et %d)", params.Fenetre, params.Historique-1)
+    case params.Alpha < 0 || params.Alpha >= 1:
+        return params, fmt.Errorf("prévision invalide: coefficient de lissage %g (attendu entre 0 et 1 exclu)", params.Alpha)
+    }
+    return params, nil
+}
+
+// monthlySeries cumule les sorties par mois calendaire sur les mois
+// complets précédant le mois en cours, à partir au plus tôt du mois de
+// création de la pièce
+func monthlySeries(piece *models.Piece, issues []models.StockMovement, first, end time.Time) []models.MonthlyQuantity {
+    if !piece.CreatedAt.IsZero() {
+        created := time.Date(piece.CreatedAt.Year(), piece.CreatedAt.Month(), 1, 0, 0, 0, 0, end.Location())
+        if created.After(first) {
+            first = created
+        }
+    }
+
+    series := make([]models.MonthlyQuantity, 0)
+    index := make(map[string]int)
+    for month := first; month.Before(end); month = month.AddDate(0, 1, 0) {
+        key := month.Format(FORECAST_MONTH_FORMAT)
+        index[key] = len(series)
+        series = append(series, models.MonthlyQuantity{Mois: key})
+    }
+
+    for _, movement := range issues {
+        if i, ok := index[movement.CreatedAt.In(end.Location()).Format(FORECAST_MONTH_FORMAT)]; ok {
+            series[i].Quantite += movement.Quantite
+        }
+    }
+    for i := range series {
+        series[i].Quantite = piece.RoundQuantity(series[i].Quantite)
+    }
+    return series
+}
+
+// movingAverage ajuste une moyenne mobile sur une fenêtre de window mois
+func movingAverage(values []float64, window int) fittedSeries {
+    result := fittedSeries{fitted: make([]float64, len(values)), start: window}
+    sum := 0.0
+    for i, value := range values {
+        if i >= window {
+            result.fitted[i] = sum / float64(window)
+            sum -= values[i-window]
+        }
+        sum += value
+    }
+
+    if len(values) < window {
+        window = len(values)
+    }
+    if window > 0 {
+        tail := 0.0
+        for _, value := range values[len(values)-window:] {
+            tail += value
+        }
+        result.next = tail / float64(window)
+    }
+    return result
+}
+
+// exponentialSmoothing ajuste un lissage exponentiel simple de coefficient
+// alpha: le niveau, initialisé au premier mois, se rapproche de chaque
+// nouvelle observation d'une fraction alpha de l'écart
+func exponentialSmoothing(values []float64, alpha float64) fittedSeries {
+    result := fittedSeries{fitted: make([]float64, len(values)), start: 1}
+    if len(values) == 0 {
+        return result
+    }
+
+    level := values[0]
+    for i := 1; i < len(values); i++ {
+        result.fitted[i] = level
+        level = alpha*values[i] + (1-alpha)*level
+    }
+    result.next = level
+    return result
+}
+
+// bestSmoothing retourne le coefficient de lissage, par pas de 0.05, qui
+// minimise l'erreur quadratique des prévisions à un mois sur l'historique
+func bestSmoothing(values []float64) float64 {
+    best, bestError := 0.3, math.Inf(1)
+    for step := 1; step < 20; step++ {
+        alpha := float64(step) / 20
+        fit := exponentialSmoothing(values, alpha)
+        sse := 0.0
+        for i := fit.start; i < len(values); i++ {
+            sse += (fit.fitted[i] - values[i]) * (fit.fitted[i] - values[i])
+        }
+        if sse < bestError-1e-12 {
+            best, bestError = alpha, sse
+        }
+    }
+    return best
+}
+
+// forecastErrors calcule les erreurs des prévisions à un mois sur l'historique
+func forecastErrors(values []float64, fit fittedSeries) models.ForecastErrors {
+    var errors models.ForecastErrors
+    absolute, squared, bias, percentage := 0.0, 0.0, 0.0, 0.0
+    consumed := 0
+
+    for i := fit.start; i < len(values); i++ {
+        diff := fit.fitted[i] - values[i]
+        absolute += math.Abs(diff)
+        squared += diff * diff
+        bias += diff
+        errors.Points++
+        if values[i] > 0 {
+            percentage += math.Abs(diff) / values[i]
+            consumed++
+        }
+    }
+
+    if errors.Points == 0 {
+        return errors
+    }
+    points := float64(errors.Points)
+    errors.MAE = models.RoundQuantity(absolute/points, 4)
+    errors.RMSE = models.RoundQuantity(math.Sqrt(squared/points), 4)
+    errors.Biais = models.RoundQuantity(bias/points, 4)
+    if consumed > 0 {
+        mape := models.RoundQuantity(100*percentage/float64(consumed), 2)
+        errors.MAPE = &mape
+    }
+    return errors
+}
+
+// forecastModel construit la prévision d'une méthode sur l'horizon: les deux
+// méthodes prévoient un niveau constant, sans tendance ni saisonnalité
+func forecastModel(method string, parameter float64, values []float64, fit fittedSeries, end time.Time, horizon int) models.ForecastModel {
+    model := models.ForecastModel{
+        Methode:   method,
+        Parametre: parameter,
+        Prevision: make([]models.MonthlyQuantity, 0, horizon),
+        Erreurs:   forecastErrors(values, fit),
+    }
+
+    monthly := models.RoundQuantity(fit.next, 2)
+    for i := 0; i < horizon; i++ {
+        model.Prevision = append(model.Prevision, models.MonthlyQuantity{
+            Mois:     end.AddDate(0, i, 0).Format(FORECAST_MONTH_FORMAT),
+            Quantite: monthly,
+        })
+    }
+    model.Total = models.RoundQuantity(fit.next*float64(horizon), 2)
+    return model
+}
+
+// ForecastConsumption prévoit la consommation mensuelle d'une pièce à partir
+// de ses sorties des derniers mois complets, par moyenne mobile et par
+// lissage exponentiel, avec les erreurs de chaque méthode sur l'historique.
+// Le mois en cours, incomplet, n'entre pas dans l'historique et ouvre
+// l'horizon de prévision.
+func (s *StockService) ForecastConsumption(id string, params models.ForecastParams) (*models.ConsumptionForecast, error) {
+    params, err := forecastParams(params)
+    if err != nil {
+        return nil, err
+    }
+
+    piece, err := s.GetPiece(id)
+    if err != nil {
+        return nil, err
+    }
+
+    now := time.Now()
+    end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+    first := end.AddDate(0, -params.Historique, 0)
+
+    issues, err := s.issueMovements(piece.ID, first, end)
+    if err != nil {
+        return nil, err
+    }
+
+    history := monthlySeries(piece, issues, first, end)
+    values := make([]float64, len(history))
+    for i, month := range history {
+        values[i] = month.Quantite
+    }
+    if len(values) < 2 {
+        return nil, fmt.Errorf("historique insuffisant: %d mois complet(s) depuis la création de la pièce %s", len(values), piece.ID)
+    }
+
+    forecast := &models.ConsumptionForecast{
+        PieceID:     piece.ID,
+        Nom:         piece.Nom,
+        Unite:       piece.UniteStock,
+        Historique:  history,
+        Modeles:     make([]models.ForecastModel, 0, 2),
+        GeneratedAt: now,
+    }
+
+    window := params.Fenetre
+    if window >= len(values) {
+        window = len(values) - 1
+    }
+    forecast.Modeles = append(forecast.Modeles, forecastModel(models.FORECAST_METHOD_MOVING_AVERAGE,
+        float64(window), values, movingAverage(values, window), end, params.Horizon))
+
+    alpha := params.Alpha
+    if alpha == 0 {
+        alpha = bestSmoothing(values)
+    }
+    forecast.Modeles = append(forecast.Modeles, forecastModel(models.FORECAST_METHOD_SMOOTHING,
+        alpha, values, exponentialSmoothing(values, alpha), end, params.Horizon))
+
+    best := -1
+    for i, model := range forecast.Modeles {
+        if model.Erreurs.Points == 0 {
+            continue
+        }
+        if best < 0 || model.Erreurs.MAE < forecast.Modeles[best].Erreurs.MAE {
+            best = i
+        }
+    }
+    if best >= 0 {
+        forecast.MeilleurModele = forecast.Modeles[best].Methode
+    }
+
+    return forecast, nil
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+    "math"
+    "testing"
+    "time"
+
+    "stock-service/models"
+)
+
+func assertSeries(t *testing.T, name string, got, want []float64) {
+    t.Helper()
+    if len(got) != len(want) {
+        t.Errorf("%s: %d valeur(s), attendu %d", name, len(got), len(want))
+        return
+    }
+    for i := range got {
+        if math.Abs(got[i]-want[i]) > 1e-9 {
+            t.Errorf("%s: valeur %d = %g, attendu %g", name, i, got[i], want[i])
+        }
+    }
+}
+
+func TestMovingAverage(t *testing.T) {
+    tests := []struct {
+        name       string
+        values     []float64
+        window     int
+        wantFitted []float64
+        wantNext   float64
+    }{
+        {"fenêtre de 3", []float64{3, 6, 9, 12, 0}, 3, []float64{0, 0, 0, 6, 9}, 7},
+        {"fenêtre de 1", []float64{4, 8, 2}, 1, []float64{0, 4, 8}, 2},
+        {"historique plus court que la fenêtre", []float64{2, 4}, 3, []float64{0, 0}, 3},
+        {"historique vide", nil, 3, []float64{}, 0},
+    }
+
+    for _, tt := range tests {
+        fit := movingAverage(tt.values, tt.window)
+        assertSeries(t, tt.name, fit.fitted, tt.wantFitted)
+        if fit.start != tt.window {
+            t.Errorf("%s: début = %d, attendu %d", tt.name, fit.start, tt.window)
+        }
+        if math.Abs(fit.next-tt.wantNext) > 1e-9 {
+            t.Errorf("%s: prévision = %g, attendu %g", tt.name, fit.next, tt.wantNext)
+        }
+    }
+}
+
+func TestExponentialSmoothing(t *testing.T) {
+    tests := []struct {
+        name       string
+        values     []float64
+        alpha      float64
+        wantFitted []float64
+        wantNext   float64
+    }{
+        {"alpha 0.5", []float64{10, 20, 10, 30}, 0.5, []float64{0, 10, 15, 12.5}, 21.25},
+        {"série constante", []float64{5, 5, 5}, 0.3, []float64{0, 5, 5}, 5},
+        {"un seul mois", []float64{7}, 0.3, []float64{0}, 7},
+        {"historique vide", nil, 0.3, []float64{}, 0},
+    }
+
+    for _, tt := range tests {
+        fit := exponentialSmoothing(tt.values, tt.alpha)
+        assertSeries(t, tt.name, fit.fitted, tt.wantFitted)
+        if math.Abs(fit.next-tt.wantNext) > 1e-9 {
+            t.Errorf("%s: prévision = %g, attendu %g", tt.name, fit.next, tt.wantNext)
+        }
+    }
+}
+
+func TestBestSmoothing(t *testing.T) {
+    tests := []struct {
+        name   string
+        values []float64
+        want   float64
+    }{
+        // Toutes les erreurs sont nulles: le plus petit coefficient l'emporte
+        {"série constante", []float64{5, 5, 5, 5}, 0.05},
+        {"changement de niveau durable", []float64{0, 0, 0, 10, 10, 10, 10, 10}, 0.95},
+        // Le niveau initial est déjà la moyenne: le lissage le plus fort
+        // suit le moins le bruit
+        {"alternance autour du niveau initial", []float64{5, 10, 0, 10, 0, 10, 0, 10, 0}, 0.05},
+    }
+
+    for _, tt := range tests {
+        if got := bestSmoothing(tt.values); math.Abs(got-tt.want) > 1e-9 {
+            t.Errorf("%s: coefficient = %g, attendu %g", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestForecastErrors(t *testing.T) {
+    fit := fittedSeries{fitted: []float64{0, 10, 15, 12.5}, start: 1}
+    errors := forecastErrors([]float64{10, 20, 10, 0}, fit)
+
+    // écarts: -10, +5, +12.5
+    if errors.Points != 3 {
+        t.Fatalf("points = %d, attendu 3", errors.Points)
+    }
+    if errors.MAE != 9.1667 || errors.Biais != 2.5 || errors.RMSE != 9.6825 {
+        t.Errorf("MAE=%g biais=%g RMSE=%g, attendu 9.1667, 2.5 et 9.6825", errors.MAE, errors.Biais, errors.RMSE)
+    }
+    // Le mois sans consommation n'entre pas dans le MAPE
+    if errors.MAPE == nil || *errors.MAPE != 50 {
+        t.Errorf("MAPE = %v, attendu 50", errors.MAPE)
+    }
+
+    empty := forecastErrors([]float64{4}, exponentialSmoothing([]float64{4}, 0.3))
+    if empty.Points != 0 || empty.MAPE != nil {
+        t.Errorf("historique d'un mois: %+v, attendu aucune erreur", empty)
+    }
+}
+
+func TestMonthlySeries(t *testing.T) {
+    first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    end := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+    issues := []models.StockMovement{
+        {Quantite: 2, CreatedAt: time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC)},
+        {Quantite: 1.5, CreatedAt: time.Date(2024, 2, 28, 23, 0, 0, 0, time.UTC)},
+        {Quantite: 4, CreatedAt: time.Date(2024, 4, 30, 12, 0, 0, 0, time.UTC)},
+        {Quantite: 9, CreatedAt: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)}, // mois en cours
+    }
+
+    tests := []struct {
+        name    string
+        created time.Time
+        want    []string
+        values  []float64
+    }{
+        {"historique complet", time.Time{}, []string{"2024-01", "2024-02", "2024-03", "2024-04"}, []float64{0, 3.5, 0, 4}},
+        {"pièce créée en cours de période", time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC), []string{"2024-02", "2024-03", "2024-04"}, []float64{3.5, 0, 4}},
+    }
+
+    for _, tt := range tests {
+        piece := &models.Piece{UniteStock: "litre", CreatedAt: tt.created}
+        series := monthlySeries(piece, issues, first, end)
+        if len(series) != len(tt.want) {
+            t.Errorf("%s: %d mois, attendu %d", tt.name, len(series), len(tt.want))
+            continue
+        }
+        for i := range series {
+            if series[i].Mois != tt.want[i] || series[i].Quantite != tt.values[i] {
+                t.Errorf("%s: %s = %g, attendu %s = %g", tt.name, series[i].Mois, series[i].Quantite, tt.want[i], tt.values[i])
+            }
+        }
+    }
+}
+
+func TestForecastParams(t *testing.T) {
+    tests := []struct {
+        name    string
+        params  models.ForecastParams
+        wantErr bool
+    }{
+        {"valeurs par défaut", models.ForecastParams{}, false},
+        {"fenêtre égale à l'historique", models.ForecastParams{Historique: 6, Fenetre: 6}, true},
+        {"historique trop court", models.ForecastParams{Historique: 1, Fenetre: 1}, true},
+        {"coefficient de lissage de 1", models.ForecastParams{Alpha: 1}, true},
+        {"horizon trop long", models.ForecastParams{Horizon: models.MAX_FORECAST_HORIZON + 1}, true},
+    }
+
+    for _, tt := range tests {
+        _, err := forecastParams(tt.params)
+        if (err != nil) != tt.wantErr {
+            t.Errorf("%s: erreur = %v, attendu une erreur: %v", tt.name, err, tt.wantErr)
+        }
+    }
+}
@@ -0,0 +1,492 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "stock-service/models"
+    "strings"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+    "github.com/google/uuid"
+    "go.uber.org/zap"
+)
+
+const (
+    INVENTORY_KEY_PREFIX = "stock:inventory:"
+    INVENTORIES_SET_KEY  = "stock:inventories"
+)
+
+// OpenInventory ouvre une session d'inventaire sur un périmètre de pièces et
+// fige les quantités attendues par emplacement
+func (s *StockService) OpenInventory(req *models.OpenInventoryRequest, userID string) (*models.InventorySession, error) {
+    ctx := context.Background()
+
+    if len(req.PieceIDs) == 0 && req.Categorie == "" && req.Emplacement == "" && req.ClasseABC == "" {
+        return nil, fmt.Errorf("périmètre d'inventaire vide: indiquer des pièces, une catégorie, un emplacement ou une classe ABC")
+    }
+
+    var pieces []models.Piece
+    if len(req.PieceIDs) > 0 {
+        for _, id := range req.PieceIDs {
+            piece, err := s.GetPiece(id)
+            if err != nil {
+                return nil, err
+            }
+            pieces = append(pieces, *piece)
+        }
+    } else {
+        all, err := s.GetAllPieces()
+        if err != nil {
+            return nil, err
+        }
+        pieces = all
+    }
+
+    now := time.Now()
+    session := &models.InventorySession{
+        ID:      uuid.New().String(),
+        Libelle: req.Libelle,
+        Statut:  models.INVENTORY_STATUS_OPEN,
+        Aveugle: req.Aveugle,
+        Perimetre: models.InventoryScope{
+            PieceIDs:    req.PieceIDs,
+            Categorie:   req.Categorie,
+            Emplacement: req.Emplacement,
+            ClasseABC:   req.ClasseABC,
+        },
+        Lignes:    make([]models.InventoryLine, 0),
+        CreatedBy: userID,
+        CreatedAt: now,
+        UpdatedAt: now,
+    }
+
+    for i := range pieces {
+        piece := &pieces[i]
+        if req.Categorie != "" && !strings.EqualFold(piece.Categorie, req.Categorie) {
+            continue
+        }
+        if !matchesPieceFilter(piece, models.PieceFilter{Emplacement: req.Emplacement, ClasseABC: req.ClasseABC}) {
+            continue
+        }
+
+        for _, location := range inventoryLocations(piece, req.Emplacement) {
+            expected := piece.LocationQuantity(location)
+            session.Lignes = append(session.Lignes, models.InventoryLine{
+                PieceID:          piece.ID,
+                Nom:              piece.Nom,
+                Emplacement:      location,
+                QuantiteAttendue: &expected,
+            })
+        }
+    }
+
+    if len(session.Lignes) == 0 {
+        return nil, fmt.Errorf("périmètre d'inventaire vide: aucune pièce ne correspond aux critères")
+    }
+
+    sort.Slice(session.Lignes, func(i, j int) bool {
+        if session.Lignes[i].Emplacement != session.Lignes[j].Emplacement {
+            return session.Lignes[i].Emplacement < session.Lignes[j].Emplacement
+        }
+        return session.Lignes[i].Nom < session.Lignes[j].Nom
+    })
+
+    sessionJSON, err := session.ToJSON()
+    if err != nil {
+        return nil, fmt.Errorf("erreur de sérialisation: %w", err)
+    }
+
+    pipe := s.redis.TxPipeline()
+    pipe.Set(ctx, INVENTORY_KEY_PREFIX+session.ID, sessionJSON, 0)
+    pipe.SAdd(ctx, INVENTORIES_SET_KEY, session.ID)
+    if _, err := pipe.Exec(ctx); err != nil {
+        return nil, fmt.Errorf("erreur lors de l'ouverture de l'inventaire: %w", err)
+    }
+
+    s.logger.Info("Session d'inventaire ouverte",
+        zap.String("inventaire_id", session.ID),
+        zap.String("libelle", session.Libelle),
+        zap.Int("lignes", len(session.Lignes)),
+        zap.Bool("aveugle", session.Aveugle))
+
+    return session, nil
+}
+
+// GetInventory récupère une session d'inventaire par ID
+func (s *StockService) GetInventory(id string) (*models.InventorySession, error) {
+    ctx := context.Background()
+
+    sessionJSON, err := s.redis.Get(ctx, INVENTORY_KEY_PREFIX+id).Result()
+    if err == redis.Nil {
+        return nil, fmt.Errorf("inventaire non trouvé: %s", id)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération de l'inventaire: %w", err)
+    }
+
+    var session models.InventorySession
+    if err := session.FromJSON([]byte(sessionJSON)); err != nil {
+        return nil, fmt.Errorf("erreur de désérialisation: %w", err)
+    }
+
+    return &session, nil
+}
+
+// GetInventories récupère les sessions d'inventaire, filtrées par statut si fourni
+func (s *StockService) GetInventories(statut string) ([]models.InventorySession, error) {
+    ctx := context.Background()
+
+    ids, err := s.redis.SMembers(ctx, INVENTORIES_SET_KEY).Result()
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération des inventaires: %w", err)
+    }
+
+    sessions := make([]models.InventorySession, 0, len(ids))
+    for _, id := range ids {
+        session, err := s.GetInventory(id)
+        if err != nil {
+            s.logger.Warn("Impossible de récupérer l'inventaire", zap.String("id", id), zap.Error(err))
+            continue
+        }
+        if statut != "" && session.Statut != statut {
+            continue
+        }
+        sessions = append(sessions, *session)
+    }
+
+    sort.Slice(sessions, func(i, j int) bool {
+        return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+    })
+
+    return sessions, nil
+}
+
+// SubmitInventoryCounts enregistre des quantités comptées; un nouveau comptage
+// d'une même ligne remplace le précédent. L'écart est mesuré par rapport au
+// stock de l'emplacement au moment du comptage, pour ne pas compter comme
+// écart les mouvements passés depuis l'ouverture.
+func (s *StockService) SubmitInventoryCounts(id string, req *models.InventoryCountRequest, userID string) (*models.InventorySession, error) {
+    var session *models.InventorySession
+
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        session, err = getOpenInventoryTx(t, id)
+        if err != nil {
+            return err
+        }
+
+        now := time.Now()
+        for _, count := range req.Comptages {
+            index, err := findInventoryLine(session, count.PieceID, count.Emplacement)
+            if err != nil {
+                return err
+            }
+
+            // La quantité comptée respecte la précision de l'unité de stock
+            piece, err := t.getPiece(count.PieceID)
+            if err != nil {
+                return err
+            }
+            counted := *count.QuantiteComptee
+            if err := piece.ValidateQuantity(counted); err != nil {
+                return err
+            }
+
+            line := &session.Lignes[index]
+            inStock := piece.LocationQuantity(line.Emplacement)
+            ecart := piece.RoundQuantity(counted - inStock)
+            line.QuantiteComptee = &counted
+            line.QuantiteEnStock = &inStock
+            line.Ecart = &ecart
+            line.ComptePar = userID
+            line.CompteLe = &now
+        }
+
+        session.UpdatedAt = now
+        queueInventory(t, session)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    s.logger.Info("Comptages d'inventaire enregistrés",
+        zap.String("inventaire_id", id),
+        zap.Int("comptages", len(req.Comptages)),
+        zap.String("user_id", userID))
+
+    return session, nil
+}
+
+// ValidateInventory clôture une session entièrement comptée et passe les
+// mouvements d'ajustement. Chaque ajustement reporte sur le stock courant
+// l'écart constaté au comptage, le même que celui du rapport: les mouvements
+// passés entre le comptage et la validation sont conservés. La validation est
+// refusée si un manquant ramène une pièce sous sa quantité réservée ou un
+// emplacement sous zéro.
+func (s *StockService) ValidateInventory(id string, userID string) (*models.InventorySession, error) {
+    var session *models.InventorySession
+    adjustments := 0
+
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        session, err = getOpenInventoryTx(t, id)
+        if err != nil {
+            return err
+        }
+
+        uncounted := 0
+        for _, line := range session.Lignes {
+            if line.QuantiteComptee == nil {
+                uncounted++
+            }
+        }
+        if uncounted > 0 {
+            return fmt.Errorf("inventaire incomplet: %d ligne(s) non comptée(s)", uncounted)
+        }
+
+        now := time.Now()
+        adjustments = 0
+        for i := range session.Lignes {
+            line := &session.Lignes[i]
+
+            piece, err := t.getPiece(line.PieceID)
+            if err != nil {
+                return err
+            }
+
+            location := piece.ResolveLocation(line.Emplacement)
+            current := piece.LocationQuantity(location)
+            // Les lignes comptées avant l'enregistrement du stock au
+            // comptage sont ramenées à la quantité comptée
+            delta := piece.RoundQuantity(*line.QuantiteComptee - current)
+            if line.Ecart != nil && line.QuantiteEnStock != nil {
+                delta = *line.Ecart
+            }
+            if delta == 0 {
+                continue
+            }
+            if current+delta < 0 {
+                return fmt.Errorf("écart inapplicable: l'emplacement %s de la pièce %s ne compte plus que %s unité(s) pour un écart de %s",
+                    location, piece.ID, models.FormatQuantity(current), models.FormatQuantity(delta))
+            }
+
+            lots, serials, err := adjustTracking(piece, delta, inventoryLotName(session, now), now)
+            if err != nil {
+                return err
+            }
+
+            oldQuantite := piece.Quantite
+            piece.AdjustLocation(location, delta)
+            piece.UpdatedAt = now
+
+            // Un manquant ne peut pas laisser des réservations sans stock:
+            // elles doivent d'abord être libérées
+            if delta < 0 && piece.Quantite < piece.QuantiteReservee {
+                return fmt.Errorf("réservations non couvertes: la pièce %s compterait %s unité(s) pour %s réservée(s)",
+                    piece.ID, models.FormatQuantity(piece.Quantite), models.FormatQuantity(piece.QuantiteReservee))
+            }
+
+            quantite := delta
+            if quantite < 0 {
+                quantite = -quantite
+            }
+            movement := newMovement(piece.ID, models.MOVEMENT_TYPE_ADJUSTMENT, quantite, oldQuantite, piece.Quantite, models.INVENTORY_MOTIF, userID)
+            movement.Emplacements = map[string]float64{location: delta}
+            movement.InventaireID = session.ID
+            movement.Lots = lots
+            movement.NumerosSerie = serials
+            // Un excédent forme une couche valorisée au coût courant, un
+            // manquant est sorti des couches comme une consommation
+            if delta > 0 {
+                movement.CoutUnitaire = s.unitCost(piece)
+                movement.Devise = piece.Devise
+                piece.AddCostLayer(delta, movement.CoutUnitaire, now, movement.ID)
+            } else {
+                s.valueIssue(movement, piece, quantite)
+            }
+            line.MouvementID = movement.ID
+
+            t.savePiece(piece)
+            t.addMovement(movement)
+            adjustments++
+        }
+
+        session.Statut = models.INVENTORY_STATUS_VALIDATED
+        session.ValidatedBy = userID
+        session.ValidatedAt = &now
+        session.UpdatedAt = now
+        queueInventory(t, session)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    s.logger.Info("Session d'inventaire validée",
+        zap.String("inventaire_id", id),
+        zap.Int("ajustements", adjustments),
+        zap.String("user_id", userID))
+
+    return session, nil
+}
+
+// CancelInventory annule une session ouverte sans toucher au stock
+func (s *StockService) CancelInventory(id string, userID string) (*models.InventorySession, error) {
+    var session *models.InventorySession
+
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        session, err = getOpenInventoryTx(t, id)
+        if err != nil {
+            return err
+        }
+
+        session.Statut = models.INVENTORY_STATUS_CANCELLED
+        session.UpdatedAt = time.Now()
+        queueInventory(t, session)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    s.logger.Info("Session d'inventaire annulée",
+        zap.String("inventaire_id", id),
+        zap.String("user_id", userID))
+
+    return session, nil
+}
+
+// GetInventoryReport calcule le rapport d'écarts d'une session, valorisé au
+// coût unitaire dans la devise configurée et arrondi selon ses règles
+func (s *StockService) GetInventoryReport(id string) (*models.InventoryReport, error) {
+    session, err := s.GetInventory(id)
+    if err != nil {
+        return nil, err
+    }
+
+    report := &models.InventoryReport{
+        SessionID:   session.ID,
+        Libelle:     session.Libelle,
+        Statut:      session.Statut,
+        LignesTotal: len(session.Lignes),
+        Lignes:      make([]models.InventoryLine, 0),
+        Devise:      s.config.Currency,
+    }
+
+    prices := make(map[string]models.Decimal)
+    for _, line := range session.Lignes {
+        if line.QuantiteComptee == nil {
+            continue
+        }
+        report.LignesComptees++
+
+        if line.Ecart == nil || *line.Ecart == 0 {
+            continue
+        }
+        report.LignesAvecEcart++
+        report.Lignes = append(report.Lignes, line)
+
+        if *line.Ecart > 0 {
+            report.EcartPositif += *line.Ecart
+        } else {
+            report.EcartNegatif += *line.Ecart
+        }
+
+        price, ok := prices[line.PieceID]
+        if !ok {
+            if piece, err := s.GetPiece(line.PieceID); err == nil {
+                price, err = s.convertMoney(s.unitCost(piece), piece.Devise, s.config.Currency)
+                if err != nil {
+                    return nil, err
+                }
+            }
+            prices[line.PieceID] = price
+        }
+        report.ValeurEcart = report.ValeurEcart.Add(price.MulQuantity(*line.Ecart))
+    }
+    report.ValeurEcart = models.RoundMoney(report.ValeurEcart, s.config.Currency)
+
+    return report, nil
+}
+
+// inventoryLotName nomme le lot créé pour un excédent d'inventaire
+func inventoryLotName(session *models.InventorySession, now time.Time) string {
+    return fmt.Sprintf("INV-%s-%s", now.Format("20060102"), session.ID[:8])
+}
+
+// inventoryLocations retourne les emplacements à compter pour une pièce
+func inventoryLocations(piece *models.Piece, location string) []string {
+    if location != "" {
+        return []string{piece.ResolveLocation(location)}
+    }
+
+    locations := make([]string, 0, len(piece.Emplacements))
+    for name := range piece.Emplacements {
+        locations = append(locations, name)
+    }
+    sort.Strings(locations)
+    return locations
+}
+
+// findInventoryLine retrouve la ligne d'une pièce; l'emplacement peut être
+// omis si la pièce n'a qu'une ligne dans la session
+func findInventoryLine(session *models.InventorySession, pieceID, location string) (int, error) {
+    found := -1
+    for i, line := range session.Lignes {
+        if line.PieceID != pieceID {
+            continue
+        }
+        if location != "" && strings.EqualFold(line.Emplacement, location) {
+            return i, nil
+        }
+        if location == "" {
+            if found >= 0 {
+                return -1, fmt.Errorf("emplacement requis: la pièce %s est comptée à plusieurs emplacements", pieceID)
+            }
+            found = i
+        }
+    }
+
+    if found < 0 {
+        return -1, fmt.Errorf("ligne d'inventaire non trouvée: pièce %s, emplacement '%s'", pieceID, location)
+    }
+    return found, nil
+}
+
+// getOpenInventoryTx lit une session sous surveillance et vérifie qu'elle est ouverte
+func getOpenInventoryTx(t *stockTx, id string) (*models.InventorySession, error) {
+    sessionJSON, err := t.watchGet(INVENTORY_KEY_PREFIX + id)
+    if err == redis.Nil {
+        return nil, fmt.Errorf("inventaire non trouvé: %s", id)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération de l'inventaire: %w", err)
+    }
+
+    var session models.InventorySession
+    if err := session.FromJSON([]byte(sessionJSON)); err != nil {
+        return nil, fmt.Errorf("erreur de désérialisation: %w", err)
+    }
+
+    if !session.IsOpen() {
+        return nil, fmt.Errorf("inventaire non ouvert: %s (statut=%s)", id, session.Statut)
+    }
+
+    return &session, nil
+}
+
+// queueInventory met en file la sauvegarde d'une session d'inventaire
+func queueInventory(t *stockTx, session *models.InventorySession) {
+    t.queue(func(pipe redis.Pipeliner) error {
+        sessionJSON, err := session.ToJSON()
+        if err != nil {
+            return fmt.Errorf("erreur de sérialisation de l'inventaire: %w", err)
+        }
+        pipe.Set(t.ctx, INVENTORY_KEY_PREFIX+session.ID, sessionJSON, 0)
+        return nil
+    })
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+    "strings"
+    "testing"
+
+    "stock-service/models"
+)
+
+// countInventory ouvre une session sur une pièce et enregistre son comptage
+func countInventory(t *testing.T, s *StockService, pieceID string, counted float64) *models.InventorySession {
+    t.Helper()
+
+    session, err := s.OpenInventory(&models.OpenInventoryRequest{Libelle: "Inventaire", PieceIDs: []string{pieceID}}, "test")
+    if err != nil {
+        t.Fatalf("ouverture de l'inventaire: %v", err)
+    }
+    _, err = s.SubmitInventoryCounts(session.ID, &models.InventoryCountRequest{Comptages: []models.InventoryCount{
+        {PieceID: pieceID, QuantiteComptee: &counted},
+    }}, "test")
+    if err != nil {
+        t.Fatalf("comptage: %v", err)
+    }
+    return session
+}
+
+// TestValidateInventoryKeepsLaterMovements vérifie que l'ajustement reporte
+// l'écart du comptage sans annuler les sorties passées avant la validation
+func TestValidateInventoryKeepsLaterMovements(t *testing.T) {
+    tests := []struct {
+        name      string
+        stock     float64
+        counted   float64
+        issued    float64
+        wantEcart float64
+        wantFinal float64
+    }{
+        {"sans écart", 10, 10, 3, 0, 7},
+        {"manquant", 10, 8, 3, -2, 5},
+        {"excédent", 10, 12, 3, 2, 9},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s, _ := newTestService(t)
+            piece := newTestPiece(t, s, tt.stock)
+            session := countInventory(t, s, piece.ID, tt.counted)
+
+            if _, err := s.DecrementStock(piece.ID, &models.StockMovementRequest{Quantite: tt.issued, Motif: "test"}, "test"); err != nil {
+                t.Fatalf("sortie: %v", err)
+            }
+            if _, err := s.ValidateInventory(session.ID, "test"); err != nil {
+                t.Fatalf("validation: %v", err)
+            }
+
+            report, err := s.GetInventoryReport(session.ID)
+            if err != nil {
+                t.Fatalf("rapport: %v", err)
+            }
+            ecart := report.EcartPositif + report.EcartNegatif
+            if ecart != tt.wantEcart {
+                t.Errorf("écart du rapport = %g, attendu %g", ecart, tt.wantEcart)
+            }
+
+            final, err := s.GetPiece(piece.ID)
+            if err != nil {
+                t.Fatalf("lecture de la pièce: %v", err)
+            }
+            if final.Quantite != tt.wantFinal {
+                t.Errorf("quantité finale = %g, attendu %g", final.Quantite, tt.wantFinal)
+            }
+            if got := final.Quantite - (tt.stock - tt.issued); got != ecart {
+                t.Errorf("ajustement passé = %g, écart du rapport = %g", got, ecart)
+            }
+        })
+    }
+}
+
+// TestValidateInventoryKeepsReservations vérifie qu'un manquant ne peut pas
+// laisser des réservations sans stock
+func TestValidateInventoryKeepsReservations(t *testing.T) {
+    s, _ := newTestService(t)
+    piece := newTestPiece(t, s, 10)
+    if _, _, err := s.ReserveStock(piece.ID, &models.ReservationRequest{InterventionID: "INT-1", Quantite: 6}, "test"); err != nil {
+        t.Fatalf("réservation: %v", err)
+    }
+    session := countInventory(t, s, piece.ID, 4)
+
+    _, err := s.ValidateInventory(session.ID, "test")
+    if err == nil || !strings.HasPrefix(err.Error(), "réservations non couvertes") {
+        t.Fatalf("validation: %v, attendu un refus pour réservations non couvertes", err)
+    }
+
+    final, err := s.GetPiece(piece.ID)
+    if err != nil {
+        t.Fatalf("lecture de la pièce: %v", err)
+    }
+    if final.Quantite != 10 || final.QuantiteReservee != 6 {
+        t.Errorf("pièce: quantite=%g reservee=%g, attendu 10 et 6", final.Quantite, final.QuantiteReservee)
+    }
+}
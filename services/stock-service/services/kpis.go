@@ -0,0 +1,256 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "stock-service/models"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+    "github.com/google/uuid"
+    "go.uber.org/zap"
+)
+
+const (
+    DENIAL_KEY_PREFIX    = "stock:denial:"
+    PIECE_DENIALS_PREFIX = "stock:denials:piece:"
+)
+
+// kpiTotals cumule les grandeurs d'un périmètre avant le calcul des ratios
+type kpiTotals struct {
+    pieces  int
+    issued  models.Decimal
+    average models.Decimal
+    end     models.Decimal
+    served  int
+    denied  int
+}
+
+// isStockDenial indique si une sortie a été refusée faute de stock
+func isStockDenial(err error) bool {
+    return strings.HasPrefix(err.Error(), "stock insuffisant")
+}
+
+// recordDenial historise une demande de sortie refusée faute de stock. Un
+// échec d'enregistrement est journalisé sans modifier la réponse au client.
+func (s *StockService) recordDenial(pieceID string, req *models.StockMovementRequest, reason, userID string) {
+    ctx := context.Background()
+
+    denial := &models.StockDenial{
+        ID:               uuid.New().String(),
+        PieceID:          pieceID,
+        QuantiteDemandee: req.Quantite,
+        Unite:            req.Unite,
+        Emplacement:      req.Emplacement,
+        Motif:            req.Motif,
+        Erreur:           reason,
+        UserID:           userID,
+        CreatedAt:        time.Now(),
+    }
+
+    denialJSON, err := denial.ToJSON()
+    if err == nil {
+        pipe := s.redis.TxPipeline()
+        pipe.Set(ctx, DENIAL_KEY_PREFIX+denial.ID, denialJSON, 0)
+        pipe.ZAdd(ctx, PIECE_DENIALS_PREFIX+pieceID, &redis.Z{
+            Score:  float64(denial.CreatedAt.UnixMilli()),
+            Member: denial.ID,
+        })
+        _, err = pipe.Exec(ctx)
+    }
+    if err != nil {
+        s.logger.Warn("Refus de sortie non historisé", zap.String("piece_id", pieceID), zap.Error(err))
+    }
+}
+
+// GetStockKPIs calcule la rotation, la couverture et le taux de service du
+// stock sur une période, au global et par catégorie. Sans début, la période
+// couvre l'année écoulée; sa fin est ramenée au plus tard à l'instant
+// présent. Les valeurs sont converties dans la devise configurée.
+func (s *StockService) GetStockKPIs(from, to *time.Time, filter models.PieceFilter) (*models.StockKPIs, error) {
+    ctx := context.Background()
+
+    now := time.Now()
+    end := now
+    if to != nil && to.Before(now) {
+        end = *to
+    }
+    start := end.AddDate(0, 0, -models.DEFAULT_KPI_PERIOD_DAYS)
+    if from != nil {
+        start = *from
+    }
+    if !start.Before(end) {
+        return nil, fmt.Errorf("période invalide: le début (%s) doit précéder la fin (%s)",
+            start.Format(time.RFC3339), end.Format(time.RFC3339))
+    }
+
+    pieces, err := s.ListPieces(filter)
+    if err != nil {
+        return nil, err
+    }
+
+    days := end.Sub(start).Hours() / 24
+    var global kpiTotals
+    byCategory := make(map[string]*kpiTotals)
+    names := make(map[string]string)
+
+    for i := range pieces {
+        piece := &pieces[i]
+        totals, err := s.pieceKPIs(ctx, piece, start, end)
+        if err != nil {
+            return nil, err
+        }
+
+        category := piece.Categorie
+        if category == "" {
+            category = "non-classe"
+        }
+        key := strings.ToLower(category)
+        if _, ok := byCategory[key]; !ok {
+            byCategory[key] = &kpiTotals{}
+            names[key] = category
+        }
+        byCategory[key].add(totals)
+        global.add(totals)
+    }
+
+    kpis := &models.StockKPIs{
+        Debut:        start,
+        Fin:          end,
+        Jours:        models.RoundQuantity(days, 2),
+        Methode:      s.config.ValuationMethod,
+        Devise:       s.config.Currency,
+        KPIValues:    global.values(days),
+        ParCategorie: make([]models.CategoryKPIs, 0, len(byCategory)),
+        GeneratedAt:  now,
+    }
+    for key, totals := range byCategory {
+        kpis.ParCategorie = append(kpis.ParCategorie, models.CategoryKPIs{
+            Categorie: names[key],
+            KPIValues: totals.values(days),
+        })
+    }
+    sort.Slice(kpis.ParCategorie, func(i, j int) bool {
+        a, b := kpis.ParCategorie[i], kpis.ParCategorie[j]
+        if a.ValeurSorties != b.ValeurSorties {
+            return a.ValeurSorties > b.ValeurSorties
+        }
+        return a.Categorie < b.Categorie
+    })
+
+    return kpis, nil
+}
+
+// pieceKPIs cumule les grandeurs d'une pièce sur la période. Le stock moyen
+// est pondéré par la durée de chaque niveau, reconstitué à partir des
+// quantités avant et après de chaque mouvement: le niveau au début de la
+// période est la quantité avant le premier mouvement qui la suit.
+func (s *StockService) pieceKPIs(ctx context.Context, piece *models.Piece, start, end time.Time) (kpiTotals, error) {
+    totals := kpiTotals{pieces: 1}
+
+    ids, err := s.redis.ZRangeByScore(ctx, PIECE_MOVEMENTS_PREFIX+piece.ID, &redis.ZRangeBy{
+        Min: strconv.FormatInt(start.UnixMilli(), 10),
+        Max: "+inf",
+    }).Result()
+    if err != nil {
+        return totals, fmt.Errorf("erreur lors de la récupération des mouvements: %w", err)
+    }
+    movements, err := s.loadMovements(ctx, ids)
+    if err != nil {
+        return totals, err
+    }
+
+    cost, err := s.convertMoney(s.unitCost(piece), piece.Devise, s.config.Currency)
+    if err != nil {
+        return totals, err
+    }
+
+    level := piece.Quantite
+    if len(movements) > 0 {
+        level = movements[0].QuantiteAvant
+    }
+    weighted := 0.0
+    cursor := start
+    for _, movement := range movements {
+        if movement.CreatedAt.After(end) {
+            break
+        }
+        weighted += level * movement.CreatedAt.Sub(cursor).Hours()
+        cursor = movement.CreatedAt
+        level = movement.QuantiteApres
+
+        if movement.Type != models.MOVEMENT_TYPE_DECREMENT {
+            continue
+        }
+        totals.served++
+
+        // Coût historisé de la sortie, à défaut coût unitaire courant
+        issued := cost.MulQuantity(movement.Quantite)
+        if movement.CoutSortie != 0 {
+            currency := movement.Devise
+            if currency == "" {
+                currency = piece.Devise
+            }
+            issued, err = s.convertMoney(movement.CoutSortie, currency, s.config.Currency)
+            if err != nil {
+                return totals, err
+            }
+        }
+        totals.issued = totals.issued.Add(models.RoundMoney(issued, s.config.Currency))
+    }
+    weighted += level * end.Sub(cursor).Hours()
+
+    totals.average = models.RoundMoney(cost.MulQuantity(weighted/end.Sub(start).Hours()), s.config.Currency)
+    totals.end = models.RoundMoney(cost.MulQuantity(level), s.config.Currency)
+
+    denied, err := s.redis.ZCount(ctx, PIECE_DENIALS_PREFIX+piece.ID,
+        strconv.FormatInt(start.UnixMilli(), 10), strconv.FormatInt(end.UnixMilli(), 10)).Result()
+    if err != nil {
+        return totals, fmt.Errorf("erreur lors de la récupération des refus de sortie: %w", err)
+    }
+    totals.denied = int(denied)
+
+    return totals, nil
+}
+
+// add cumule les grandeurs d'un autre périmètre
+func (k *kpiTotals) add(other kpiTotals) {
+    k.pieces += other.pieces
+    k.issued = k.issued.Add(other.issued)
+    k.average = k.average.Add(other.average)
+    k.end = k.end.Add(other.end)
+    k.served += other.served
+    k.denied += other.denied
+}
+
+// values calcule les indicateurs d'un périmètre sur une période de days jours
+func (k *kpiTotals) values(days float64) models.KPIValues {
+    values := models.KPIValues{
+        NombrePieces:       k.pieces,
+        ValeurSorties:      k.issued,
+        ValeurStockMoyenne: k.average,
+        ValeurStockFin:     k.end,
+        DemandesServies:    k.served,
+        DemandesRefusees:   k.denied,
+    }
+
+    if k.average > 0 {
+        rotation := k.issued.Float64() / k.average.Float64()
+        rounded := models.RoundQuantity(rotation, 2)
+        annualized := models.RoundQuantity(rotation*365/days, 2)
+        values.Rotation = &rounded
+        values.RotationAnnualisee = &annualized
+    }
+    if k.issued > 0 {
+        coverage := models.RoundQuantity(k.end.Float64()/(k.issued.Float64()/days), 1)
+        values.JoursCouverture = &coverage
+    }
+    if requests := k.served + k.denied; requests > 0 {
+        rate := models.RoundQuantity(100*float64(k.served)/float64(requests), 2)
+        values.TauxService = &rate
+    }
+    return values
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+    "fmt"
+    "sort"
+    "stock-service/models"
+    "strings"
+    "time"
+
+    "go.uber.org/zap"
+)
+
+// allocateLocations détermine les emplacements d'où sortir une quantité.
+// Un emplacement explicite doit couvrir toute la quantité; sinon le stock est
+// pris dans l'emplacement principal, puis dans les emplacements les mieux fournis.
+func allocateLocations(piece *models.Piece, location string, quantite float64) (map[string]float64, error) {
+    if location != "" {
+        location = piece.ResolveLocation(location)
+        if available := piece.LocationQuantity(location); available < quantite {
+            return nil, fmt.Errorf("stock insuffisant à l'emplacement %s: disponible=%s, demandé=%s",
+                location, models.FormatQuantity(available), models.FormatQuantity(quantite))
+        }
+        return map[string]float64{location: quantite}, nil
+    }
+
+    locations := make([]string, 0, len(piece.Emplacements))
+    for name := range piece.Emplacements {
+        locations = append(locations, name)
+    }
+    defaultLocation := piece.DefaultLocation()
+    sort.Slice(locations, func(i, j int) bool {
+        if locations[i] == defaultLocation || locations[j] == defaultLocation {
+            return locations[i] == defaultLocation
+        }
+        qi, qj := piece.Emplacements[locations[i]], piece.Emplacements[locations[j]]
+        if qi != qj {
+            return qi > qj
+        }
+        return locations[i] < locations[j]
+    })
+
+    allocation := make(map[string]float64)
+    remaining := quantite
+    for _, name := range locations {
+        if remaining <= 0 {
+            break
+        }
+        taken := piece.Emplacements[name]
+        if taken > remaining {
+            taken = remaining
+        }
+        if taken > 0 {
+            allocation[name] = taken
+            remaining = piece.RoundQuantity(remaining - taken)
+        }
+    }
+
+    if remaining > 0 {
+        return nil, fmt.Errorf("stock insuffisant: disponible=%s, demandé=%s",
+            models.FormatQuantity(piece.RoundQuantity(quantite-remaining)), models.FormatQuantity(quantite))
+    }
+
+    return allocation, nil
+}
+
+// TransferStock déplace des unités d'une pièce entre deux emplacements.
+// La quantité totale de la pièce est inchangée.
+func (s *StockService) TransferStock(id string, req *models.TransferRequest, userID string) (*models.Piece, *models.StockMovement, error) {
+    var piece *models.Piece
+    var movement *models.StockMovement
+
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        piece, err = t.getPiece(id)
+        if err != nil {
+            return err
+        }
+
+        source := piece.ResolveLocation(req.Source)
+        destination := piece.ResolveLocation(req.Destination)
+        if source == destination {
+            return fmt.Errorf("emplacements identiques: %s", source)
+        }
+
+        if err := piece.ValidateQuantity(req.Quantite); err != nil {
+            return err
+        }
+        if available := piece.LocationQuantity(source); available < req.Quantite {
+            return fmt.Errorf("stock insuffisant à l'emplacement %s: disponible=%s, demandé=%s",
+                source, models.FormatQuantity(available), models.FormatQuantity(req.Quantite))
+        }
+
+        piece.AdjustLocation(source, -req.Quantite)
+        piece.AdjustLocation(destination, req.Quantite)
+        piece.UpdatedAt = time.Now()
+
+        movement = newMovement(id, models.MOVEMENT_TYPE_TRANSFER, req.Quantite, piece.Quantite, piece.Quantite, req.Motif, userID)
+        movement.EmplacementSource = source
+        movement.EmplacementDestination = destination
+
+        t.savePiece(piece)
+        t.addMovement(movement)
+        return nil
+    })
+    if err != nil {
+        return nil, nil, err
+    }
+
+    s.logger.Info("Stock transféré",
+        zap.String("piece_id", id),
+        zap.String("source", movement.EmplacementSource),
+        zap.String("destination", movement.EmplacementDestination),
+        zap.Float64("quantite", req.Quantite),
+        zap.String("motif", req.Motif))
+
+    return piece, movement, nil
+}
+
+// matchesPieceFilter vérifie qu'une pièce satisfait les critères de filtrage
+func matchesPieceFilter(piece *models.Piece, filter models.PieceFilter) bool {
+    if filter.Categorie != "" && !strings.EqualFold(piece.Categorie, strings.TrimSpace(filter.Categorie)) {
+        return false
+    }
+    if filter.Emplacement != "" && !piece.HasLocation(strings.TrimSpace(filter.Emplacement)) {
+        return false
+    }
+    if filter.FournisseurID != "" && piece.SupplierLinkFor(filter.FournisseurID) == nil {
+        return false
+    }
+    if filter.ClasseABC != "" || filter.ClasseXYZ != "" {
+        if piece.Classification == nil {
+            return false
+        }
+        if !models.MatchesClass(piece.Classification.ABC, filter.ClasseABC) ||
+            !models.MatchesClass(piece.Classification.XYZ, filter.ClasseXYZ) {
+            return false
+        }
+    }
+    return true
+}
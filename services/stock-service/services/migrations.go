@@ -0,0 +1,315 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "stock-service/models"
+    "strings"
+
+    "github.com/go-redis/redis/v8"
+    "go.uber.org/zap"
+)
+
+const (
+    SCHEMA_VERSION_KEY = "stock:schema:version"
+
+    // SCHEMA_VERSION_INITIAL est la version d'une base sans version
+    // enregistrée, antérieure aux migrations
+    SCHEMA_VERSION_INITIAL = 1
+
+    // SCHEMA_VERSION_DECIMAL_QUANTITIES correspond au passage des quantités
+    // entières aux quantités décimales
+    SCHEMA_VERSION_DECIMAL_QUANTITIES = 2
+
+    // SCHEMA_VERSION_SUPPLIERS correspond au remplacement du fournisseur en
+    // texte libre par des liens vers le référentiel des fournisseurs
+    SCHEMA_VERSION_SUPPLIERS = 3
+
+    // SCHEMA_VERSION_MOVEMENT_DATES correspond à l'ajout des dates de
+    // dernière entrée et de dernière sortie sur les pièces
+    SCHEMA_VERSION_MOVEMENT_DATES = 4
+
+    // MIGRATION_MOVEMENTS_PAGE est le nombre de mouvements relus à la fois,
+    // du plus récent au plus ancien, pour retrouver les dernières dates
+    MIGRATION_MOVEMENTS_PAGE = 100
+)
+
+// RunMigrations applique les migrations dans l'ordre des versions de schéma
+// et s'arrête à la première en erreur: une migration suppose que les
+// précédentes sont terminées
+func (s *StockService) RunMigrations() error {
+    migrations := []struct {
+        name string
+        run  func() error
+    }{
+        {"quantités décimales", s.MigrateQuantities},
+        {"fournisseurs", s.MigrateSuppliers},
+        {"dates de mouvement", s.MigrateMovementDates},
+    }
+
+    for _, migration := range migrations {
+        if err := migration.run(); err != nil {
+            return fmt.Errorf("migration %s: %w", migration.name, err)
+        }
+    }
+    return nil
+}
+
+// checkSchemaVersion indique si la migration vers la version target est déjà
+// appliquée, et refuse de l'appliquer si la version enregistrée n'est pas la
+// version immédiatement précédente
+func (s *StockService) checkSchemaVersion(ctx context.Context, target int) (bool, error) {
+    version, err := s.redis.Get(ctx, SCHEMA_VERSION_KEY).Int()
+    if err == redis.Nil {
+        version = SCHEMA_VERSION_INITIAL
+    } else if err != nil {
+        return false, fmt.Errorf("erreur lors de la lecture de la version de schéma: %w", err)
+    }
+
+    switch {
+    case version >= target:
+        return true, nil
+    case version != target-1:
+        return false, fmt.Errorf("version de schéma %d: la migration vers la version %d suppose la version %d", version, target, target-1)
+    }
+    return false, nil
+}
+
+// MigrateQuantities réécrit les pièces enregistrées avec des quantités
+// entières. Un entier JSON se relit tel quel en quantité décimale; la
+// réécriture arrondit les quantités à la précision de l'unité de stock et
+// complète les champs dérivés. Les mouvements, réservations et inventaires
+// historiques sont relus sans réécriture. La migration est idempotente et
+// n'est marquée terminée que si toutes les pièces ont été réécrites.
+func (s *StockService) MigrateQuantities() error {
+    ctx := context.Background()
+
+    done, err := s.checkSchemaVersion(ctx, SCHEMA_VERSION_DECIMAL_QUANTITIES)
+    if err != nil || done {
+        return err
+    }
+
+    pieceIDs, err := s.redis.SMembers(ctx, PIECES_SET_KEY).Result()
+    if err != nil {
+        return fmt.Errorf("erreur lors de la récupération des IDs: %w", err)
+    }
+
+    migrated, failed := 0, 0
+    for _, id := range pieceIDs {
+        err := s.runStockTx(func(t *stockTx) error {
+            piece, err := t.getPiece(id)
+            if err != nil {
+                return err
+            }
+            // Réécriture de format: les pièces ne changent pas
+            t.silent = true
+            t.savePiece(piece)
+            return nil
+        })
+        if err != nil {
+            s.logger.Warn("Pièce non migrée", zap.String("id", id), zap.Error(err))
+            failed++
+            continue
+        }
+        migrated++
+    }
+    if failed > 0 {
+        return fmt.Errorf("migration des quantités incomplète: %d pièce(s) en erreur sur %d", failed, len(pieceIDs))
+    }
+
+    if err := s.redis.Set(ctx, SCHEMA_VERSION_KEY, SCHEMA_VERSION_DECIMAL_QUANTITIES, 0).Err(); err != nil {
+        return fmt.Errorf("erreur lors de l'enregistrement de la version de schéma: %w", err)
+    }
+
+    s.logger.Info("Migration des quantités décimales terminée",
+        zap.Int("pieces", migrated),
+        zap.Int("version", SCHEMA_VERSION_DECIMAL_QUANTITIES))
+
+    return nil
+}
+
+// MigrateSuppliers rattache les pièces et les commandes enregistrées avec un
+// fournisseur en texte libre au référentiel des fournisseurs. Les noms
+// équivalents aux accents, à la casse et aux espaces près désignent le même
+// fournisseur, créé au besoin dans la devise de la pièce; le lien créé est
+// préféré et reprend le prix unitaire de la pièce comme prix d'achat. La
+// migration est idempotente et n'est marquée terminée que si toutes les
+// pièces et commandes ont été rattachées.
+func (s *StockService) MigrateSuppliers() error {
+    ctx := context.Background()
+
+    done, err := s.checkSchemaVersion(ctx, SCHEMA_VERSION_SUPPLIERS)
+    if err != nil || done {
+        return err
+    }
+
+    pieces, err := s.GetAllPieces()
+    if err != nil {
+        return err
+    }
+
+    migrated, failed := 0, 0
+    for _, piece := range pieces {
+        if len(piece.Fournisseurs) > 0 || strings.TrimSpace(piece.Fournisseur) == "" {
+            continue
+        }
+        supplier, err := s.ResolveSupplier(piece.Fournisseur, piece.Devise)
+        if err == nil {
+            err = s.runStockTx(func(t *stockTx) error {
+                current, err := t.getPiece(piece.ID)
+                if err != nil {
+                    return err
+                }
+                if len(current.Fournisseurs) > 0 {
+                    return nil
+                }
+                if err := s.preferSupplier(current, supplier, txSuppliers(t, nil)); err != nil {
+                    return err
+                }
+                t.silent = true
+                t.savePiece(current)
+                return nil
+            })
+        }
+        if err != nil {
+            s.logger.Warn("Fournisseur de la pièce non migré", zap.String("id", piece.ID), zap.Error(err))
+            failed++
+            continue
+        }
+        migrated++
+    }
+
+    orders, err := s.GetPurchaseOrders("", "")
+    if err != nil {
+        return err
+    }
+    for _, order := range orders {
+        if order.FournisseurID != "" || strings.TrimSpace(order.Fournisseur) == "" {
+            continue
+        }
+        supplier, err := s.ResolveSupplier(order.Fournisseur, order.Devise)
+        if err == nil {
+            err = s.runStockTx(func(t *stockTx) error {
+                current, err := getPurchaseOrderTx(t, order.ID)
+                if err != nil {
+                    return err
+                }
+                current.FournisseurID = supplier.ID
+                current.Fournisseur = supplier.Nom
+                queuePurchaseOrder(t, current)
+                return nil
+            })
+        }
+        if err != nil {
+            s.logger.Warn("Fournisseur de la commande non migré", zap.String("id", order.ID), zap.Error(err))
+            failed++
+        }
+    }
+
+    if failed > 0 {
+        return fmt.Errorf("migration des fournisseurs incomplète: %d pièce(s) ou commande(s) en erreur", failed)
+    }
+
+    if err := s.redis.Set(ctx, SCHEMA_VERSION_KEY, SCHEMA_VERSION_SUPPLIERS, 0).Err(); err != nil {
+        return fmt.Errorf("erreur lors de l'enregistrement de la version de schéma: %w", err)
+    }
+
+    s.logger.Info("Migration des fournisseurs terminée",
+        zap.Int("pieces", migrated),
+        zap.Int("version", SCHEMA_VERSION_SUPPLIERS))
+
+    return nil
+}
+
+// MigrateMovementDates renseigne les dates de dernière entrée et de dernière
+// sortie des pièces enregistrées avant leur suivi, à partir de l'historique
+// des mouvements. Les mouvements sont relus du plus récent au plus ancien
+// jusqu'à trouver une entrée et une sortie. La migration est idempotente et
+// n'est marquée terminée que si toutes les pièces ont été traitées.
+func (s *StockService) MigrateMovementDates() error {
+    ctx := context.Background()
+
+    done, err := s.checkSchemaVersion(ctx, SCHEMA_VERSION_MOVEMENT_DATES)
+    if err != nil || done {
+        return err
+    }
+
+    pieces, err := s.GetAllPieces()
+    if err != nil {
+        return err
+    }
+
+    migrated, failed := 0, 0
+    for _, piece := range pieces {
+        if piece.DerniereEntree != nil && piece.DerniereSortie != nil {
+            continue
+        }
+        latest, err := s.latestMovements(ctx, piece.ID)
+        if err == nil && len(latest) > 0 {
+            err = s.runStockTx(func(t *stockTx) error {
+                current, err := t.getPiece(piece.ID)
+                if err != nil {
+                    return err
+                }
+                for i := range latest {
+                    current.RecordMovement(&latest[i])
+                }
+                t.silent = true
+                t.savePiece(current)
+                return nil
+            })
+        }
+        if err != nil {
+            s.logger.Warn("Dates de mouvement de la pièce non migrées", zap.String("id", piece.ID), zap.Error(err))
+            failed++
+            continue
+        }
+        migrated++
+    }
+
+    if failed > 0 {
+        return fmt.Errorf("migration des dates de mouvement incomplète: %d pièce(s) en erreur", failed)
+    }
+
+    if err := s.redis.Set(ctx, SCHEMA_VERSION_KEY, SCHEMA_VERSION_MOVEMENT_DATES, 0).Err(); err != nil {
+        return fmt.Errorf("erreur lors de l'enregistrement de la version de schéma: %w", err)
+    }
+
+    s.logger.Info("Migration des dates de mouvement terminée",
+        zap.Int("pieces", migrated),
+        zap.Int("version", SCHEMA_VERSION_MOVEMENT_DATES))
+
+    return nil
+}
+
+// latestMovements retourne la dernière entrée et la dernière sortie de
+// l'historique d'une pièce, quand elles existent
+func (s *StockService) latestMovements(ctx context.Context, pieceID string) ([]models.StockMovement, error) {
+    latest := make([]models.StockMovement, 0, 2)
+    found := make(map[string]bool)
+
+    for start := int64(0); ; start += MIGRATION_MOVEMENTS_PAGE {
+        ids, err := s.redis.ZRevRange(ctx, PIECE_MOVEMENTS_PREFIX+pieceID, start, start+MIGRATION_MOVEMENTS_PAGE-1).Result()
+        if err != nil {
+            return nil, fmt.Errorf("erreur lors de la récupération des mouvements: %w", err)
+        }
+        movements, err := s.loadMovements(ctx, ids)
+        if err != nil {
+            return nil, err
+        }
+
+        for _, movement := range movements {
+            if movement.Type != models.MOVEMENT_TYPE_INCREMENT && movement.Type != models.MOVEMENT_TYPE_DECREMENT {
+                continue
+            }
+            if !found[movement.Type] {
+                found[movement.Type] = true
+                latest = append(latest, movement)
+            }
+        }
+
+        if len(found) == 2 || len(ids) < MIGRATION_MOVEMENTS_PAGE {
+            return latest, nil
+        }
+    }
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "stock-service/models"
+    "strconv"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+    "github.com/google/uuid"
+    "go.uber.org/zap"
+)
+
+const (
+    MOVEMENT_KEY_PREFIX    = "stock:movement:"
+    PIECE_MOVEMENTS_PREFIX = "stock:movements:piece:"
+
+    DEFAULT_MOVEMENTS_LIMIT = 50
+    MAX_MOVEMENTS_LIMIT     = 200
+)
+
+// newMovement prépare un mouvement de stock pour une pièce
+func newMovement(pieceID, movementType string, quantite, avant, apres float64, motif, userID string) *models.StockMovement {
+    return &models.StockMovement{
+        ID:            uuid.New().String(),
+        PieceID:       pieceID,
+        Type:          movementType,
+        Quantite:      quantite,
+        QuantiteAvant: avant,
+        QuantiteApres: apres,
+        Motif:         motif,
+        UserID:        userID,
+        CreatedAt:     time.Now(),
+    }
+}
+
+// initialMovement prépare l'entrée correspondant au stock initial d'une pièce
+func initialMovement(piece *models.Piece, userID string) *models.StockMovement {
+    movement := newMovement(piece.ID, models.MOVEMENT_TYPE_INCREMENT, piece.Quantite, 0, piece.Quantite, models.MOTIF_STOCK_INITIAL, userID)
+    movement.CreatedAt = piece.CreatedAt
+    movement.CoutUnitaire = piece.PrixMoyenPondere
+    movement.Devise = piece.Devise
+
+    movement.Emplacements = make(map[string]float64)
+    for location, quantite := range piece.Emplacements {
+        if quantite > 0 {
+            movement.Emplacements[location] = quantite
+        }
+    }
+
+    switch piece.Suivi {
+    case models.TRACKING_LOT:
+        movement.Lots = make(map[string]float64)
+        for _, lot := range piece.Lots {
+            movement.Lots[lot.Numero] = lot.Quantite
+        }
+    case models.TRACKING_SERIAL:
+        for _, lot := range piece.Lots {
+            movement.NumerosSerie = append(movement.NumerosSerie, lot.Numero)
+        }
+    }
+
+    return movement
+}
+
+// queueMovement ajoute l'enregistrement du mouvement à une transaction Redis
+func queueMovement(ctx context.Context, pipe redis.Pipeliner, movement *models.StockMovement) error {
+    movementJSON, err := movement.ToJSON()
+    if err != nil {
+        return fmt.Errorf("erreur de sérialisation du mouvement: %w", err)
+    }
+
+    pipe.Set(ctx, MOVEMENT_KEY_PREFIX+movement.ID, movementJSON, 0)
+    pipe.ZAdd(ctx, PIECE_MOVEMENTS_PREFIX+movement.PieceID, &redis.Z{
+        Score:  float64(movement.CreatedAt.UnixMilli()),
+        Member: movement.ID,
+    })
+
+    // Index de traçabilité par numéro de série
+    for _, serial := range movement.NumerosSerie {
+        pipe.ZAdd(ctx, SERIAL_MOVEMENTS_PREFIX+serial, &redis.Z{
+            Score:  float64(movement.CreatedAt.UnixMilli()),
+            Member: movement.ID,
+        })
+    }
+
+    return nil
+}
+
+// GetMovements récupère l'historique paginé des mouvements d'une pièce,
+// du plus récent au plus ancien
+func (s *StockService) GetMovements(pieceID string, filter models.MovementFilter) ([]models.StockMovement, int64, error) {
+    ctx := context.Background()
+
+    if _, err := s.GetPiece(pieceID); err != nil {
+        return nil, 0, err
+    }
+
+    if filter.Page < 1 {
+        filter.Page = 1
+    }
+    if filter.Limit < 1 {
+        filter.Limit = DEFAULT_MOVEMENTS_LIMIT
+    }
+    if filter.Limit > MAX_MOVEMENTS_LIMIT {
+        filter.Limit = MAX_MOVEMENTS_LIMIT
+    }
+
+    minScore, maxScore := "-inf", "+inf"
+    if filter.From != nil {
+        minScore = strconv.FormatInt(filter.From.UnixMilli(), 10)
+    }
+    if filter.To != nil {
+        maxScore = strconv.FormatInt(filter.To.UnixMilli(), 10)
+    }
+
+    indexKey := PIECE_MOVEMENTS_PREFIX + pieceID
+
+    total, err := s.redis.ZCount(ctx, indexKey, minScore, maxScore).Result()
+    if err != nil {
+        return nil, 0, fmt.Errorf("erreur lors du comptage des mouvements: %w", err)
+    }
+
+    ids, err := s.redis.ZRevRangeByScore(ctx, indexKey, &redis.ZRangeBy{
+        Min:    minScore,
+        Max:    maxScore,
+        Offset: int64((filter.Page - 1) * filter.Limit),
+        Count:  int64(filter.Limit),
+    }).Result()
+    if err != nil {
+        return nil, 0, fmt.Errorf("erreur lors de la récupération des mouvements: %w", err)
+    }
+
+    movements, err := s.loadMovements(ctx, ids)
+    if err != nil {
+        return nil, 0, err
+    }
+
+    return movements, total, nil
+}
+
+// loadMovements charge les mouvements correspondant aux IDs, dans l'ordre donné
+func (s *StockService) loadMovements(ctx context.Context, ids []string) ([]models.StockMovement, error) {
+    movements := make([]models.StockMovement, 0, len(ids))
+    if len(ids) == 0 {
+        return movements, nil
+    }
+
+    keys := make([]string, len(ids))
+    for i, id := range ids {
+        keys[i] = MOVEMENT_KEY_PREFIX + id
+    }
+
+    values, err := s.redis.MGet(ctx, keys...).Result()
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération des mouvements: %w", err)
+    }
+
+    for i, value := range values {
+        raw, ok := value.(string)
+        if !ok {
+            continue
+        }
+
+        var movement models.StockMovement
+        if err := movement.FromJSON([]byte(raw)); err != nil {
+            s.logger.Warn("Mouvement illisible ignoré", zap.String("id", ids[i]), zap.Error(err))
+            continue
+        }
+        movements = append(movements, movement)
+    }
+
+    return movements, nil
+}
+
+// setMovementUnit conserve la quantité et l'unité saisies lorsque le
+// mouvement a été exprimé dans une autre unité que l'unité de stock
+func setMovementUnit(movement *models.StockMovement, req *models.StockMovementRequest) {
+    if req.Unite == "" {
+        return
+    }
+    movement.Unite = models.CanonicalUnit(req.Unite)
+    movement.QuantiteSaisie = req.Quantite
+}
@@ -0,0 +1,513 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "stock-service/models"
+    "strings"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+    "github.com/google/uuid"
+    "go.uber.org/zap"
+)
+
+const (
+    PURCHASE_ORDER_KEY_PREFIX = "stock:order:"
+    PURCHASE_ORDERS_SET_KEY   = "stock:orders"
+)
+
+// GeneratePurchaseOrders crée des commandes en brouillon à partir des alertes
+// de stock faible, une par fournisseur préféré, au prix d'achat du
+// fournisseur. Chaque pièce en alerte est commandée à la quantité suggérée
+// par sa politique de réapprovisionnement, déduction faite des quantités déjà
+// en commande (brouillons compris). Les pièces sans fournisseur sont
+// regroupées par devise dans des commandes à compléter.
+func (s *StockService) GeneratePurchaseOrders(filter models.PieceFilter, userID string) ([]models.PurchaseOrder, error) {
+    ctx := context.Background()
+
+    pieces, err := s.ListPieces(filter)
+    if err != nil {
+        return nil, err
+    }
+
+    existing, err := s.GetPurchaseOrders("", "")
+    if err != nil {
+        return nil, err
+    }
+    pending := pendingQuantities(existing, true)
+
+    now := time.Now()
+    orders := make([]*models.PurchaseOrder, 0)
+    bySupplier := make(map[string]*models.PurchaseOrder)
+
+    for i := range pieces {
+        piece := &pieces[i]
+        if !piece.IsLowStock() {
+            continue
+        }
+
+        quantite, err := s.suggestedQuantity(piece, pending[piece.ID])
+        if err != nil {
+            return nil, err
+        }
+        if quantite <= 0 {
+            continue
+        }
+
+        supplier, price := s.preferredPurchase(piece)
+        key := "|" + piece.Devise
+        if supplier != nil {
+            key = supplier.ID
+        }
+        order, ok := bySupplier[key]
+        if !ok {
+            order = newPurchaseOrder(supplier, piece.Devise, userID, now)
+            bySupplier[key] = order
+            orders = append(orders, order)
+        }
+        order.Lignes = append(order.Lignes, models.PurchaseOrderLine{
+            PieceID:      piece.ID,
+            Nom:          piece.Nom,
+            Unite:        piece.UniteStock,
+            Quantite:     quantite,
+            PrixUnitaire: price,
+        })
+    }
+
+    result := make([]models.PurchaseOrder, 0, len(orders))
+    if len(orders) == 0 {
+        return result, nil
+    }
+
+    pipe := s.redis.TxPipeline()
+    for _, order := range orders {
+        order.RefreshAmounts()
+        orderJSON, err := order.ToJSON()
+        if err != nil {
+            return nil, fmt.Errorf("erreur de sérialisation: %w", err)
+        }
+        pipe.Set(ctx, PURCHASE_ORDER_KEY_PREFIX+order.ID, orderJSON, 0)
+        pipe.SAdd(ctx, PURCHASE_ORDERS_SET_KEY, order.ID)
+        result = append(result, *order)
+    }
+    if _, err := pipe.Exec(ctx); err != nil {
+        return nil, fmt.Errorf("erreur lors de la création des commandes: %w", err)
+    }
+
+    s.logger.Info("Commandes fournisseur générées depuis les alertes",
+        zap.Int("commandes", len(result)),
+        zap.String("user_id", userID))
+
+    return result, nil
+}
+
+// GetPurchaseOrder récupère une commande fournisseur par ID
+func (s *StockService) GetPurchaseOrder(id string) (*models.PurchaseOrder, error) {
+    ctx := context.Background()
+
+    orderJSON, err := s.redis.Get(ctx, PURCHASE_ORDER_KEY_PREFIX+id).Result()
+    if err == redis.Nil {
+        return nil, fmt.Errorf("commande non trouvée: %s", id)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération de la commande: %w", err)
+    }
+
+    var order models.PurchaseOrder
+    if err := order.FromJSON([]byte(orderJSON)); err != nil {
+        return nil, fmt.Errorf("erreur de désérialisation: %w", err)
+    }
+
+    return &order, nil
+}
+
+// GetPurchaseOrders récupère les commandes fournisseur, filtrées par statut
+// et par fournisseur (ID ou nom) si fournis, de la plus récente à la plus
+// ancienne
+func (s *StockService) GetPurchaseOrders(statut, fournisseur string) ([]models.PurchaseOrder, error) {
+    ctx := context.Background()
+
+    ids, err := s.redis.SMembers(ctx, PURCHASE_ORDERS_SET_KEY).Result()
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération des commandes: %w", err)
+    }
+
+    orders := make([]models.PurchaseOrder, 0, len(ids))
+    for _, id := range ids {
+        order, err := s.GetPurchaseOrder(id)
+        if err != nil {
+            s.logger.Warn("Impossible de récupérer la commande", zap.String("id", id), zap.Error(err))
+            continue
+        }
+        if statut != "" && order.Statut != statut {
+            continue
+        }
+        if fournisseur != "" && order.FournisseurID != fournisseur && !strings.EqualFold(order.Fournisseur, fournisseur) {
+            continue
+        }
+        orders = append(orders, *order)
+    }
+
+    sort.Slice(orders, func(i, j int) bool {
+        return orders[i].CreatedAt.After(orders[j].CreatedAt)
+    })
+
+    return orders, nil
+}
+
+// UpdatePurchaseOrder modifie le fournisseur, les notes ou les lignes d'une
+// commande en brouillon. Un changement de fournisseur convertit les prix dans
+// sa devise; une ligne sans prix reprend le prix d'achat chez le fournisseur
+// de la commande, à défaut le prix unitaire de la pièce.
+func (s *StockService) UpdatePurchaseOrder(id string, req *models.UpdatePurchaseOrderRequest, userID string) (*models.PurchaseOrder, error) {
+    var order *models.PurchaseOrder
+
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        order, err = getPurchaseOrderTx(t, id)
+        if err != nil {
+            return err
+        }
+        if order.Statut != models.PURCHASE_ORDER_STATUS_DRAFT {
+            return fmt.Errorf("commande non modifiable: %s (statut=%s)", id, order.Statut)
+        }
+
+        if req.FournisseurID != nil {
+            if err := s.changeOrderSupplier(order, *req.FournisseurID); err != nil {
+                return err
+            }
+        }
+        if req.Notes != nil {
+            order.Notes = *req.Notes
+        }
+        if req.Lignes != nil {
+            lines, err := s.purchaseOrderLines(order, req.Lignes)
+            if err != nil {
+                return err
+            }
+            order.Lignes = lines
+        }
+
+        order.RefreshAmounts()
+        order.UpdatedAt = time.Now()
+        queuePurchaseOrder(t, order)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    s.logger.Info("Commande fournisseur modifiée",
+        zap.String("commande_id", id),
+        zap.String("user_id", userID))
+
+    return order, nil
+}
+
+// SendPurchaseOrder passe une commande en brouillon au statut envoyée; ses
+// quantités apparaissent dès lors comme en commande dans les alertes
+func (s *StockService) SendPurchaseOrder(id string, userID string) (*models.PurchaseOrder, error) {
+    var order *models.PurchaseOrder
+
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        order, err = getPurchaseOrderTx(t, id)
+        if err != nil {
+            return err
+        }
+        if order.Statut != models.PURCHASE_ORDER_STATUS_DRAFT {
+            return fmt.Errorf("commande non modifiable: %s (statut=%s)", id, order.Statut)
+        }
+        if order.FournisseurID == "" {
+            return fmt.Errorf("commande invalide: fournisseur requis")
+        }
+        if len(order.Lignes) == 0 {
+            return fmt.Errorf("commande invalide: aucune ligne")
+        }
+
+        now := time.Now()
+        order.Statut = models.PURCHASE_ORDER_STATUS_SENT
+        order.SentAt = &now
+        order.UpdatedAt = now
+        queuePurchaseOrder(t, order)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    s.logger.Info("Commande fournisseur envoyée",
+        zap.String("commande_id", id),
+        zap.String("fournisseur", order.Fournisseur),
+        zap.String("user_id", userID))
+
+    return order, nil
+}
+
+// CancelPurchaseOrder annule une commande non soldée; les quantités déjà
+// reçues restent en stock
+func (s *StockService) CancelPurchaseOrder(id string, userID string) (*models.PurchaseOrder, error) {
+    var order *models.PurchaseOrder
+
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        order, err = getPurchaseOrderTx(t, id)
+        if err != nil {
+            return err
+        }
+        if order.IsClosed() {
+            return fmt.Errorf("commande non modifiable: %s (statut=%s)", id, order.Statut)
+        }
+
+        now := time.Now()
+        order.Statut = models.PURCHASE_ORDER_STATUS_CANCELLED
+        order.CancelledAt = &now
+        order.UpdatedAt = now
+        queuePurchaseOrder(t, order)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    s.logger.Info("Commande fournisseur annulée",
+        zap.String("commande_id", id),
+        zap.String("user_id", userID))
+
+    return order, nil
+}
+
+// ReceivePurchaseOrder enregistre une livraison sur une commande envoyée:
+// chaque ligne reçue passe une entrée de stock valorisée au prix de la
+// commande, dans la même transaction que la mise à jour de la commande
+func (s *StockService) ReceivePurchaseOrder(id string, req *models.ReceivePurchaseOrderRequest, userID string) (*models.PurchaseOrderReceiptResult, error) {
+    var order *models.PurchaseOrder
+    var movements []models.StockMovement
+
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        order, err = getPurchaseOrderTx(t, id)
+        if err != nil {
+            return err
+        }
+        if !order.IsOpen() {
+            return fmt.Errorf("commande non ouverte: %s (statut=%s)", id, order.Statut)
+        }
+
+        movements = make([]models.StockMovement, 0, len(req.Receptions))
+        for _, receipt := range req.Receptions {
+            index := order.FindLine(receipt.PieceID)
+            if index < 0 {
+                return fmt.Errorf("ligne de commande non trouvée: pièce %s", receipt.PieceID)
+            }
+            line := &order.Lignes[index]
+            if receipt.Quantite > line.Remaining()+models.QUANTITY_EPSILON {
+                return fmt.Errorf("réception invalide: %s reçu(s) pour %s restant(s) sur la pièce %s",
+                    models.FormatQuantity(receipt.Quantite), models.FormatQuantity(line.Remaining()), receipt.PieceID)
+            }
+
+            cost := line.PrixUnitaire
+            piece, movement, err := s.applyIncrement(t, receipt.PieceID, &models.StockMovementRequest{
+                Quantite:       receipt.Quantite,
+                Motif:          models.PURCHASE_ORDER_MOTIF + " " + order.Numero,
+                Emplacement:    receipt.Emplacement,
+                Lot:            receipt.Lot,
+                NumerosSerie:   receipt.NumerosSerie,
+                DatePeremption: receipt.DatePeremption,
+                CoutUnitaire:   &cost,
+                Devise:         order.Devise,
+            }, userID)
+            if err != nil {
+                return err
+            }
+            movement.CommandeID = order.ID
+
+            line.QuantiteRecue = piece.RoundQuantity(line.QuantiteRecue + movement.Quantite)
+            movements = append(movements, *movement)
+        }
+
+        now := time.Now()
+        order.Statut = models.PURCHASE_ORDER_STATUS_RECEIVED
+        for _, line := range order.Lignes {
+            if line.Remaining() > 0 {
+                order.Statut = models.PURCHASE_ORDER_STATUS_PARTIAL
+                break
+            }
+        }
+        if order.Statut == models.PURCHASE_ORDER_STATUS_RECEIVED {
+            order.ReceivedAt = &now
+        }
+        order.UpdatedAt = now
+        queuePurchaseOrder(t, order)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    s.logger.Info("Réception de commande fournisseur",
+        zap.String("commande_id", id),
+        zap.String("statut", order.Statut),
+        zap.Int("mouvements", len(movements)),
+        zap.String("user_id", userID))
+
+    return &models.PurchaseOrderReceiptResult{Commande: order, Mouvements: movements}, nil
+}
+
+// onOrderQuantities retourne, par pièce, la quantité restant à livrer sur
+// les commandes envoyées ou partiellement reçues
+func (s *StockService) onOrderQuantities() (map[string]float64, error) {
+    orders, err := s.GetPurchaseOrders("", "")
+    if err != nil {
+        return nil, err
+    }
+    return pendingQuantities(orders, false), nil
+}
+
+// pendingQuantities cumule par pièce les quantités restant à livrer sur les
+// commandes ouvertes, et sur les brouillons si demandé
+func pendingQuantities(orders []models.PurchaseOrder, withDrafts bool) map[string]float64 {
+    pending := make(map[string]float64)
+    for _, order := range orders {
+        if !order.IsOpen() && !(withDrafts && order.Statut == models.PURCHASE_ORDER_STATUS_DRAFT) {
+            continue
+        }
+        for _, line := range order.Lignes {
+            pending[line.PieceID] += line.Remaining()
+        }
+    }
+    return pending
+}
+
+// purchaseOrderLines construit les lignes d'une commande à partir d'une saisie
+func (s *StockService) purchaseOrderLines(order *models.PurchaseOrder, requests []models.PurchaseOrderLineRequest) ([]models.PurchaseOrderLine, error) {
+    lines := make([]models.PurchaseOrderLine, 0, len(requests))
+    seen := make(map[string]bool, len(requests))
+
+    for _, req := range requests {
+        if seen[req.PieceID] {
+            return nil, fmt.Errorf("commande invalide: pièce en double %s", req.PieceID)
+        }
+        seen[req.PieceID] = true
+
+        piece, err := s.GetPiece(req.PieceID)
+        if err != nil {
+            return nil, err
+        }
+        if err := piece.ValidateQuantity(req.Quantite); err != nil {
+            return nil, err
+        }
+
+        price, devise := piece.PrixUnitaire, piece.Devise
+        if link := piece.SupplierLinkFor(order.FournisseurID); order.FournisseurID != "" && link != nil {
+            if supplier, err := s.GetSupplier(order.FournisseurID); err == nil {
+                price, devise = link.PrixAchat, supplier.Devise
+            }
+        }
+        if req.PrixUnitaire != nil {
+            price = *req.PrixUnitaire
+        } else if price, err = s.convertMoney(price, devise, order.Devise); err != nil {
+            return nil, err
+        }
+
+        lines = append(lines, models.PurchaseOrderLine{
+            PieceID:      piece.ID,
+            Nom:          piece.Nom,
+            Unite:        piece.UniteStock,
+            Quantite:     req.Quantite,
+            PrixUnitaire: price,
+        })
+    }
+
+    return lines, nil
+}
+
+// preferredPurchase retourne le fournisseur préféré d'une pièce et son prix
+// d'achat; sans fournisseur, le prix unitaire de la pièce
+func (s *StockService) preferredPurchase(piece *models.Piece) (*models.Supplier, models.Decimal) {
+    link := piece.PreferredSupplier()
+    if link == nil {
+        return nil, piece.PrixUnitaire
+    }
+    supplier, err := s.GetSupplier(link.FournisseurID)
+    if err != nil {
+        s.logger.Warn("Fournisseur préféré introuvable", zap.String("piece_id", piece.ID), zap.Error(err))
+        return nil, piece.PrixUnitaire
+    }
+    return supplier, link.PrixAchat
+}
+
+// changeOrderSupplier affecte une commande à un fournisseur et convertit les
+// prix des lignes dans la devise de ce fournisseur
+func (s *StockService) changeOrderSupplier(order *models.PurchaseOrder, supplierID string) error {
+    supplier, err := s.GetSupplier(supplierID)
+    if err != nil {
+        return fmt.Errorf("fournisseur invalide: %s inconnu", supplierID)
+    }
+
+    for i := range order.Lignes {
+        line := &order.Lignes[i]
+        if line.PrixUnitaire, err = s.convertMoney(line.PrixUnitaire, order.Devise, supplier.Devise); err != nil {
+            return err
+        }
+    }
+
+    order.FournisseurID = supplier.ID
+    order.Fournisseur = supplier.Nom
+    order.Devise = supplier.Devise
+    return nil
+}
+
+// newPurchaseOrder prépare une commande en brouillon pour un fournisseur, ou
+// sans fournisseur dans la devise donnée
+func newPurchaseOrder(supplier *models.Supplier, devise, userID string, now time.Time) *models.PurchaseOrder {
+    id := uuid.New().String()
+    order := &models.PurchaseOrder{
+        ID:        id,
+        Numero:    fmt.Sprintf("CF-%s-%s", now.Format("20060102"), id[:8]),
+        Statut:    models.PURCHASE_ORDER_STATUS_DRAFT,
+        Devise:    models.CanonicalCurrency(devise),
+        Lignes:    make([]models.PurchaseOrderLine, 0),
+        CreatedBy: userID,
+        CreatedAt: now,
+        UpdatedAt: now,
+    }
+    if supplier != nil {
+        order.FournisseurID = supplier.ID
+        order.Fournisseur = supplier.Nom
+        order.Devise = supplier.Devise
+    }
+    return order
+}
+
+// getPurchaseOrderTx lit une commande en plaçant sa clé sous surveillance
+func getPurchaseOrderTx(t *stockTx, id string) (*models.PurchaseOrder, error) {
+    orderJSON, err := t.watchGet(PURCHASE_ORDER_KEY_PREFIX + id)
+    if err == redis.Nil {
+        return nil, fmt.Errorf("commande non trouvée: %s", id)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération de la commande: %w", err)
+    }
+
+    var order models.PurchaseOrder
+    if err := order.FromJSON([]byte(orderJSON)); err != nil {
+        return nil, fmt.Errorf("erreur de désérialisation: %w", err)
+    }
+
+    return &order, nil
+}
+
+// queuePurchaseOrder met en file l'enregistrement d'une commande
+func queuePurchaseOrder(t *stockTx, order *models.PurchaseOrder) {
+    t.queue(func(pipe redis.Pipeliner) error {
+        orderJSON, err := order.ToJSON()
+        if err != nil {
+            return fmt.Errorf("erreur de sérialisation de la commande: %w", err)
+        }
+        pipe.Set(t.ctx, PURCHASE_ORDER_KEY_PREFIX+order.ID, orderJSON, 0)
+        return nil
+    })
+}
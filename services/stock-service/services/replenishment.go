@@ -0,0 +1,404 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "math"
+    "sort"
+    "stock-service/models"
+    "strconv"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+    "go.uber.org/zap"
+)
+
+// issueMovements charge les sorties de stock d'une pièce sur une période, de
+// la plus ancienne à la plus récente. Les ajustements d'inventaire ne sont
+// pas des consommations et sont exclus.
+func (s *StockService) issueMovements(pieceID string, from, to time.Time) ([]models.StockMovement, error) {
+    ctx := context.Background()
+
+    ids, err := s.redis.ZRangeByScore(ctx, PIECE_MOVEMENTS_PREFIX+pieceID, &redis.ZRangeBy{
+        Min: strconv.FormatInt(from.UnixMilli(), 10),
+        Max: strconv.FormatInt(to.UnixMilli(), 10),
+    }).Result()
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération des mouvements: %w", err)
+    }
+
+    movements, err := s.loadMovements(ctx, ids)
+    if err != nil {
+        return nil, err
+    }
+
+    issues := make([]models.StockMovement, 0, len(movements))
+    for _, movement := range movements {
+        if movement.Type == models.MOVEMENT_TYPE_DECREMENT {
+            issues = append(issues, movement)
+        }
+    }
+    return issues, nil
+}
+
+// consumptionSeries répartit les sorties d'une pièce en consommations par
+// période de periodDays jours, les périodes sans sortie comptant pour zéro.
+// La série commence au plus tôt à la création de la pièce, pour ne pas
+// diluer la consommation d'une pièce récente.
+func consumptionSeries(piece *models.Piece, issues []models.StockMovement, from, to time.Time, periodDays int) []float64 {
+    if !piece.CreatedAt.IsZero() && piece.CreatedAt.After(from) {
+        from = piece.CreatedAt
+    }
+    period := time.Duration(periodDays) * 24 * time.Hour
+    count := int(math.Ceil(float64(to.Sub(from)) / float64(period)))
+    if count < 1 {
+        count = 1
+    }
+
+    series := make([]float64, count)
+    for _, movement := range issues {
+        index := int(movement.CreatedAt.Sub(from) / period)
+        if index < 0 {
+            index = 0
+        }
+        if index >= count {
+            index = count - 1
+        }
+        series[index] += movement.Quantite
+    }
+    return series
+}
+
+// meanStdDev retourne la moyenne et l'écart type (échantillon) d'une série
+func meanStdDev(series []float64) (float64, float64) {
+    if len(series) == 0 {
+        return 0, 0
+    }
+    total := 0.0
+    for _, value := range series {
+        total += value
+    }
+    mean := total / float64(len(series))
+    if len(series) < 2 {
+        return mean, 0
+    }
+    variance := 0.0
+    for _, value := range series {
+        variance += (value - mean) * (value - mean)
+    }
+    return mean, math.Sqrt(variance / float64(len(series)-1))
+}
+
+// consumptionStats calcule la moyenne et l'écart type de la consommation
+// journalière d'une pièce
+func consumptionStats(piece *models.Piece, issues []models.StockMovement, from, to time.Time) models.ConsumptionStats {
+    daily := consumptionSeries(piece, issues, from, to, 1)
+    stats := models.ConsumptionStats{
+        JoursObserves: len(daily),
+        NombreSorties: len(issues),
+    }
+    for _, movement := range issues {
+        stats.Total += movement.Quantite
+    }
+    stats.Moyenne, stats.EcartType = meanStdDev(daily)
+    return stats
+}
+
+// serviceFactor retourne le coefficient de sécurité associé à un niveau de
+// service, quantile de la loi normale centrée réduite (1.645 pour 95%)
+func serviceFactor(level float64) float64 {
+    return math.Sqrt2 * math.Erfinv(2*level-1)
+}
+
+// reorderParams complète les paramètres du calcul avec la configuration et
+// les valide
+func (s *StockService) reorderParams(params models.ReorderPointParams) (models.ReorderPointParams, error) {
+    if params.NiveauService == 0 {
+        params.NiveauService = s.config.ServiceLevel
+    }
+    if params.PeriodeJours == 0 {
+        params.PeriodeJours = s.config.ConsumptionWindowDays
+    }
+    if params.NiveauService < 0.5 || params.NiveauService >= 1 {
+        return params, fmt.Errorf("niveau de service invalide: %g (attendu entre 0.5 et 1 exclu)", params.NiveauService)
+    }
+    if params.PeriodeJours < 1 {
+        return params, fmt.Errorf("période invalide: %d jour(s)", params.PeriodeJours)
+    }
+    return params, nil
+}
+
+// supplierIndex charge les fournisseurs indexés par ID
+func (s *StockService) supplierIndex() (map[string]*models.Supplier, error) {
+    suppliers, err := s.GetSuppliers(false)
+    if err != nil {
+        return nil, err
+    }
+    index := make(map[string]*models.Supplier, len(suppliers))
+    for i := range suppliers {
+        index[suppliers[i].ID] = &suppliers[i]
+    }
+    return index, nil
+}
+
+// leadTimeDays retourne le fournisseur préféré de la pièce et son délai de
+// livraison, à défaut le délai configuré
+func (s *StockService) leadTimeDays(piece *models.Piece, suppliers map[string]*models.Supplier) (string, int) {
+    if link := piece.PreferredSupplier(); link != nil {
+        if supplier, ok := suppliers[link.FournisseurID]; ok {
+            return supplier.ID, supplier.DelaiLivraisonJours
+        }
+    }
+    return "", s.config.DefaultLeadTimeDays
+}
+
+// reorderPoint calcule le point de commande d'une pièce:
+// stock de sécurité = z × σ × √L et point de commande = μ × L + stock de
+// sécurité, avec μ et σ la moyenne et l'écart type de la consommation
+// journalière, L le délai de livraison en jours et z le coefficient du
+// niveau de service. Les deux quantités sont arrondies par excès à la
+// précision de l'unité de stock.
+func reorderPoint(piece *models.Piece, stats models.ConsumptionStats, supplierID string, leadTime int, level float64) models.ReorderPoint {
+    lead := float64(leadTime)
+    safety := piece.CeilQuantity(serviceFactor(level) * stats.EcartType * math.Sqrt(lead))
+    point := piece.CeilQuantity(stats.Moyenne*lead + safety)
+
+    stats.Total = piece.RoundQuantity(stats.Total)
+    stats.Moyenne = models.RoundQuantity(stats.Moyenne, 4)
+    stats.EcartType = models.RoundQuantity(stats.EcartType, 4)
+
+    return models.ReorderPoint{
+        PieceID:             piece.ID,
+        Nom:                 piece.Nom,
+        Categorie:           piece.Categorie,
+        Unite:               piece.UniteStock,
+        SeuilActuel:         piece.SeuilMin,
+        Consommation:        stats,
+        FournisseurID:       supplierID,
+        DelaiLivraisonJours: leadTime,
+        NiveauService:       level,
+        StockSecurite:       safety,
+        PointCommande:       point,
+        Ecart:               piece.RoundQuantity(point - piece.SeuilMin),
+        Fiable:              stats.NombreSorties >= models.MIN_REORDER_ISSUES,
+    }
+}
+
+// computeReorderPoints calcule les points de commande d'une liste de pièces
+func (s *StockService) computeReorderPoints(pieces []models.Piece, params models.ReorderPointParams) ([]models.ReorderPoint, error) {
+    suppliers, err := s.supplierIndex()
+    if err != nil {
+        return nil, err
+    }
+
+    to := time.Now()
+    from := to.AddDate(0, 0, -params.PeriodeJours)
+
+    points := make([]models.ReorderPoint, 0, len(pieces))
+    for i := range pieces {
+        piece := &pieces[i]
+        issues, err := s.issueMovements(piece.ID, from, to)
+        if err != nil {
+            return nil, err
+        }
+        supplierID, leadTime := s.leadTimeDays(piece, suppliers)
+        stats := consumptionStats(piece, issues, from, to)
+        points = append(points, reorderPoint(piece, stats, supplierID, leadTime, params.NiveauService))
+    }
+    return points, nil
+}
+
+// GetReorderPoints calcule les points de commande recommandés des pièces,
+// les seuils les plus éloignés de la recommandation en premier
+func (s *StockService) GetReorderPoints(filter models.PieceFilter, params models.ReorderPointParams) ([]models.ReorderPoint, error) {
+    params, err := s.reorderParams(params)
+    if err != nil {
+        return nil, err
+    }
+
+    pieces, err := s.ListPieces(filter)
+    if err != nil {
+        return nil, err
+    }
+
+    points, err := s.computeReorderPoints(pieces, params)
+    if err != nil {
+        return nil, err
+    }
+
+    sort.SliceStable(points, func(i, j int) bool {
+        return math.Abs(points[i].Ecart) > math.Abs(points[j].Ecart)
+    })
+    return points, nil
+}
+
+// ApplyReorderPoints remplace le seuil minimum des pièces par leur point de
+// commande recommandé. Les pièces dont l'historique est insuffisant, dont le
+// seuil est déjà à jour, ou dont le nouveau seuil serait nul ou incompatible
+// avec la politique de réapprovisionnement, sont ignorées avec leur motif:
+// une pièce refusée n'interrompt pas l'application aux suivantes.
+func (s *StockService) ApplyReorderPoints(filter models.PieceFilter, req *models.ApplyReorderPointsRequest, userID string) (*models.ReorderPointsResult, error) {
+    params, err := s.reorderParams(models.ReorderPointParams{
+        NiveauService: req.NiveauService,
+        PeriodeJours:  req.PeriodeJours,
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    var pieces []models.Piece
+    if len(req.PieceIDs) > 0 {
+        for _, id := range req.PieceIDs {
+            piece, err := s.GetPiece(id)
+            if err != nil {
+                return nil, err
+            }
+            pieces = append(pieces, *piece)
+        }
+    } else {
+        pieces, err = s.ListPieces(filter)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    points, err := s.computeReorderPoints(pieces, params)
+    if err != nil {
+        return nil, err
+    }
+
+    result := &models.ReorderPointsResult{
+        Appliques: make([]models.ReorderPoint, 0),
+        Ignores:   make([]models.ReorderPoint, 0),
+    }
+    for _, point := range points {
+        switch {
+        case !point.Fiable:
+            point.Raison = "historique insuffisant"
+        case point.Ecart == 0:
+            point.Raison = "seuil déjà à jour"
+        case point.PointCommande <= 0:
+            point.Raison = "point de commande nul"
+        }
+        if point.Raison != "" {
+            result.Ignores = append(result.Ignores, point)
+            continue
+        }
+
+        err := s.runStockTx(func(t *stockTx) error {
+            piece, err := t.getPiece(point.PieceID)
+            if err != nil {
+                return err
+            }
+            piece.SeuilMin = point.PointCommande
+            if err := piece.ValidatePolicy(); err != nil {
+                return err
+            }
+            piece.UpdatedAt = time.Now()
+            t.savePiece(piece)
+            return nil
+        })
+        if err != nil {
+            s.logger.Warn("Point de commande non appliqué", zap.String("piece_id", point.PieceID), zap.Error(err))
+            point.Raison = err.Error()
+            result.Ignores = append(result.Ignores, point)
+            continue
+        }
+        result.Appliques = append(result.Appliques, point)
+    }
+
+    s.logger.Info("Points de commande appliqués",
+        zap.Int("appliques", len(result.Appliques)),
+        zap.Int("ignores", len(result.Ignores)),
+        zap.Float64("niveau_service", params.NiveauService),
+        zap.String("user_id", userID))
+
+    return result, nil
+}
+
+// setReplenishmentPolicy affecte la politique de réapprovisionnement de la
+// pièce; le type "aucune" la retire
+func setReplenishmentPolicy(piece *models.Piece, policy *models.ReplenishmentPolicy) error {
+    if policy == nil || policy.Type == models.REPLENISHMENT_POLICY_NONE {
+        piece.PolitiqueReappro = nil
+        return nil
+    }
+    copied := *policy
+    piece.PolitiqueReappro = &copied
+    return piece.ValidatePolicy()
+}
+
+// annualDemand estime la consommation annuelle d'une pièce à partir de sa
+// consommation journalière sur la période configurée
+func (s *StockService) annualDemand(piece *models.Piece) (float64, error) {
+    to := time.Now()
+    from := to.AddDate(0, 0, -s.config.ConsumptionWindowDays)
+
+    issues, err := s.issueMovements(piece.ID, from, to)
+    if err != nil {
+        return 0, err
+    }
+    return consumptionStats(piece, issues, from, to).Moyenne * 365, nil
+}
+
+// economicOrderQuantity calcule la quantité économique de commande (formule
+// de Wilson): √(2 × D × S / H), avec D la consommation annuelle, S le coût de
+// passation d'une commande et H le coût annuel de possession d'une unité.
+// Sans consommation observée, elle est nulle.
+func (s *StockService) economicOrderQuantity(piece *models.Piece) (float64, error) {
+    policy := piece.PolitiqueReappro
+    holding := s.unitCost(piece).Float64() * policy.TauxPossession
+    if holding <= 0 {
+        return 0, nil
+    }
+
+    demand, err := s.annualDemand(piece)
+    if err != nil {
+        return 0, err
+    }
+    return math.Sqrt(2 * demand * policy.CoutPassation.Float64() / holding), nil
+}
+
+// suggestedQuantity calcule la quantité à commander pour une pièce selon sa
+// politique de réapprovisionnement, compte tenu du stock disponible et des
+// quantités déjà en commande:
+//   - min/max: jusqu'au stock maximum;
+//   - QEC: la quantité économique, au moins ce qui manque pour repasser le
+//     seuil minimum;
+//   - sans politique: jusqu'au double du seuil minimum.
+// Sous politique explicite, rien n'est commandé tant que le stock disponible
+// augmenté des commandes en cours dépasse le seuil. La quantité est ensuite
+// portée au minimum de commande du fournisseur préféré et arrondie au
+// conditionnement supérieur.
+func (s *StockService) suggestedQuantity(piece *models.Piece, pending float64) (float64, error) {
+    position := piece.QuantiteDisponible + pending
+    policy := piece.PolitiqueReappro
+
+    var quantite float64
+    switch {
+    case policy == nil:
+        quantite = 2*piece.SeuilMin - position
+    case position > piece.SeuilMin:
+        return 0, nil
+    case policy.Type == models.REPLENISHMENT_POLICY_MIN_MAX:
+        quantite = math.Max(policy.StockMax, piece.SeuilMin) - position
+    case policy.Type == models.REPLENISHMENT_POLICY_EOQ:
+        eoq, err := s.economicOrderQuantity(piece)
+        if err != nil {
+            return 0, err
+        }
+        quantite = math.Max(eoq, piece.SeuilMin-position)
+    }
+    if quantite <= 0 {
+        return 0, nil
+    }
+
+    if link := piece.PreferredSupplier(); link != nil && link.QuantiteMinimum > quantite {
+        quantite = link.QuantiteMinimum
+    }
+    if policy != nil && policy.Conditionnement > 0 {
+        quantite = math.Ceil(quantite/policy.Conditionnement-models.QUANTITY_EPSILON) * policy.Conditionnement
+    }
+
+    return piece.CeilQuantity(quantite), nil
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+    "math"
+    "testing"
+    "time"
+
+    "stock-service/models"
+)
+
+func TestServiceFactor(t *testing.T) {
+    tests := []struct {
+        level float64
+        want  float64
+    }{
+        {0.5, 0},
+        {0.9, 1.2816},
+        {0.95, 1.6449},
+        {0.99, 2.3263},
+    }
+
+    for _, tt := range tests {
+        if got := serviceFactor(tt.level); math.Abs(got-tt.want) > 1e-4 {
+            t.Errorf("serviceFactor(%g) = %.4f, attendu %.4f", tt.level, got, tt.want)
+        }
+    }
+}
+
+func TestMeanStdDev(t *testing.T) {
+    tests := []struct {
+        name     string
+        series   []float64
+        wantMean float64
+        wantDev  float64
+    }{
+        {"série vide", nil, 0, 0},
+        {"une valeur", []float64{4}, 4, 0},
+        {"constante", []float64{3, 3, 3}, 3, 0},
+        {"écart type d'échantillon", []float64{2, 4, 4, 4, 5, 5, 7, 9}, 5, 2.1381},
+    }
+
+    for _, tt := range tests {
+        mean, dev := meanStdDev(tt.series)
+        if math.Abs(mean-tt.wantMean) > 1e-4 || math.Abs(dev-tt.wantDev) > 1e-4 {
+            t.Errorf("%s: moyenne=%.4f écart type=%.4f, attendu %.4f et %.4f", tt.name, mean, dev, tt.wantMean, tt.wantDev)
+        }
+    }
+}
+
+func TestConsumptionSeries(t *testing.T) {
+    from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    to := from.AddDate(0, 0, 10)
+    issue := func(day int, quantite float64) models.StockMovement {
+        return models.StockMovement{Quantite: quantite, CreatedAt: from.AddDate(0, 0, day).Add(time.Hour)}
+    }
+
+    tests := []struct {
+        name    string
+        created time.Time
+        period  int
+        issues  []models.StockMovement
+        want    []float64
+    }{
+        {"périodes sans sortie à zéro", time.Time{}, 5, []models.StockMovement{issue(1, 2), issue(3, 1)}, []float64{3, 0}},
+        {"par jour", time.Time{}, 1, []models.StockMovement{issue(0, 1), issue(9, 4)}, []float64{1, 0, 0, 0, 0, 0, 0, 0, 0, 4}},
+        {"pièce récente", from.AddDate(0, 0, 6), 2, []models.StockMovement{issue(7, 2)}, []float64{2, 0}},
+        {"période plus longue que la fenêtre", time.Time{}, 30, []models.StockMovement{issue(2, 5)}, []float64{5}},
+    }
+
+    for _, tt := range tests {
+        piece := &models.Piece{CreatedAt: tt.created}
+        got := consumptionSeries(piece, tt.issues, from, to, tt.period)
+        if len(got) != len(tt.want) {
+            t.Errorf("%s: %d période(s), attendu %d", tt.name, len(got), len(tt.want))
+            continue
+        }
+        for i := range got {
+            if got[i] != tt.want[i] {
+                t.Errorf("%s: période %d = %g, attendu %g", tt.name, i, got[i], tt.want[i])
+            }
+        }
+    }
+}
+
+func TestReorderPoint(t *testing.T) {
+    tests := []struct {
+        name       string
+        unit       string
+        stats      models.ConsumptionStats
+        leadTime   int
+        seuil      float64
+        wantSafety float64
+        wantPoint  float64
+        wantFiable bool
+    }{
+        {"consommation régulière", "pièce", models.ConsumptionStats{Moyenne: 2, NombreSorties: 10}, 7, 10, 0, 14, true},
+        // 1.6449 × 1 × √4 = 3.29 arrondi par excès
+        {"stock de sécurité", "pièce", models.ConsumptionStats{Moyenne: 2, EcartType: 1, NombreSorties: 10}, 4, 10, 4, 12, true},
+        {"unité décimale", "litre", models.ConsumptionStats{Moyenne: 0.33, EcartType: 1, NombreSorties: 10}, 4, 1, 3.3, 4.7, true},
+        {"délai nul", "pièce", models.ConsumptionStats{Moyenne: 2, EcartType: 1, NombreSorties: 10}, 0, 5, 0, 0, true},
+        {"historique insuffisant", "pièce", models.ConsumptionStats{Moyenne: 1, NombreSorties: models.MIN_REORDER_ISSUES - 1}, 5, 5, 0, 5, false},
+    }
+
+    for _, tt := range tests {
+        piece := &models.Piece{ID: "P1", UniteStock: tt.unit, SeuilMin: tt.seuil}
+        point := reorderPoint(piece, tt.stats, "", tt.leadTime, 0.95)
+        if point.StockSecurite != tt.wantSafety || point.PointCommande != tt.wantPoint {
+            t.Errorf("%s: stock de sécurité=%g point=%g, attendu %g et %g", tt.name, point.StockSecurite, point.PointCommande, tt.wantSafety, tt.wantPoint)
+        }
+        if point.Ecart != piece.RoundQuantity(tt.wantPoint-tt.seuil) {
+            t.Errorf("%s: écart = %g, attendu %g", tt.name, point.Ecart, tt.wantPoint-tt.seuil)
+        }
+        if point.Fiable != tt.wantFiable {
+            t.Errorf("%s: fiable = %v, attendu %v", tt.name, point.Fiable, tt.wantFiable)
+        }
+    }
+}
+
+func TestEconomicOrderQuantity(t *testing.T) {
+    s, _ := newTestService(t)
+    s.config.ConsumptionWindowDays = 30
+
+    policy := &models.ReplenishmentPolicy{Type: models.REPLENISHMENT_POLICY_EOQ, CoutPassation: models.DecimalFromFloat(50), TauxPossession: 0.2}
+    piece := &models.Piece{
+        Nom:              "Filtre à huile",
+        Quantite:         1000,
+        SeuilMin:         10,
+        PrixUnitaire:     models.DecimalFromFloat(5),
+        UniteStock:       "pièce",
+        PolitiqueReappro: policy,
+    }
+    if err := s.CreatePiece(piece, "test"); err != nil {
+        t.Fatalf("création de la pièce: %v", err)
+    }
+
+    eoq, err := s.economicOrderQuantity(piece)
+    if err != nil || eoq != 0 {
+        t.Fatalf("QEC sans consommation = %g (%v), attendu 0", eoq, err)
+    }
+
+    // 100 unités sorties le jour de la création: D = 100 × 365 par an,
+    // H = 5 × 0.2 = 1, QEC = √(2 × 36500 × 50 / 1)
+    if _, err := s.DecrementStock(piece.ID, &models.StockMovementRequest{Quantite: 100, Motif: "test"}, "test"); err != nil {
+        t.Fatalf("sortie: %v", err)
+    }
+    piece, err = s.GetPiece(piece.ID)
+    if err != nil {
+        t.Fatalf("lecture de la pièce: %v", err)
+    }
+    eoq, err = s.economicOrderQuantity(piece)
+    if err != nil {
+        t.Fatalf("QEC: %v", err)
+    }
+    if want := math.Sqrt(2 * 36500 * 50); math.Abs(eoq-want) > 1e-6 {
+        t.Errorf("QEC = %g, attendu %g", eoq, want)
+    }
+}
+
+func TestSuggestedQuantity(t *testing.T) {
+    minMax := &models.ReplenishmentPolicy{Type: models.REPLENISHMENT_POLICY_MIN_MAX, StockMax: 50}
+    packed := &models.ReplenishmentPolicy{Type: models.REPLENISHMENT_POLICY_MIN_MAX, StockMax: 50, Conditionnement: 12}
+
+    tests := []struct {
+        name       string
+        disponible float64
+        pending    float64
+        policy     *models.ReplenishmentPolicy
+        minimum    float64
+        want       float64
+    }{
+        {"sans politique: double du seuil", 4, 0, nil, 0, 12},
+        {"sans politique, commandes en cours", 4, 10, nil, 0, 2},
+        {"sans politique, au-dessus du double", 25, 0, nil, 0, 0},
+        {"min/max jusqu'au maximum", 4, 0, minMax, 0, 46},
+        {"min/max au-dessus du seuil", 12, 0, minMax, 0, 0},
+        {"min/max couvert par les commandes", 4, 10, minMax, 0, 0},
+        {"minimum de commande fournisseur", 4, 0, minMax, 60, 60},
+        {"conditionnement supérieur", 4, 0, packed, 0, 48},
+    }
+
+    s := &StockService{}
+    for _, tt := range tests {
+        piece := &models.Piece{UniteStock: "pièce", SeuilMin: 8, QuantiteDisponible: tt.disponible, PolitiqueReappro: tt.policy}
+        if tt.minimum > 0 {
+            piece.Fournisseurs = []models.SupplierLink{{FournisseurID: "F1", Prefere: true, QuantiteMinimum: tt.minimum}}
+        }
+        got, err := s.suggestedQuantity(piece, tt.pending)
+        if err != nil {
+            t.Errorf("%s: erreur inattendue: %v", tt.name, err)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("%s: quantité suggérée = %g, attendu %g", tt.name, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "stock-service/models"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+    "github.com/google/uuid"
+    "go.uber.org/zap"
+)
+
+const (
+    RESERVATION_KEY_PREFIX           = "stock:reservation:"
+    PIECE_RESERVATIONS_PREFIX        = "stock:reservations:piece:"
+    INTERVENTION_RESERVATIONS_PREFIX = "stock:reservations:intervention:"
+)
+
+// ReserveStock réserve des unités disponibles d'une pièce pour une intervention
+func (s *StockService) ReserveStock(pieceID string, req *models.ReservationRequest, userID string) (*models.Reservation, *models.Piece, error) {
+    var piece *models.Piece
+    now := time.Now()
+    reservation := &models.Reservation{
+        ID:             uuid.New().String(),
+        PieceID:        pieceID,
+        InterventionID: req.InterventionID,
+        Quantite:       req.Quantite,
+        Statut:         models.RESERVATION_STATUS_ACTIVE,
+        Motif:          req.Motif,
+        UserID:         userID,
+        CreatedAt:      now,
+        UpdatedAt:      now,
+    }
+
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        piece, err = t.getPiece(pieceID)
+        if err != nil {
+            return err
+        }
+
+        if err := piece.ValidateQuantity(req.Quantite); err != nil {
+            return err
+        }
+        if piece.QuantiteDisponible < req.Quantite {
+            return fmt.Errorf("stock insuffisant: disponible=%s, demandé=%s",
+                models.FormatQuantity(piece.QuantiteDisponible), models.FormatQuantity(req.Quantite))
+        }
+
+        piece.QuantiteReservee = piece.RoundQuantity(piece.QuantiteReservee + req.Quantite)
+        piece.RefreshDisponible()
+        piece.UpdatedAt = now
+        t.savePiece(piece)
+        queueReservation(t, reservation, true)
+        return nil
+    })
+    if err != nil {
+        return nil, nil, err
+    }
+
+    s.logger.Info("Stock réservé",
+        zap.String("reservation_id", reservation.ID),
+        zap.String("piece_id", pieceID),
+        zap.String("intervention_id", req.InterventionID),
+        zap.Float64("quantite", req.Quantite))
+
+    return reservation, piece, nil
+}
+
+// ReleaseReservation libère une réservation active sans consommer le stock
+func (s *StockService) ReleaseReservation(id string) (*models.Reservation, *models.Piece, error) {
+    var reservation *models.Reservation
+    var piece *models.Piece
+
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        reservation, err = getActiveReservationTx(t, id)
+        if err != nil {
+            return err
+        }
+
+        piece, err = t.getPiece(reservation.PieceID)
+        if err != nil {
+            return err
+        }
+
+        now := time.Now()
+        piece.QuantiteReservee = piece.RoundQuantity(piece.QuantiteReservee - reservation.Quantite)
+        piece.RefreshDisponible()
+        piece.UpdatedAt = now
+        reservation.Statut = models.RESERVATION_STATUS_RELEASED
+        reservation.UpdatedAt = now
+
+        t.savePiece(piece)
+        queueReservation(t, reservation, false)
+        return nil
+    })
+    if err != nil {
+        return nil, nil, err
+    }
+
+    s.logger.Info("Réservation libérée",
+        zap.String("reservation_id", id),
+        zap.String("piece_id", reservation.PieceID),
+        zap.Float64("quantite", reservation.Quantite))
+
+    return reservation, piece, nil
+}
+
+// ConsumeReservation transforme une réservation en sortie de stock. Si la
+// quantité consommée est inférieure à la quantité réservée, le reliquat est libéré.
+func (s *StockService) ConsumeReservation(id string, req *models.ConsumeReservationRequest, userID string) (*models.Reservation, *models.Piece, error) {
+    var reservation *models.Reservation
+    var piece *models.Piece
+
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        reservation, err = getActiveReservationTx(t, id)
+        if err != nil {
+            return err
+        }
+
+        quantite := reservation.Quantite
+        if req.Quantite != nil {
+            if *req.Quantite > reservation.Quantite {
+                return fmt.Errorf("quantité supérieure à la réservation: réservé=%s, demandé=%s",
+                    models.FormatQuantity(reservation.Quantite), models.FormatQuantity(*req.Quantite))
+            }
+            quantite = *req.Quantite
+        }
+
+        piece, err = t.getPiece(reservation.PieceID)
+        if err != nil {
+            return err
+        }
+        if err := piece.ValidateQuantity(quantite); err != nil {
+            return err
+        }
+
+        motif := req.Motif
+        if motif == "" {
+            motif = fmt.Sprintf("Consommation réservation intervention %s", reservation.InterventionID)
+        }
+
+        allocation, err := allocateLocations(piece, "", quantite)
+        if err != nil {
+            return err
+        }
+
+        lots, serials, err := s.issueTracking(piece, req.Lot, req.NumerosSerie, quantite)
+        if err != nil {
+            return err
+        }
+
+        now := time.Now()
+        oldQuantite := piece.Quantite
+        for location, taken := range allocation {
+            piece.AdjustLocation(location, -taken)
+        }
+        piece.QuantiteReservee = piece.RoundQuantity(piece.QuantiteReservee - reservation.Quantite)
+        piece.RefreshDisponible()
+        piece.UpdatedAt = now
+
+        movement := newMovement(piece.ID, models.MOVEMENT_TYPE_DECREMENT, quantite, oldQuantite, piece.Quantite, motif, userID)
+        movement.ReservationID = reservation.ID
+        movement.Emplacements = allocation
+        movement.Lots = lots
+        movement.NumerosSerie = serials
+        s.valueIssue(movement, piece, quantite)
+
+        reservation.QuantiteConsommee = quantite
+        reservation.Statut = models.RESERVATION_STATUS_CONSUMED
+        reservation.UpdatedAt = now
+
+        t.savePiece(piece)
+        t.addMovement(movement)
+        queueReservation(t, reservation, false)
+        return nil
+    })
+    if err != nil {
+        return nil, nil, err
+    }
+
+    s.logger.Info("Réservation consommée",
+        zap.String("reservation_id", id),
+        zap.String("piece_id", reservation.PieceID),
+        zap.Float64("quantite_reservee", reservation.Quantite),
+        zap.Float64("quantite_consommee", reservation.QuantiteConsommee))
+
+    return reservation, piece, nil
+}
+
+// GetReservation récupère une réservation par ID
+func (s *StockService) GetReservation(id string) (*models.Reservation, error) {
+    ctx := context.Background()
+
+    reservationJSON, err := s.redis.Get(ctx, RESERVATION_KEY_PREFIX+id).Result()
+    if err == redis.Nil {
+        return nil, fmt.Errorf("réservation non trouvée: %s", id)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération de la réservation: %w", err)
+    }
+
+    var reservation models.Reservation
+    if err := reservation.FromJSON([]byte(reservationJSON)); err != nil {
+        return nil, fmt.Errorf("erreur de désérialisation: %w", err)
+    }
+
+    return &reservation, nil
+}
+
+// GetPieceReservations récupère les réservations d'une pièce, filtrées par statut si fourni
+func (s *StockService) GetPieceReservations(pieceID string, statut string) ([]models.Reservation, error) {
+    if _, err := s.GetPiece(pieceID); err != nil {
+        return nil, err
+    }
+
+    return s.listReservations(PIECE_RESERVATIONS_PREFIX+pieceID, statut)
+}
+
+// GetInterventionReservations récupère les réservations d'une intervention
+func (s *StockService) GetInterventionReservations(interventionID string, statut string) ([]models.Reservation, error) {
+    return s.listReservations(INTERVENTION_RESERVATIONS_PREFIX+interventionID, statut)
+}
+
+// listReservations charge les réservations référencées par un ensemble d'index
+func (s *StockService) listReservations(indexKey string, statut string) ([]models.Reservation, error) {
+    ctx := context.Background()
+
+    ids, err := s.redis.SMembers(ctx, indexKey).Result()
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération des réservations: %w", err)
+    }
+
+    reservations := make([]models.Reservation, 0, len(ids))
+    for _, id := range ids {
+        reservation, err := s.GetReservation(id)
+        if err != nil {
+            s.logger.Warn("Impossible de récupérer la réservation", zap.String("id", id), zap.Error(err))
+            continue
+        }
+        if statut != "" && reservation.Statut != statut {
+            continue
+        }
+        reservations = append(reservations, *reservation)
+    }
+
+    sort.Slice(reservations, func(i, j int) bool {
+        return reservations[i].CreatedAt.Before(reservations[j].CreatedAt)
+    })
+
+    return reservations, nil
+}
+
+// getActiveReservationTx lit une réservation sous surveillance et vérifie qu'elle est active
+func getActiveReservationTx(t *stockTx, id string) (*models.Reservation, error) {
+    reservationJSON, err := t.watchGet(RESERVATION_KEY_PREFIX + id)
+    if err == redis.Nil {
+        return nil, fmt.Errorf("réservation non trouvée: %s", id)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération de la réservation: %w", err)
+    }
+
+    var reservation models.Reservation
+    if err := reservation.FromJSON([]byte(reservationJSON)); err != nil {
+        return nil, fmt.Errorf("erreur de désérialisation: %w", err)
+    }
+
+    if !reservation.IsActive() {
+        return nil, fmt.Errorf("réservation non active: %s (statut=%s)", id, reservation.Statut)
+    }
+
+    return &reservation, nil
+}
+
+// queueReservation met en file la sauvegarde d'une réservation et, à la
+// création, son indexation par pièce et par intervention
+func queueReservation(t *stockTx, reservation *models.Reservation, index bool) {
+    t.queue(func(pipe redis.Pipeliner) error {
+        reservationJSON, err := reservation.ToJSON()
+        if err != nil {
+            return fmt.Errorf("erreur de sérialisation de la réservation: %w", err)
+        }
+
+        pipe.Set(t.ctx, RESERVATION_KEY_PREFIX+reservation.ID, reservationJSON, 0)
+        if index {
+            pipe.SAdd(t.ctx, PIECE_RESERVATIONS_PREFIX+reservation.PieceID, reservation.ID)
+            pipe.SAdd(t.ctx, INTERVENTION_RESERVATIONS_PREFIX+reservation.InterventionID, reservation.ID)
+        }
+        return nil
+    })
+}
@@ -3,6 +3,9 @@ package services
 import (
     "context"
     "fmt"
+    "net/http"
+    "sort"
+    "stock-service/config"
     "stock-service/models"
     "strings"
     "time"
@@ -19,19 +22,27 @@ const (
 )
 
 type StockService struct {
-    redis  *redis.Client
-    logger *zap.Logger
+    redis    *redis.Client
+    config   *config.Config
+    logger   *zap.Logger
+    events   *eventHub    // abonnés locaux au flux des pièces
+    webhooks *http.Client // client des envois de webhooks
 }
 
-func NewStockService(redisClient *redis.Client, logger *zap.Logger) *StockService {
-    return &StockService{
+func NewStockService(redisClient *redis.Client, cfg *config.Config, logger *zap.Logger) *StockService {
+    s := &StockService{
         redis:  redisClient,
+        config: cfg,
         logger: logger,
+        events: newEventHub(),
     }
+    s.webhooks = s.newWebhookClient()
+    return s
 }
 
-// CreatePiece crée une nouvelle pièce en stock
-func (s *StockService) CreatePiece(piece *models.Piece) error {
+// CreatePiece crée une nouvelle pièce en stock; une quantité initiale non
+// nulle est historisée comme une entrée
+func (s *StockService) CreatePiece(piece *models.Piece, userID string) error {
     ctx := context.Background()
 
     // Génération d'un ID si non fourni
@@ -53,6 +64,64 @@ func (s *StockService) CreatePiece(piece *models.Piece) error {
     piece.CreatedAt = now
     piece.UpdatedAt = now
 
+    // Unités de référence du catalogue
+    piece.NormalizeUnits()
+
+    // Les quantités saisies respectent la précision de l'unité de stock
+    if err := piece.ValidateQuantities(); err != nil {
+        return err
+    }
+
+    // Répartition initiale du stock par emplacement
+    piece.InitLocations(piece.Emplacements)
+
+    // Lots ou numéros de série du stock initial
+    if err := validateInitialTracking(piece); err != nil {
+        return err
+    }
+
+    // Le prix est exprimé dans la devise de la pièce, par défaut celle du service
+    if piece.Devise == "" {
+        piece.Devise = s.config.Currency
+    }
+    piece.Devise = models.CanonicalCurrency(piece.Devise)
+    if _, err := s.convertMoney(piece.PrixUnitaire, piece.Devise, s.config.Currency); err != nil {
+        return err
+    }
+
+    // Politique de réapprovisionnement, cohérente avec le seuil minimum
+    if err := setReplenishmentPolicy(piece, piece.PolitiqueReappro); err != nil {
+        return err
+    }
+
+    // Fournisseurs: liens explicites, à défaut le nom libre, résolu vers un
+    // fournisseur existant ou créé. La résolution vient en dernier: un
+    // fournisseur créé ne doit pas survivre à une pièce refusée.
+    if len(piece.Fournisseurs) > 0 {
+        if err := s.setSupplierLinks(piece, piece.Fournisseurs, s.GetSupplier); err != nil {
+            return err
+        }
+    } else if strings.TrimSpace(piece.Fournisseur) != "" {
+        supplier, err := s.ResolveSupplier(piece.Fournisseur, piece.Devise)
+        if err != nil {
+            return err
+        }
+        if err := s.preferSupplier(piece, supplier, s.GetSupplier); err != nil {
+            return err
+        }
+    }
+
+    // Le stock initial est valorisé au prix unitaire saisi et forme la
+    // première couche de coût
+    piece.PrixMoyenPondere = piece.PrixUnitaire
+    piece.CouchesCout = nil
+    var movement *models.StockMovement
+    if piece.Quantite > 0 {
+        movement = initialMovement(piece, userID)
+        piece.AddCostLayer(piece.Quantite, piece.PrixUnitaire, now, movement.ID)
+        piece.RecordMovement(movement)
+    }
+
     // Sérialisation
     pieceJSON, err := piece.ToJSON()
     if err != nil {
@@ -73,6 +142,24 @@ func (s *StockService) CreatePiece(piece *models.Piece) error {
         pipe.SAdd(ctx, CATEGORY_SET_PREFIX+strings.ToLower(piece.Categorie), piece.ID)
     }
 
+    // Historisation du stock initial et de son coût
+    if movement != nil {
+        if err := queueMovement(ctx, pipe, movement); err != nil {
+            return err
+        }
+    }
+    if err := queueCostChange(ctx, pipe, initialCostChange(piece, userID)); err != nil {
+        return err
+    }
+
+    // Diffusion aux clients du flux temps réel et aux autres services
+    if err := queueStockEvent(ctx, pipe, newStockEvent(models.STOCK_EVENT_CREATED, piece, nil)); err != nil {
+        return err
+    }
+    if err := s.queueDomainEvent(ctx, pipe, newDomainEvent(models.DOMAIN_EVENT_PIECE_CREATED, piece, nil)); err != nil {
+        return err
+    }
+
     // Exécution de la transaction
     _, err = pipe.Exec(ctx)
     if err != nil {
@@ -82,7 +169,7 @@ func (s *StockService) CreatePiece(piece *models.Piece) error {
     s.logger.Info("Pièce créée avec succès",
         zap.String("id", piece.ID),
         zap.String("nom", piece.Nom),
-        zap.Int("quantite", piece.Quantite))
+        zap.Float64("quantite", piece.Quantite))
 
     return nil
 }
@@ -132,55 +219,133 @@ func (s *StockService) GetAllPieces() ([]models.Piece, error) {
     return pieces, nil
 }
 
-// UpdatePiece met à jour une pièce existante
-func (s *StockService) UpdatePiece(id string, updates *models.UpdatePieceRequest) (*models.Piece, error) {
-    // Récupération de la pièce existante
-    piece, err := s.GetPiece(id)
+// ListPieces récupère les pièces correspondant aux critères de filtrage
+func (s *StockService) ListPieces(filter models.PieceFilter) ([]models.Piece, error) {
+    pieces, err := s.GetAllPieces()
     if err != nil {
         return nil, err
     }
 
-    // Application des mises à jour
-    if updates.Nom != nil {
-        piece.Nom = *updates.Nom
-    }
-    if updates.Description != nil {
-        piece.Description = *updates.Description
-    }
-    if updates.SeuilMin != nil {
-        piece.SeuilMin = *updates.SeuilMin
-    }
-    if updates.PrixUnitaire != nil {
-        piece.PrixUnitaire = *updates.PrixUnitaire
-    }
-    if updates.Fournisseur != nil {
-        piece.Fournisseur = *updates.Fournisseur
-    }
-    if updates.Emplacement != nil {
-        piece.Emplacement = *updates.Emplacement
-    }
-    if updates.CodeEAN != nil {
-        piece.CodeEAN = *updates.CodeEAN
-    }
-    if updates.Categorie != nil {
-        piece.Categorie = *updates.Categorie
-    }
-    if updates.UniteStock != nil {
-        piece.UniteStock = *updates.UniteStock
+    results := make([]models.Piece, 0, len(pieces))
+    for i := range pieces {
+        if matchesPieceFilter(&pieces[i], filter) {
+            results = append(results, pieces[i])
+        }
     }
 
-    // Mise à jour du timestamp
-    piece.UpdatedAt = time.Now()
+    return results, nil
+}
 
-    // Sauvegarde
-    ctx := context.Background()
-    pieceJSON, err := piece.ToJSON()
+// UpdatePiece met à jour une pièce existante
+func (s *StockService) UpdatePiece(id string, updates *models.UpdatePieceRequest) (*models.Piece, error) {
+    var piece *models.Piece
+    var created *models.Supplier
+
+    // La mise à jour passe par une transaction optimiste pour ne pas écraser
+    // un mouvement de stock concurrent
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        piece, err = t.getPiece(id)
+        if err != nil {
+            return err
+        }
+
+        // Application des mises à jour
+        if updates.Nom != nil {
+            piece.Nom = *updates.Nom
+        }
+        if updates.Description != nil {
+            piece.Description = *updates.Description
+        }
+        if updates.SeuilMin != nil {
+            piece.SeuilMin = *updates.SeuilMin
+        }
+        if updates.PrixUnitaire != nil {
+            piece.PrixUnitaire = *updates.PrixUnitaire
+        }
+        if updates.Emplacement != nil {
+            piece.Emplacement = *updates.Emplacement
+        }
+        if updates.CodeEAN != nil {
+            piece.CodeEAN = *updates.CodeEAN
+        }
+        if updates.Categorie != nil {
+            piece.Categorie = *updates.Categorie
+        }
+        unite := piece.UniteStock
+        if updates.UniteStock != nil {
+            piece.UniteStock = *updates.UniteStock
+        }
+        if updates.Conversions != nil {
+            piece.Conversions = updates.Conversions
+        }
+        piece.NormalizeUnits()
+        // Les quantités, seuils, réservations et coûts unitaires sont
+        // exprimés dans l'unité de stock et ne sont pas convertis
+        if piece.UniteStock != models.CanonicalUnit(unite) && (piece.Quantite > 0 || piece.QuantiteReservee > 0) {
+            return fmt.Errorf("unité invalide: changement d'unité de stock impossible avec un stock (%s) ou des réservations (%s) non nuls",
+                models.FormatQuantity(piece.Quantite), models.FormatQuantity(piece.QuantiteReservee))
+        }
+        // Une unité moins précise ne doit pas tronquer le stock existant
+        if err := piece.ValidateQuantities(); err != nil {
+            return err
+        }
+        // Un nom de fournisseur libre inconnu crée le fournisseur avec la
+        // pièce: il n'est pas enregistré si la mise à jour est refusée
+        created = nil
+        if updates.Fournisseurs != nil {
+            if err := s.setSupplierLinks(piece, updates.Fournisseurs, txSuppliers(t, nil)); err != nil {
+                return err
+            }
+        } else if updates.Fournisseur != nil {
+            var supplier *models.Supplier
+            if strings.TrimSpace(*updates.Fournisseur) != "" {
+                var isNew bool
+                supplier, isNew, err = s.resolveSupplierTx(t, *updates.Fournisseur, piece.Devise)
+                if err != nil {
+                    return err
+                }
+                if isNew {
+                    created = supplier
+                }
+            }
+            if err := s.preferSupplier(piece, supplier, txSuppliers(t, created)); err != nil {
+                return err
+            }
+        }
+        if updates.PolitiqueReappro != nil {
+            if err := setReplenishmentPolicy(piece, updates.PolitiqueReappro); err != nil {
+                return err
+            }
+        } else if err := piece.ValidatePolicy(); err != nil {
+            return err
+        }
+        if updates.Suivi != nil {
+            suivi := *updates.Suivi
+            if suivi == "aucun" {
+                suivi = models.TRACKING_NONE
+            }
+            // Les unités déjà en stock n'ont pas de numéro de lot ou de série
+            if suivi != piece.Suivi && piece.Quantite > 0 {
+                return fmt.Errorf("suivi invalide: changement de suivi impossible avec un stock non nul (%s)", models.FormatQuantity(piece.Quantite))
+            }
+            piece.Suivi = suivi
+        }
+
+        // Mise à jour du timestamp
+        piece.UpdatedAt = time.Now()
+
+        t.savePiece(piece)
+        return nil
+    })
     if err != nil {
-        return nil, fmt.Errorf("erreur de sérialisation: %w", err)
+        return nil, err
     }
 
-    if err := s.redis.Set(ctx, PIECE_KEY_PREFIX+id, pieceJSON, 0).Err(); err != nil {
-        return nil, fmt.Errorf("erreur lors de la mise à jour: %w", err)
+    if created != nil {
+        s.logger.Info("Fournisseur créé depuis un nom libre",
+            zap.String("fournisseur_id", created.ID),
+            zap.String("nom", created.Nom))
     }
 
     s.logger.Info("Pièce mise à jour avec succès",
@@ -190,34 +355,43 @@ func (s *StockService) UpdatePiece(id string, updates *models.UpdatePieceRequest
     return piece, nil
 }
 
-// DeletePiece supprime une pièce
+// DeletePiece supprime une pièce. Une pièce dont une partie du stock est
+// réservée n'est pas supprimée: ses réservations doivent d'abord être
+// libérées ou consommées.
 func (s *StockService) DeletePiece(id string) error {
-    ctx := context.Background()
+    var piece *models.Piece
 
-    // Récupération de la pièce pour obtenir la catégorie
-    piece, err := s.GetPiece(id)
-    if err != nil {
-        return err
-    }
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        piece, err = t.getPiece(id)
+        if err != nil {
+            return err
+        }
 
-    // Transaction Redis
-    pipe := s.redis.TxPipeline()
-    
-    // Suppression de la pièce
-    pipe.Del(ctx, PIECE_KEY_PREFIX+id)
-    
-    // Suppression de l'ensemble des pièces
-    pipe.SRem(ctx, PIECES_SET_KEY, id)
-    
-    // Suppression de l'ensemble de la catégorie
-    if piece.Categorie != "" {
-        pipe.SRem(ctx, CATEGORY_SET_PREFIX+strings.ToLower(piece.Categorie), id)
-    }
+        if piece.QuantiteReservee > 0 {
+            return fmt.Errorf("suppression impossible: %s unité(s) réservée(s) sur la pièce %s",
+                models.FormatQuantity(piece.QuantiteReservee), id)
+        }
 
-    // Exécution
-    _, err = pipe.Exec(ctx)
+        // La pièce n'est pas sauvegardée: sa suppression, et sa diffusion
+        // aux clients du flux temps réel et aux autres services, sont
+        // appliquées dans le MULTI
+        t.queue(func(pipe redis.Pipeliner) error {
+            pipe.Del(t.ctx, PIECE_KEY_PREFIX+id)
+            pipe.SRem(t.ctx, PIECES_SET_KEY, id)
+            if piece.Categorie != "" {
+                pipe.SRem(t.ctx, CATEGORY_SET_PREFIX+strings.ToLower(piece.Categorie), id)
+            }
+
+            if err := queueStockEvent(t.ctx, pipe, newStockEvent(models.STOCK_EVENT_DELETED, piece, nil)); err != nil {
+                return err
+            }
+            return s.queueDomainEvent(t.ctx, pipe, newDomainEvent(models.DOMAIN_EVENT_PIECE_DELETED, piece, nil))
+        })
+        return nil
+    })
     if err != nil {
-        return fmt.Errorf("erreur lors de la suppression: %w", err)
+        return err
     }
 
     s.logger.Info("Pièce supprimée avec succès",
@@ -227,86 +401,190 @@ func (s *StockService) DeletePiece(id string) error {
     return nil
 }
 
-// IncrementStock augmente la quantité en stock
-func (s *StockService) IncrementStock(id string, quantite int, motif string) (*models.Piece, error) {
-    piece, err := s.GetPiece(id)
+// IncrementStock augmente la quantité en stock et historise le mouvement
+func (s *StockService) IncrementStock(id string, req *models.StockMovementRequest, userID string) (*models.Piece, error) {
+    var piece *models.Piece
+    var movement *models.StockMovement
+
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        piece, movement, err = s.applyIncrement(t, id, req, userID)
+        return err
+    })
     if err != nil {
         return nil, err
     }
 
-    oldQuantite := piece.Quantite
-    piece.Quantite += quantite
-    piece.UpdatedAt = time.Now()
+    s.logger.Info("Stock incrémenté",
+        zap.String("piece_id", id),
+        zap.String("nom", piece.Nom),
+        zap.Float64("ancien_stock", movement.QuantiteAvant),
+        zap.Float64("nouveau_stock", movement.QuantiteApres),
+        zap.Float64("increment", movement.Quantite),
+        zap.Any("emplacements", movement.Emplacements),
+        zap.String("motif", req.Motif))
 
-    // Sauvegarde
-    ctx := context.Background()
-    pieceJSON, err := piece.ToJSON()
-    if err != nil {
-        return nil, fmt.Errorf("erreur de sérialisation: %w", err)
-    }
+    return piece, nil
+}
+
+// DecrementStock diminue la quantité en stock et historise le mouvement.
+// La vérification du stock et la mise à jour sont atomiques.
+func (s *StockService) DecrementStock(id string, req *models.StockMovementRequest, userID string) (*models.Piece, error) {
+    var piece *models.Piece
+    var movement *models.StockMovement
 
-    if err := s.redis.Set(ctx, PIECE_KEY_PREFIX+id, pieceJSON, 0).Err(); err != nil {
-        return nil, fmt.Errorf("erreur lors de la mise à jour: %w", err)
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        piece, movement, err = s.applyDecrement(t, id, req, userID)
+        return err
+    })
+    if err != nil {
+        if isStockDenial(err) {
+            s.recordDenial(id, req, err.Error(), userID)
+        }
+        return nil, err
     }
 
-    s.logger.Info("Stock incrémenté",
+    s.logger.Info("Stock décrémenté",
         zap.String("piece_id", id),
         zap.String("nom", piece.Nom),
-        zap.Int("ancien_stock", oldQuantite),
-        zap.Int("nouveau_stock", piece.Quantite),
-        zap.Int("increment", quantite),
-        zap.String("motif", motif))
+        zap.Float64("ancien_stock", movement.QuantiteAvant),
+        zap.Float64("nouveau_stock", movement.QuantiteApres),
+        zap.Float64("decrement", movement.Quantite),
+        zap.Any("emplacements", movement.Emplacements),
+        zap.String("motif", req.Motif))
 
     return piece, nil
 }
 
-// DecrementStock diminue la quantité en stock
-func (s *StockService) DecrementStock(id string, quantite int, motif string) (*models.Piece, error) {
-    piece, err := s.GetPiece(id)
+// applyIncrement applique une entrée de stock dans une transaction
+func (s *StockService) applyIncrement(t *stockTx, id string, req *models.StockMovementRequest, userID string) (*models.Piece, *models.StockMovement, error) {
+    piece, err := t.getPiece(id)
     if err != nil {
-        return nil, err
+        return nil, nil, err
+    }
+
+    // Quantité saisie dans une autre unité que l'unité de stock
+    quantite, factor, err := piece.ConvertQuantity(req.Quantite, req.Unite)
+    if err != nil {
+        return nil, nil, err
+    }
+    // Coût de réception ramené à la devise et à l'unité de stock de la pièce
+    cout := req.CoutUnitaire
+    if cout != nil {
+        converted, err := s.convertMoney(*cout, req.Devise, piece.Devise)
+        if err != nil {
+            return nil, nil, err
+        }
+        perStockUnit := converted.DivQuantity(factor)
+        cout = &perStockUnit
     }
 
-    if piece.Quantite < quantite {
-        return nil, fmt.Errorf("stock insuffisant: disponible=%d, demandé=%d", piece.Quantite, quantite)
+    location := req.Emplacement
+    if location == "" {
+        location = piece.DefaultLocation()
     }
+    location = piece.ResolveLocation(location)
+
+    now := time.Now()
+    lots, serials, err := receiveTracking(piece, req.Lot, req.NumerosSerie, req.DatePeremption, quantite, now)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    cost, costChange := s.receiveCost(piece, quantite, cout, userID, now)
 
     oldQuantite := piece.Quantite
-    piece.Quantite -= quantite
-    piece.UpdatedAt = time.Now()
+    piece.AdjustLocation(location, quantite)
+    piece.UpdatedAt = now
 
-    // Sauvegarde
-    ctx := context.Background()
-    pieceJSON, err := piece.ToJSON()
+    movement := newMovement(id, models.MOVEMENT_TYPE_INCREMENT, quantite, oldQuantite, piece.Quantite, req.Motif, userID)
+    movement.Emplacements = map[string]float64{location: quantite}
+    movement.Lots = lots
+    movement.NumerosSerie = serials
+    movement.CoutUnitaire = cost
+    movement.Devise = piece.Devise
+    setMovementUnit(movement, req)
+    piece.AddCostLayer(quantite, cost, now, movement.ID)
+    t.savePiece(piece)
+    t.addMovement(movement)
+
+    if costChange != nil {
+        costChange.MouvementID = movement.ID
+        t.queue(func(pipe redis.Pipeliner) error {
+            return queueCostChange(t.ctx, pipe, costChange)
+        })
+    }
+
+    return piece, movement, nil
+}
+
+// applyDecrement applique une sortie de stock dans une transaction
+func (s *StockService) applyDecrement(t *stockTx, id string, req *models.StockMovementRequest, userID string) (*models.Piece, *models.StockMovement, error) {
+    piece, err := t.getPiece(id)
     if err != nil {
-        return nil, fmt.Errorf("erreur de sérialisation: %w", err)
+        return nil, nil, err
     }
 
-    if err := s.redis.Set(ctx, PIECE_KEY_PREFIX+id, pieceJSON, 0).Err(); err != nil {
-        return nil, fmt.Errorf("erreur lors de la mise à jour: %w", err)
+    quantite, _, err := piece.ConvertQuantity(req.Quantite, req.Unite)
+    if err != nil {
+        return nil, nil, err
     }
 
-    s.logger.Info("Stock décrémenté",
-        zap.String("piece_id", id),
-        zap.String("nom", piece.Nom),
-        zap.Int("ancien_stock", oldQuantite),
-        zap.Int("nouveau_stock", piece.Quantite),
-        zap.Int("decrement", quantite),
-        zap.String("motif", motif))
+    // Les unités réservées pour des interventions ne peuvent pas être consommées
+    if piece.QuantiteDisponible < quantite {
+        return nil, nil, fmt.Errorf("stock insuffisant: disponible=%s, demandé=%s",
+            models.FormatQuantity(piece.QuantiteDisponible), models.FormatQuantity(quantite))
+    }
 
-    return piece, nil
+    allocation, err := allocateLocations(piece, req.Emplacement, quantite)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    lots, serials, err := s.issueTracking(piece, req.Lot, req.NumerosSerie, quantite)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    oldQuantite := piece.Quantite
+    for location, quantite := range allocation {
+        piece.AdjustLocation(location, -quantite)
+    }
+    piece.UpdatedAt = time.Now()
+
+    movement := newMovement(id, models.MOVEMENT_TYPE_DECREMENT, quantite, oldQuantite, piece.Quantite, req.Motif, userID)
+    movement.Emplacements = allocation
+    movement.Lots = lots
+    movement.NumerosSerie = serials
+    setMovementUnit(movement, req)
+    s.valueIssue(movement, piece, quantite)
+    t.savePiece(piece)
+    t.addMovement(movement)
+
+    return piece, movement, nil
 }
 
-// GetLowStockAlerts récupère les alertes de stock faible
-func (s *StockService) GetLowStockAlerts() ([]models.AlerteStock, error) {
-    pieces, err := s.GetAllPieces()
+// GetLowStockAlerts récupère les alertes de stock faible, avec la quantité
+// déjà en commande, et les alertes de péremption des lots périmés ou
+// périmant avant l'horizon configuré
+func (s *StockService) GetLowStockAlerts(filter models.PieceFilter) ([]models.AlerteStock, error) {
+    pieces, err := s.ListPieces(filter)
+    if err != nil {
+        return nil, err
+    }
+
+    onOrder, err := s.onOrderQuantities()
     if err != nil {
         return nil, err
     }
 
     alerts := make([]models.AlerteStock, 0)
+    now := time.Now()
+    horizon := time.Duration(s.config.ExpiryAlertDays) * 24 * time.Hour
 
-    for _, piece := range pieces {
+    for i := range pieces {
+        piece := &pieces[i]
         if piece.IsLowStock() {
             severite := "attention"
             if piece.IsCriticalStock() {
@@ -314,20 +592,47 @@ func (s *StockService) GetLowStockAlerts() ([]models.AlerteStock, error) {
             }
 
             alert := models.AlerteStock{
-                PieceID:          piece.ID,
-                Nom:              piece.Nom,
-                Quantite:         piece.Quantite,
-                SeuilMin:         piece.SeuilMin,
-                Severite:         severite,
-                PourcentageStock: piece.GetStockPercentage(),
+                PieceID:            piece.ID,
+                Nom:                piece.Nom,
+                Type:               models.ALERT_TYPE_LOW_STOCK,
+                Quantite:           piece.Quantite,
+                QuantiteReservee:   piece.QuantiteReservee,
+                QuantiteDisponible: piece.QuantiteDisponible,
+                Emplacements:       piece.Emplacements,
+                SeuilMin:           piece.SeuilMin,
+                Severite:           severite,
+                PourcentageStock:   piece.GetStockPercentage(),
+                QuantiteEnCommande: piece.RoundQuantity(onOrder[piece.ID]),
+                Classe:             piece.Classification.Class(),
+            }
+            if alert.QuantiteSuggeree, err = s.suggestedQuantity(piece, onOrder[piece.ID]); err != nil {
+                return nil, err
             }
             alerts = append(alerts, alert)
         }
+
+        if alert := expiryAlert(piece, now, horizon); alert != nil {
+            alert.Classe = piece.Classification.Class()
+            alerts = append(alerts, *alert)
+        }
     }
 
+    // Les pièces de classe A sont traitées en premier
+    sort.SliceStable(alerts, func(i, j int) bool {
+        return models.ClassRank(classABC(alerts[i].Classe)) < models.ClassRank(classABC(alerts[j].Classe))
+    })
+
     return alerts, nil
 }
 
+// classABC extrait la classe ABC d'une classe combinée ("AX" donne "A")
+func classABC(class string) string {
+    if class == "" {
+        return ""
+    }
+    return class[:1]
+}
+
 // SearchPieces recherche des pièces par nom ou description
 func (s *StockService) SearchPieces(query string) ([]models.Piece, error) {
     allPieces, err := s.GetAllPieces()
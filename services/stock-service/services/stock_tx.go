@@ -0,0 +1,191 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "math/rand"
+    "stock-service/models"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+)
+
+const (
+    MAX_TX_RETRIES = 100
+)
+
+// stockTx représente une transaction optimiste (WATCH/MULTI/EXEC) sur le stock.
+// Les lectures passent par la connexion surveillée, les écritures sont mises en
+// file et appliquées ensemble dans le MULTI final.
+type stockTx struct {
+    ctx       context.Context
+    tx        *redis.Tx
+    pieces    map[string]*models.Piece
+    initial   map[string]thresholdState          // état des seuils à la lecture
+    movements map[string][]*models.StockMovement // par pièce, dans l'ordre
+    silent    bool                               // écritures techniques, sans notification ni événement
+    dirty     []string
+    writes    []func(pipe redis.Pipeliner) error
+}
+
+// watchGet lit une clé en la plaçant sous surveillance; retourne redis.Nil
+// si la clé n'existe pas
+func (t *stockTx) watchGet(key string) (string, error) {
+    if err := t.tx.Watch(t.ctx, key).Err(); err != nil {
+        return "", fmt.Errorf("erreur lors de la surveillance de %s: %w", key, err)
+    }
+    return t.tx.Get(t.ctx, key).Result()
+}
+
+// getPiece lit une pièce en plaçant sa clé sous surveillance
+func (t *stockTx) getPiece(id string) (*models.Piece, error) {
+    if piece, ok := t.pieces[id]; ok {
+        return piece, nil
+    }
+
+    pieceJSON, err := t.watchGet(PIECE_KEY_PREFIX + id)
+    if err == redis.Nil {
+        return nil, fmt.Errorf("pièce non trouvée: %s", id)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération: %w", err)
+    }
+
+    var piece models.Piece
+    if err := piece.FromJSON([]byte(pieceJSON)); err != nil {
+        return nil, fmt.Errorf("erreur de désérialisation: %w", err)
+    }
+
+    t.pieces[id] = &piece
+    t.initial[id] = pieceThresholds(&piece)
+    return &piece, nil
+}
+
+// savePiece marque une pièce lue dans la transaction comme à sauvegarder
+func (t *stockTx) savePiece(piece *models.Piece) {
+    for _, id := range t.dirty {
+        if id == piece.ID {
+            return
+        }
+    }
+    t.pieces[piece.ID] = piece
+    t.dirty = append(t.dirty, piece.ID)
+}
+
+// addMovement met en file l'historisation d'un mouvement et reporte sa date
+// sur la pièce concernée, lue dans la transaction
+func (t *stockTx) addMovement(movement *models.StockMovement) {
+    if piece, ok := t.pieces[movement.PieceID]; ok {
+        piece.RecordMovement(movement)
+    }
+    t.movements[movement.PieceID] = append(t.movements[movement.PieceID], movement)
+    t.queue(func(pipe redis.Pipeliner) error {
+        return queueMovement(t.ctx, pipe, movement)
+    })
+}
+
+// queue ajoute une écriture à exécuter dans le MULTI de la transaction
+func (t *stockTx) queue(write func(pipe redis.Pipeliner) error) {
+    t.writes = append(t.writes, write)
+}
+
+// queuePieceEvents met en file les événements des pièces modifiées par la
+// transaction, pour les clients du flux temps réel et pour les autres
+// services: un événement par mouvement, ou une modification sans mouvement.
+// Publiés dans le MULTI, ils ne sont émis que si les écritures qui les
+// provoquent sont appliquées.
+func (s *StockService) queuePieceEvents(t *stockTx) error {
+    for _, id := range t.dirty {
+        piece := t.pieces[id]
+        piece.RefreshDisponible()
+
+        streamEvents := make([]*models.StockEvent, 0, 1)
+        domainEvents := make([]*models.DomainEvent, 0, 1)
+        for _, movement := range t.movements[id] {
+            streamEvents = append(streamEvents, newStockEvent(models.STOCK_EVENT_MOVEMENT, piece, movement))
+            domainEvents = append(domainEvents, newDomainEvent(models.DomainEventType(movement), piece, movement))
+        }
+        if len(t.movements[id]) == 0 {
+            streamEvents = append(streamEvents, newStockEvent(models.STOCK_EVENT_UPDATED, piece, nil))
+            domainEvents = append(domainEvents, newDomainEvent(models.DOMAIN_EVENT_PIECE_UPDATED, piece, nil))
+        }
+
+        t.queue(func(pipe redis.Pipeliner) error {
+            for _, event := range streamEvents {
+                if err := queueStockEvent(t.ctx, pipe, event); err != nil {
+                    return err
+                }
+            }
+            for _, event := range domainEvents {
+                if err := s.queueDomainEvent(t.ctx, pipe, event); err != nil {
+                    return err
+                }
+            }
+            return nil
+        })
+    }
+    return nil
+}
+
+// runStockTx exécute fn dans une transaction optimiste. Si une clé surveillée
+// est modifiée par un autre client avant l'EXEC, fn est rejouée sur des
+// données fraîches, ce qui garantit que la vérification et la mise à jour
+// des quantités sont atomiques.
+func (s *StockService) runStockTx(fn func(t *stockTx) error) error {
+    ctx := context.Background()
+
+    for attempt := 1; attempt <= MAX_TX_RETRIES; attempt++ {
+        err := s.redis.Watch(ctx, func(tx *redis.Tx) error {
+            t := &stockTx{
+                ctx:       ctx,
+                tx:        tx,
+                pieces:    make(map[string]*models.Piece),
+                initial:   make(map[string]thresholdState),
+                movements: make(map[string][]*models.StockMovement),
+            }
+
+            if err := fn(t); err != nil {
+                return err
+            }
+
+            // Les notifications de franchissement de seuil et les événements
+            // des pièces sont enregistrés avec les écritures qui les
+            // provoquent
+            if !t.silent {
+                if err := s.queueThresholdEvents(t); err != nil {
+                    return err
+                }
+                if err := s.queuePieceEvents(t); err != nil {
+                    return err
+                }
+            }
+
+            _, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+                for _, id := range t.dirty {
+                    pieceJSON, err := t.pieces[id].ToJSON()
+                    if err != nil {
+                        return fmt.Errorf("erreur de sérialisation: %w", err)
+                    }
+                    pipe.Set(ctx, PIECE_KEY_PREFIX+id, pieceJSON, 0)
+                }
+
+                for _, write := range t.writes {
+                    if err := write(pipe); err != nil {
+                        return err
+                    }
+                }
+                return nil
+            })
+            return err
+        })
+
+        if err != redis.TxFailedErr {
+            return err
+        }
+
+        // Conflit: une autre écriture est passée entre WATCH et EXEC
+        time.Sleep(time.Duration(rand.Intn(attempt*500)+100) * time.Microsecond)
+    }
+
+    return fmt.Errorf("conflit de concurrence: transaction abandonnée après %d tentatives", MAX_TX_RETRIES)
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+    "context"
+    "strings"
+    "sync"
+    "testing"
+
+    "stock-service/config"
+    "stock-service/models"
+
+    "github.com/alicebob/miniredis/v2"
+    "github.com/go-redis/redis/v8"
+    "go.uber.org/zap"
+)
+
+// newTestService démarre un service sur un Redis en mémoire
+func newTestService(t *testing.T) (*StockService, *redis.Client) {
+    t.Helper()
+
+    server := miniredis.RunT(t)
+    client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+    t.Cleanup(func() { client.Close() })
+
+    cfg := &config.Config{
+        FEFOMode:        config.FEFO_MODE_SUGGESTION,
+        ValuationMethod: models.VALUATION_METHOD_AVERAGE,
+        Currency:        models.DEFAULT_CURRENCY,
+        ExchangeRates:   map[string]models.Decimal{models.DEFAULT_CURRENCY: models.DecimalFromFloat(1)},
+    }
+    return NewStockService(client, cfg, zap.NewNop()), client
+}
+
+// newTestPiece crée une pièce avec le stock initial donné
+func newTestPiece(t *testing.T, s *StockService, quantite float64) *models.Piece {
+    t.Helper()
+
+    piece := &models.Piece{
+        Nom:          "Roulement 6205",
+        Quantite:     quantite,
+        SeuilMin:     1,
+        PrixUnitaire: models.DecimalFromFloat(5),
+        UniteStock:   "pièce",
+    }
+    if err := s.CreatePiece(piece, "test"); err != nil {
+        t.Fatalf("création de la pièce: %v", err)
+    }
+    return piece
+}
+
+// TestConcurrentDecrements vérifie que des sorties parallèles sur une même
+// pièce ne vendent jamais plus que le stock: chaque sortie acceptée est
+// historisée une fois et les autres sont refusées pour stock insuffisant
+func TestConcurrentDecrements(t *testing.T) {
+    const (
+        stock      = 100
+        decrements = 300
+    )
+
+    s, client := newTestService(t)
+    piece := &models.Piece{
+        Nom:          "Roulement 6205",
+        Quantite:     stock,
+        SeuilMin:     10,
+        PrixUnitaire: models.DecimalFromFloat(5),
+        UniteStock:   "pièce",
+    }
+    if err := s.CreatePiece(piece, "test"); err != nil {
+        t.Fatalf("création de la pièce: %v", err)
+    }
+
+    var (
+        mu        sync.Mutex
+        succeeded int
+        denied    int
+        failures  []error
+        wg        sync.WaitGroup
+        start     = make(chan struct{})
+    )
+    for i := 0; i < decrements; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            <-start
+            _, err := s.DecrementStock(piece.ID, &models.StockMovementRequest{Quantite: 1, Motif: "test"}, "test")
+
+            mu.Lock()
+            defer mu.Unlock()
+            switch {
+            case err == nil:
+                succeeded++
+            case strings.HasPrefix(err.Error(), "stock insuffisant"):
+                denied++
+            default:
+                failures = append(failures, err)
+            }
+        }()
+    }
+    close(start)
+    wg.Wait()
+
+    for _, err := range failures {
+        t.Errorf("sortie en erreur: %v", err)
+    }
+    if succeeded != stock {
+        t.Errorf("sorties acceptées = %d, attendu %d", succeeded, stock)
+    }
+    if denied != decrements-stock {
+        t.Errorf("sorties refusées = %d, attendu %d", denied, decrements-stock)
+    }
+
+    final, err := s.GetPiece(piece.ID)
+    if err != nil {
+        t.Fatalf("lecture de la pièce: %v", err)
+    }
+    if final.Quantite != 0 {
+        t.Errorf("quantité finale = %g, attendu 0", final.Quantite)
+    }
+
+    // Le stock initial est historisé comme une entrée
+    movements, err := client.ZCard(context.Background(), PIECE_MOVEMENTS_PREFIX+piece.ID).Result()
+    if err != nil {
+        t.Fatalf("lecture de l'historique: %v", err)
+    }
+    if movements != int64(succeeded)+1 {
+        t.Errorf("mouvements historisés = %d, attendu %d", movements, succeeded+1)
+    }
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "stock-service/models"
+    "sync"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+    "github.com/google/uuid"
+    "go.uber.org/zap"
+)
+
+const (
+    STOCK_EVENTS_CHANNEL       = "stock:events" // canal Pub/Sub partagé par les instances
+    STOCK_EVENT_BUFFER         = 64             // événements en attente par abonné
+    STOCK_EVENT_RETRY_INTERVAL = 2 * time.Second
+)
+
+// EventSubscription représente un abonnement local au flux des pièces. Le
+// canal d'événements est fermé à la fin de l'abonnement, y compris quand
+// l'abonné ne consomme pas assez vite: le client doit alors se reconnecter.
+type EventSubscription struct {
+    hub    *eventHub
+    filter models.StockEventFilter
+    events chan *models.StockEvent
+    once   sync.Once
+}
+
+// Events retourne le canal des événements de l'abonnement
+func (sub *EventSubscription) Events() <-chan *models.StockEvent {
+    return sub.events
+}
+
+// Close met fin à l'abonnement
+func (sub *EventSubscription) Close() {
+    sub.hub.remove(sub)
+}
+
+// eventHub diffuse aux abonnés de l'instance les événements reçus du canal
+// Redis
+type eventHub struct {
+    mu          sync.Mutex
+    subscribers map[*EventSubscription]struct{}
+}
+
+func newEventHub() *eventHub {
+    return &eventHub{subscribers: make(map[*EventSubscription]struct{})}
+}
+
+func (h *eventHub) add(filter models.StockEventFilter) *EventSubscription {
+    sub := &EventSubscription{
+        hub:    h,
+        filter: filter,
+        events: make(chan *models.StockEvent, STOCK_EVENT_BUFFER),
+    }
+    h.mu.Lock()
+    h.subscribers[sub] = struct{}{}
+    h.mu.Unlock()
+    return sub
+}
+
+func (h *eventHub) remove(sub *EventSubscription) {
+    h.mu.Lock()
+    delete(h.subscribers, sub)
+    h.mu.Unlock()
+    sub.once.Do(func() { close(sub.events) })
+}
+
+// broadcast transmet un événement aux abonnés dont le filtre l'accepte,
+// sans jamais bloquer: un abonné dont la file est pleine est déconnecté
+func (h *eventHub) broadcast(event *models.StockEvent) int {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    dropped := 0
+    for sub := range h.subscribers {
+        if !sub.filter.Matches(event) {
+            continue
+        }
+        select {
+        case sub.events <- event:
+        default:
+            delete(h.subscribers, sub)
+            sub.once.Do(func() { close(sub.events) })
+            dropped++
+        }
+    }
+    return dropped
+}
+
+// SubscribeEvents abonne l'appelant aux événements des pièces satisfaisant
+// le filtre; l'abonnement doit être fermé par Close
+func (s *StockService) SubscribeEvents(filter models.StockEventFilter) *EventSubscription {
+    return s.events.add(filter)
+}
+
+// RunEventStream relaie aux abonnés de l'instance les événements publiés par
+// toutes les instances sur le canal Redis, jusqu'à l'annulation du contexte.
+// L'abonnement Redis est rétabli après une coupure; les événements publiés
+// pendant la coupure sont perdus pour les clients connectés.
+func (s *StockService) RunEventStream(ctx context.Context) {
+    for {
+        s.relayEvents(ctx)
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-time.After(STOCK_EVENT_RETRY_INTERVAL):
+        }
+    }
+}
+
+// relayEvents relaie les messages du canal jusqu'à une erreur ou l'annulation
+// du contexte
+func (s *StockService) relayEvents(ctx context.Context) {
+    pubsub := s.redis.Subscribe(ctx, STOCK_EVENTS_CHANNEL)
+    defer pubsub.Close()
+
+    if _, err := pubsub.Receive(ctx); err != nil {
+        if ctx.Err() == nil {
+            s.logger.Warn("Abonnement au flux des pièces impossible", zap.Error(err))
+        }
+        return
+    }
+
+    for {
+        msg, err := pubsub.ReceiveMessage(ctx)
+        if err != nil {
+            if ctx.Err() == nil {
+                s.logger.Warn("Flux des pièces interrompu", zap.Error(err))
+            }
+            return
+        }
+
+        var event models.StockEvent
+        if err := event.FromJSON([]byte(msg.Payload)); err != nil {
+            s.logger.Warn("Événement de pièce illisible", zap.Error(err))
+            continue
+        }
+        if dropped := s.events.broadcast(&event); dropped > 0 {
+            s.logger.Warn("Abonnés du flux trop lents déconnectés",
+                zap.Int("abonnes", dropped),
+                zap.String("event_id", event.ID))
+        }
+    }
+}
+
+// newStockEvent construit l'événement d'une pièce; les emplacements
+// concernés sont ceux de la pièce et, pour un mouvement, ceux du mouvement
+func newStockEvent(eventType string, piece *models.Piece, movement *models.StockMovement) *models.StockEvent {
+    locations := make(map[string]bool, len(piece.Emplacements))
+    for location := range piece.Emplacements {
+        locations[location] = true
+    }
+    if movement != nil {
+        for location := range movement.Emplacements {
+            locations[location] = true
+        }
+    }
+    emplacements := make([]string, 0, len(locations))
+    for location := range locations {
+        emplacements = append(emplacements, location)
+    }
+    sort.Strings(emplacements)
+
+    return &models.StockEvent{
+        ID:           uuid.New().String(),
+        Type:         eventType,
+        PieceID:      piece.ID,
+        Categorie:    piece.Categorie,
+        Emplacements: emplacements,
+        Piece:        piece,
+        Mouvement:    movement,
+        CreatedAt:    time.Now(),
+    }
+}
+
+// queueStockEvent met en file la publication d'un événement sur le canal
+// partagé par les instances
+func queueStockEvent(ctx context.Context, pipe redis.Pipeliner, event *models.StockEvent) error {
+    eventJSON, err := event.ToJSON()
+    if err != nil {
+        return fmt.Errorf("erreur de sérialisation de l'événement: %w", err)
+    }
+    pipe.Publish(ctx, STOCK_EVENTS_CHANNEL, eventJSON)
+    return nil
+}
@@ -0,0 +1,454 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "stock-service/models"
+    "strings"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+    "github.com/google/uuid"
+    "go.uber.org/zap"
+)
+
+const (
+    SUPPLIER_KEY_PREFIX      = "stock:supplier:"
+    SUPPLIERS_SET_KEY        = "stock:suppliers"
+    SUPPLIER_NAME_KEY_PREFIX = "stock:suppliers:name:"
+)
+
+// CreateSupplier crée un fournisseur; deux fournisseurs ne peuvent pas avoir
+// le même nom aux accents, à la casse et aux espaces près
+func (s *StockService) CreateSupplier(req *models.CreateSupplierRequest) (*models.Supplier, error) {
+    supplier := s.newSupplier(req.Nom, req.Devise)
+    supplier.Contact = req.Contact
+    if req.DelaiLivraisonJours != nil {
+        supplier.DelaiLivraisonJours = *req.DelaiLivraisonJours
+    }
+
+    if err := s.saveNewSupplier(supplier); err != nil {
+        return nil, err
+    }
+
+    s.logger.Info("Fournisseur créé",
+        zap.String("fournisseur_id", supplier.ID),
+        zap.String("nom", supplier.Nom))
+
+    return supplier, nil
+}
+
+// GetSupplier récupère un fournisseur par ID
+func (s *StockService) GetSupplier(id string) (*models.Supplier, error) {
+    ctx := context.Background()
+
+    supplierJSON, err := s.redis.Get(ctx, SUPPLIER_KEY_PREFIX+id).Result()
+    if err == redis.Nil {
+        return nil, fmt.Errorf("fournisseur non trouvé: %s", id)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération du fournisseur: %w", err)
+    }
+
+    var supplier models.Supplier
+    if err := supplier.FromJSON([]byte(supplierJSON)); err != nil {
+        return nil, fmt.Errorf("erreur de désérialisation: %w", err)
+    }
+
+    return &supplier, nil
+}
+
+// GetSuppliers récupère les fournisseurs par ordre alphabétique, seulement
+// les actifs si demandé
+func (s *StockService) GetSuppliers(activeOnly bool) ([]models.Supplier, error) {
+    ctx := context.Background()
+
+    ids, err := s.redis.SMembers(ctx, SUPPLIERS_SET_KEY).Result()
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération des fournisseurs: %w", err)
+    }
+
+    suppliers := make([]models.Supplier, 0, len(ids))
+    for _, id := range ids {
+        supplier, err := s.GetSupplier(id)
+        if err != nil {
+            s.logger.Warn("Impossible de récupérer le fournisseur", zap.String("id", id), zap.Error(err))
+            continue
+        }
+        if activeOnly && !supplier.Actif {
+            continue
+        }
+        suppliers = append(suppliers, *supplier)
+    }
+
+    sort.Slice(suppliers, func(i, j int) bool {
+        return models.SupplierKey(suppliers[i].Nom) < models.SupplierKey(suppliers[j].Nom)
+    })
+
+    return suppliers, nil
+}
+
+// UpdateSupplier met à jour un fournisseur. Un changement de nom est reporté
+// sur les pièces dont il est le fournisseur préféré; un changement de devise
+// convertit les prix d'achat des pièces liées, dans la même transaction.
+func (s *StockService) UpdateSupplier(id string, updates *models.UpdateSupplierRequest) (*models.Supplier, error) {
+    var supplier *models.Supplier
+    renamed := false
+
+    var linked []models.Piece
+    if updates.Devise != nil {
+        var err error
+        linked, err = s.ListPieces(models.PieceFilter{FournisseurID: id})
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    err := s.runStockTx(func(t *stockTx) error {
+        var err error
+        supplier, err = getSupplierTx(t, id)
+        if err != nil {
+            return err
+        }
+
+        oldKey := models.SupplierKey(supplier.Nom)
+        renamed = false
+        if updates.Nom != nil && strings.TrimSpace(*updates.Nom) != supplier.Nom {
+            name := strings.TrimSpace(*updates.Nom)
+            newKey := models.SupplierKey(name)
+            if newKey != oldKey {
+                existing, err := t.watchGet(SUPPLIER_NAME_KEY_PREFIX + newKey)
+                if err != nil && err != redis.Nil {
+                    return fmt.Errorf("erreur lors de la vérification du nom: %w", err)
+                }
+                if err == nil && existing != id {
+                    return fmt.Errorf("fournisseur existant: %s", name)
+                }
+                t.queue(func(pipe redis.Pipeliner) error {
+                    pipe.Del(t.ctx, SUPPLIER_NAME_KEY_PREFIX+oldKey)
+                    pipe.Set(t.ctx, SUPPLIER_NAME_KEY_PREFIX+newKey, id, 0)
+                    return nil
+                })
+            }
+            supplier.Nom = name
+            renamed = true
+        }
+        if updates.Contact != nil {
+            supplier.Contact = *updates.Contact
+        }
+        if updates.DelaiLivraisonJours != nil {
+            supplier.DelaiLivraisonJours = *updates.DelaiLivraisonJours
+        }
+        if updates.Devise != nil {
+            devise := models.CanonicalCurrency(*updates.Devise)
+            if devise != supplier.Devise {
+                if err := s.convertPurchasePrices(t, linked, id, supplier.Devise, devise); err != nil {
+                    return err
+                }
+            }
+            supplier.Devise = devise
+        }
+        if updates.Actif != nil {
+            supplier.Actif = *updates.Actif
+        }
+
+        supplier.UpdatedAt = time.Now()
+        queueSupplier(t, supplier)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    if renamed {
+        s.refreshSupplierNames(supplier)
+    }
+
+    s.logger.Info("Fournisseur mis à jour",
+        zap.String("fournisseur_id", id),
+        zap.String("nom", supplier.Nom))
+
+    return supplier, nil
+}
+
+// ResolveSupplier retrouve un fournisseur par son nom normalisé, ou le crée
+// avec la devise donnée et le délai de livraison par défaut
+func (s *StockService) ResolveSupplier(name, devise string) (*models.Supplier, error) {
+    ctx := context.Background()
+    name = strings.TrimSpace(name)
+
+    for attempt := 1; attempt <= MAX_TX_RETRIES; attempt++ {
+        id, err := s.redis.Get(ctx, SUPPLIER_NAME_KEY_PREFIX+models.SupplierKey(name)).Result()
+        if err == nil {
+            return s.GetSupplier(id)
+        }
+        if err != redis.Nil {
+            return nil, fmt.Errorf("erreur lors de la recherche du fournisseur: %w", err)
+        }
+
+        supplier := s.newSupplier(name, devise)
+        err = s.saveNewSupplier(supplier)
+        if err == nil {
+            s.logger.Info("Fournisseur créé depuis un nom libre",
+                zap.String("fournisseur_id", supplier.ID),
+                zap.String("nom", supplier.Nom))
+            return supplier, nil
+        }
+        // Créé entre-temps par un autre client: relecture par le nom
+        if !strings.HasPrefix(err.Error(), "fournisseur existant") {
+            return nil, err
+        }
+    }
+
+    return nil, fmt.Errorf("fournisseur non résolu: %s", name)
+}
+
+// supplierLookup lit un fournisseur par ID: directement, ou sous surveillance
+// dans une transaction
+type supplierLookup func(id string) (*models.Supplier, error)
+
+// txSuppliers lit les fournisseurs sous surveillance dans une transaction;
+// created est le fournisseur dont elle met en file la création, s'il y en a
+func txSuppliers(t *stockTx, created *models.Supplier) supplierLookup {
+    return func(id string) (*models.Supplier, error) {
+        if created != nil && created.ID == id {
+            return created, nil
+        }
+        return getSupplierTx(t, id)
+    }
+}
+
+// setSupplierLinks valide les liens fournisseur d'une pièce, complète les prix
+// d'achat manquants et reporte le nom du fournisseur préféré dans Fournisseur
+func (s *StockService) setSupplierLinks(piece *models.Piece, links []models.SupplierLink, lookup supplierLookup) error {
+    seen := make(map[string]bool, len(links))
+    preferred := 0
+    result := make([]models.SupplierLink, 0, len(links))
+
+    for _, link := range links {
+        if seen[link.FournisseurID] {
+            return fmt.Errorf("fournisseur invalide: %s lié deux fois à la pièce", link.FournisseurID)
+        }
+        seen[link.FournisseurID] = true
+
+        supplier, err := lookup(link.FournisseurID)
+        if err != nil {
+            return fmt.Errorf("fournisseur invalide: %s inconnu", link.FournisseurID)
+        }
+        if link.Prefere {
+            preferred++
+        }
+        if err := piece.ValidateQuantity(link.QuantiteMinimum); err != nil {
+            return err
+        }
+        if link.PrixAchat == 0 {
+            if link.PrixAchat, err = s.convertMoney(piece.PrixUnitaire, piece.Devise, supplier.Devise); err != nil {
+                return err
+            }
+        }
+        result = append(result, link)
+    }
+    if preferred > 1 {
+        return fmt.Errorf("fournisseur invalide: un seul fournisseur préféré par pièce")
+    }
+
+    piece.Fournisseurs = result
+    if len(result) == 0 {
+        piece.Fournisseurs = nil
+    }
+    return refreshPreferredSupplier(piece, lookup)
+}
+
+// preferSupplier rattache une pièce à un fournisseur, s'il ne l'est pas déjà,
+// et en fait son fournisseur préféré; sans fournisseur, tous les liens de la
+// pièce sont retirés
+func (s *StockService) preferSupplier(piece *models.Piece, supplier *models.Supplier, lookup supplierLookup) error {
+    if supplier == nil {
+        piece.Fournisseurs = nil
+        piece.Fournisseur = ""
+        return nil
+    }
+
+    links := make([]models.SupplierLink, 0, len(piece.Fournisseurs)+1)
+    found := false
+    for _, link := range piece.Fournisseurs {
+        link.Prefere = link.FournisseurID == supplier.ID
+        found = found || link.Prefere
+        links = append(links, link)
+    }
+    if !found {
+        links = append(links, models.SupplierLink{FournisseurID: supplier.ID, Prefere: true})
+    }
+    return s.setSupplierLinks(piece, links, lookup)
+}
+
+// refreshPreferredSupplier reporte le nom du fournisseur préféré dans le
+// champ Fournisseur, conservé pour les clients existants
+func refreshPreferredSupplier(piece *models.Piece, lookup supplierLookup) error {
+    link := piece.PreferredSupplier()
+    if link == nil {
+        piece.Fournisseur = ""
+        return nil
+    }
+    supplier, err := lookup(link.FournisseurID)
+    if err != nil {
+        return err
+    }
+    piece.Fournisseur = supplier.Nom
+    return nil
+}
+
+// convertPurchasePrices convertit dans la nouvelle devise du fournisseur les
+// prix d'achat de ses liens avec les pièces données
+func (s *StockService) convertPurchasePrices(t *stockTx, pieces []models.Piece, supplierID, from, to string) error {
+    for _, linked := range pieces {
+        piece, err := t.getPiece(linked.ID)
+        if err != nil {
+            return err
+        }
+        for i := range piece.Fournisseurs {
+            link := &piece.Fournisseurs[i]
+            if link.FournisseurID != supplierID {
+                continue
+            }
+            if link.PrixAchat, err = s.convertMoney(link.PrixAchat, from, to); err != nil {
+                return err
+            }
+            piece.UpdatedAt = time.Now()
+            t.savePiece(piece)
+        }
+    }
+    return nil
+}
+
+// refreshSupplierNames reporte le nouveau nom d'un fournisseur sur les
+// pièces dont il est le fournisseur préféré
+func (s *StockService) refreshSupplierNames(supplier *models.Supplier) {
+    pieces, err := s.ListPieces(models.PieceFilter{FournisseurID: supplier.ID})
+    if err != nil {
+        s.logger.Warn("Nom du fournisseur non reporté sur les pièces", zap.String("fournisseur_id", supplier.ID), zap.Error(err))
+        return
+    }
+
+    for _, piece := range pieces {
+        if link := piece.PreferredSupplier(); link == nil || link.FournisseurID != supplier.ID {
+            continue
+        }
+        err := s.runStockTx(func(t *stockTx) error {
+            current, err := t.getPiece(piece.ID)
+            if err != nil {
+                return err
+            }
+            current.Fournisseur = supplier.Nom
+            t.savePiece(current)
+            return nil
+        })
+        if err != nil {
+            s.logger.Warn("Nom du fournisseur non reporté", zap.String("piece_id", piece.ID), zap.Error(err))
+        }
+    }
+}
+
+// newSupplier prépare un fournisseur actif
+func (s *StockService) newSupplier(name, devise string) *models.Supplier {
+    if devise == "" {
+        devise = s.config.Currency
+    }
+    now := time.Now()
+    return &models.Supplier{
+        ID:                  uuid.New().String(),
+        Nom:                 strings.TrimSpace(name),
+        DelaiLivraisonJours: s.config.DefaultLeadTimeDays,
+        Devise:              models.CanonicalCurrency(devise),
+        Actif:               true,
+        CreatedAt:           now,
+        UpdatedAt:           now,
+    }
+}
+
+// saveNewSupplier enregistre un nouveau fournisseur et réserve son nom dans
+// la même transaction
+func (s *StockService) saveNewSupplier(supplier *models.Supplier) error {
+    key := models.SupplierKey(supplier.Nom)
+    if key == "" {
+        return fmt.Errorf("fournisseur invalide: nom vide")
+    }
+
+    return s.runStockTx(func(t *stockTx) error {
+        _, err := t.watchGet(SUPPLIER_NAME_KEY_PREFIX + key)
+        if err == nil {
+            return fmt.Errorf("fournisseur existant: %s", supplier.Nom)
+        }
+        if err != redis.Nil {
+            return fmt.Errorf("erreur lors de la vérification du nom: %w", err)
+        }
+
+        queueNewSupplier(t, supplier)
+        return nil
+    })
+}
+
+// resolveSupplierTx retrouve sous surveillance un fournisseur par son nom
+// normalisé, ou met en file sa création avec les écritures de la
+// transaction: il n'est créé que si elles sont appliquées
+func (s *StockService) resolveSupplierTx(t *stockTx, name, devise string) (*models.Supplier, bool, error) {
+    key := models.SupplierKey(name)
+    if key == "" {
+        return nil, false, fmt.Errorf("fournisseur invalide: nom vide")
+    }
+
+    id, err := t.watchGet(SUPPLIER_NAME_KEY_PREFIX + key)
+    if err == nil {
+        supplier, err := getSupplierTx(t, id)
+        return supplier, false, err
+    }
+    if err != redis.Nil {
+        return nil, false, fmt.Errorf("erreur lors de la recherche du fournisseur: %w", err)
+    }
+
+    supplier := s.newSupplier(name, devise)
+    queueNewSupplier(t, supplier)
+    return supplier, true, nil
+}
+
+// queueNewSupplier met en file l'enregistrement d'un nouveau fournisseur et
+// la réservation de son nom
+func queueNewSupplier(t *stockTx, supplier *models.Supplier) {
+    queueSupplier(t, supplier)
+    t.queue(func(pipe redis.Pipeliner) error {
+        pipe.Set(t.ctx, SUPPLIER_NAME_KEY_PREFIX+models.SupplierKey(supplier.Nom), supplier.ID, 0)
+        pipe.SAdd(t.ctx, SUPPLIERS_SET_KEY, supplier.ID)
+        return nil
+    })
+}
+
+// getSupplierTx lit un fournisseur en plaçant sa clé sous surveillance
+func getSupplierTx(t *stockTx, id string) (*models.Supplier, error) {
+    supplierJSON, err := t.watchGet(SUPPLIER_KEY_PREFIX + id)
+    if err == redis.Nil {
+        return nil, fmt.Errorf("fournisseur non trouvé: %s", id)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération du fournisseur: %w", err)
+    }
+
+    var supplier models.Supplier
+    if err := supplier.FromJSON([]byte(supplierJSON)); err != nil {
+        return nil, fmt.Errorf("erreur de désérialisation: %w", err)
+    }
+
+    return &supplier, nil
+}
+
+// queueSupplier met en file l'enregistrement d'un fournisseur
+func queueSupplier(t *stockTx, supplier *models.Supplier) {
+    t.queue(func(pipe redis.Pipeliner) error {
+        supplierJSON, err := supplier.ToJSON()
+        if err != nil {
+            return fmt.Errorf("erreur de sérialisation du fournisseur: %w", err)
+        }
+        pipe.Set(t.ctx, SUPPLIER_KEY_PREFIX+supplier.ID, supplierJSON, 0)
+        return nil
+    })
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "stock-service/config"
+    "stock-service/models"
+    "strings"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+    "go.uber.org/zap"
+)
+
+const (
+    SERIAL_MOVEMENTS_PREFIX = "stock:movements:serial:"
+)
+
+// validateInitialTracking vérifie que les lots d'une nouvelle pièce suivie
+// couvrent exactement sa quantité initiale
+func validateInitialTracking(piece *models.Piece) error {
+    if !piece.IsTracked() {
+        if len(piece.Lots) > 0 {
+            return fmt.Errorf("suivi invalide: lots fournis pour une pièce sans suivi")
+        }
+        return nil
+    }
+
+    seen := make(map[string]bool, len(piece.Lots))
+    for _, lot := range piece.Lots {
+        if seen[lot.Numero] {
+            return fmt.Errorf("suivi invalide: numéro en double %s", lot.Numero)
+        }
+        seen[lot.Numero] = true
+        if piece.Suivi == models.TRACKING_SERIAL && lot.Quantite != 1 {
+            return fmt.Errorf("suivi invalide: le numéro de série %s doit avoir une quantité de 1", lot.Numero)
+        }
+        if err := piece.ValidateQuantity(lot.Quantite); err != nil {
+            return err
+        }
+    }
+
+    if total := piece.LotsQuantity(); total != piece.Quantite {
+        return fmt.Errorf("suivi invalide: les lots couvrent %s unité(s) pour une quantité de %s",
+            models.FormatQuantity(total), models.FormatQuantity(piece.Quantite))
+    }
+
+    return nil
+}
+
+// receiveTracking enregistre le lot ou les numéros de série d'une entrée,
+// avec leur éventuelle date de péremption
+func receiveTracking(piece *models.Piece, lot string, serials []string, expiresAt *time.Time, quantite float64, receivedAt time.Time) (map[string]float64, []string, error) {
+    switch piece.Suivi {
+    case models.TRACKING_LOT:
+        lot = strings.TrimSpace(lot)
+        if lot == "" {
+            return nil, nil, fmt.Errorf("suivi invalide: numéro de lot requis pour la pièce %s", piece.ID)
+        }
+        if index := piece.FindLot(lot); index >= 0 {
+            existing := &piece.Lots[index]
+            if expiresAt != nil {
+                if existing.DatePeremption != nil && !existing.DatePeremption.Equal(*expiresAt) {
+                    return nil, nil, fmt.Errorf("suivi invalide: le lot %s a déjà une date de péremption au %s", lot, existing.DatePeremption.Format("2006-01-02"))
+                }
+                existing.DatePeremption = expiresAt
+            }
+            existing.Quantite = piece.RoundQuantity(existing.Quantite + quantite)
+        } else {
+            piece.Lots = append(piece.Lots, models.StockLot{Numero: lot, Quantite: quantite, DateReception: receivedAt, DatePeremption: expiresAt})
+        }
+        return map[string]float64{lot: quantite}, nil, nil
+
+    case models.TRACKING_SERIAL:
+        serials, err := normalizeSerials(serials, quantite)
+        if err != nil {
+            return nil, nil, err
+        }
+        for _, serial := range serials {
+            if piece.FindLot(serial) >= 0 {
+                return nil, nil, fmt.Errorf("suivi invalide: numéro de série déjà en stock %s", serial)
+            }
+        }
+        for _, serial := range serials {
+            piece.Lots = append(piece.Lots, models.StockLot{Numero: serial, Quantite: 1, DateReception: receivedAt, DatePeremption: expiresAt})
+        }
+        return nil, serials, nil
+    }
+
+    if lot != "" || len(serials) > 0 || expiresAt != nil {
+        return nil, nil, fmt.Errorf("suivi invalide: la pièce %s n'est suivie ni par lot ni par numéro de série", piece.ID)
+    }
+    return nil, nil, nil
+}
+
+// issueTracking retire le lot ou les numéros de série d'une sortie. Sans lot
+// précisé, les lots sont consommés dans l'ordre FEFO; un lot choisi hors de
+// cet ordre, ou périmé, est refusé en mode FEFO strict et seulement signalé
+// sinon.
+func (s *StockService) issueTracking(piece *models.Piece, lot string, serials []string, quantite float64) (map[string]float64, []string, error) {
+    now := time.Now()
+
+    switch piece.Suivi {
+    case models.TRACKING_LOT:
+        lot = strings.TrimSpace(lot)
+        if lot == "" {
+            return issueFEFO(piece, quantite, now)
+        }
+        index := piece.FindLot(lot)
+        if index < 0 {
+            return nil, nil, fmt.Errorf("suivi invalide: lot %s absent du stock", lot)
+        }
+        if piece.Lots[index].Quantite < quantite {
+            return nil, nil, fmt.Errorf("stock insuffisant dans le lot %s: disponible=%s, demandé=%s",
+                lot, models.FormatQuantity(piece.Lots[index].Quantite), models.FormatQuantity(quantite))
+        }
+        if err := s.checkExpiredIssue(piece, index, now); err != nil {
+            return nil, nil, err
+        }
+        if first := firstToExpire(piece, now); first != nil && expiresEarlier(first, &piece.Lots[index]) {
+            if s.config.FEFOMode == config.FEFO_MODE_STRICT {
+                return nil, nil, fmt.Errorf("FEFO non respecté: le lot %s périme avant le lot %s", first.Numero, lot)
+            }
+            s.logger.Warn("Sortie hors ordre FEFO",
+                zap.String("piece_id", piece.ID),
+                zap.String("lot", lot),
+                zap.String("lot_suggere", first.Numero))
+        }
+        removeFromLot(piece, index, quantite)
+        return map[string]float64{lot: quantite}, nil, nil
+
+    case models.TRACKING_SERIAL:
+        serials, err := normalizeSerials(serials, quantite)
+        if err != nil {
+            return nil, nil, err
+        }
+        for _, serial := range serials {
+            index := piece.FindLot(serial)
+            if index < 0 {
+                return nil, nil, fmt.Errorf("suivi invalide: numéro de série absent du stock %s", serial)
+            }
+            if err := s.checkExpiredIssue(piece, index, now); err != nil {
+                return nil, nil, err
+            }
+        }
+        for _, serial := range serials {
+            removeFromLot(piece, piece.FindLot(serial), 1)
+        }
+        return nil, serials, nil
+    }
+
+    if lot != "" || len(serials) > 0 {
+        return nil, nil, fmt.Errorf("suivi invalide: la pièce %s n'est suivie ni par lot ni par numéro de série", piece.ID)
+    }
+    return nil, nil, nil
+}
+
+// checkExpiredIssue contrôle la sortie d'un lot ou d'un numéro de série
+// désigné par l'appelant: un lot périmé, que l'ordre FEFO écarte, est refusé
+// en mode FEFO strict et seulement signalé sinon
+func (s *StockService) checkExpiredIssue(piece *models.Piece, index int, now time.Time) error {
+    lot := &piece.Lots[index]
+    if !lot.IsExpired(now) {
+        return nil
+    }
+    if s.config.FEFOMode == config.FEFO_MODE_STRICT {
+        return fmt.Errorf("FEFO non respecté: le lot %s est périmé depuis le %s", lot.Numero, lot.DatePeremption.Format("2006-01-02"))
+    }
+    s.logger.Warn("Sortie d'un lot périmé",
+        zap.String("piece_id", piece.ID),
+        zap.String("lot", lot.Numero),
+        zap.Time("date_peremption", *lot.DatePeremption))
+    return nil
+}
+
+// adjustTracking répercute un ajustement d'inventaire sur les lots: un écart
+// négatif est retiré des lots dans l'ordre FEFO, un écart positif crée un lot
+// d'inventaire. Un excédent de pièces suivies par numéro de série ne peut pas
+// être régularisé sans les numéros et doit passer par une entrée.
+func adjustTracking(piece *models.Piece, delta float64, lotName string, now time.Time) (map[string]float64, []string, error) {
+    if !piece.IsTracked() || delta == 0 {
+        return nil, nil, nil
+    }
+
+    if delta > 0 {
+        if piece.Suivi == models.TRACKING_SERIAL {
+            return nil, nil, fmt.Errorf("suivi invalide: excédent de %s unité(s) sur la pièce %s suivie par numéro de série, à saisir en entrée avec les numéros", models.FormatQuantity(delta), piece.ID)
+        }
+        return receiveTracking(piece, lotName, nil, nil, delta, now)
+    }
+
+    sortFEFO(piece.Lots)
+
+    lots := make(map[string]float64)
+    var serials []string
+    remaining := -delta
+    for remaining > 0 && len(piece.Lots) > 0 {
+        lot := piece.Lots[0]
+        taken := lot.Quantite
+        if taken > remaining {
+            taken = remaining
+        }
+        if piece.Suivi == models.TRACKING_SERIAL {
+            serials = append(serials, lot.Numero)
+        } else {
+            lots[lot.Numero] = taken
+        }
+        removeFromLot(piece, 0, taken)
+        remaining = piece.RoundQuantity(remaining - taken)
+    }
+
+    if len(lots) == 0 {
+        lots = nil
+    }
+    return lots, serials, nil
+}
+
+// removeFromLot retire une quantité d'un lot et supprime le lot épuisé
+func removeFromLot(piece *models.Piece, index int, quantite float64) {
+    piece.Lots[index].Quantite = piece.RoundQuantity(piece.Lots[index].Quantite - quantite)
+    if piece.Lots[index].Quantite <= 0 {
+        piece.Lots = append(piece.Lots[:index], piece.Lots[index+1:]...)
+    }
+}
+
+// normalizeSerials vérifie qu'il y a un numéro de série distinct par unité;
+// une quantité fractionnaire ne peut pas être suivie par numéro de série
+func normalizeSerials(serials []string, quantite float64) ([]string, error) {
+    if float64(len(serials)) != quantite {
+        return nil, fmt.Errorf("suivi invalide: %d numéro(s) de série fourni(s) pour une quantité de %s", len(serials), models.FormatQuantity(quantite))
+    }
+
+    seen := make(map[string]bool, len(serials))
+    result := make([]string, 0, len(serials))
+    for _, serial := range serials {
+        serial = strings.TrimSpace(serial)
+        if serial == "" || seen[serial] {
+            return nil, fmt.Errorf("suivi invalide: numéro de série vide ou en double '%s'", serial)
+        }
+        seen[serial] = true
+        result = append(result, serial)
+    }
+    return result, nil
+}
+
+// TraceSerial retrace tous les mouvements d'un numéro de série, toutes pièces confondues
+func (s *StockService) TraceSerial(serial string) (*models.SerialTrace, error) {
+    ctx := context.Background()
+    serial = strings.TrimSpace(serial)
+
+    ids, err := s.redis.ZRangeByScore(ctx, SERIAL_MOVEMENTS_PREFIX+serial, &redis.ZRangeBy{
+        Min: "-inf",
+        Max: "+inf",
+    }).Result()
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la recherche du numéro de série: %w", err)
+    }
+    if len(ids) == 0 {
+        return nil, fmt.Errorf("numéro de série non trouvé: %s", serial)
+    }
+
+    movements, err := s.loadMovements(ctx, ids)
+    if err != nil {
+        return nil, err
+    }
+
+    trace := &models.SerialTrace{
+        NumeroSerie: serial,
+        Mouvements:  movements,
+    }
+    if len(movements) > 0 {
+        trace.PieceID = movements[len(movements)-1].PieceID
+        if piece, err := s.GetPiece(trace.PieceID); err == nil {
+            trace.EnStock = piece.FindLot(serial) >= 0
+        }
+    }
+
+    return trace, nil
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "stock-service/models"
+    "strings"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+)
+
+const (
+    PIECE_COSTS_PREFIX = "stock:costs:piece:"
+)
+
+// unitCost retourne le coût unitaire de valorisation d'une pièce selon la
+// méthode configurée
+func (s *StockService) unitCost(piece *models.Piece) models.Decimal {
+    return piece.UnitCost(s.config.ValuationMethod)
+}
+
+// issueCost retire une sortie des couches de coût FIFO et retourne le coût
+// des unités sorties selon la méthode configurée, arrondi selon la devise de
+// la pièce. Les couches sont tenues à jour quelle que soit la méthode.
+func (s *StockService) issueCost(piece *models.Piece, quantite float64) models.Decimal {
+    cost := piece.ConsumeCostLayers(quantite)
+    if s.config.ValuationMethod != models.VALUATION_METHOD_FIFO {
+        cost = piece.PrixMoyenPondere.MulQuantity(quantite)
+    }
+    return models.RoundMoney(cost, piece.Devise)
+}
+
+// valueIssue valorise un mouvement de sortie: coût total arrondi et coût
+// unitaire correspondant, dans la devise de la pièce
+func (s *StockService) valueIssue(movement *models.StockMovement, piece *models.Piece, quantite float64) {
+    movement.CoutSortie = s.issueCost(piece, quantite)
+    movement.CoutUnitaire = movement.CoutSortie.DivQuantity(quantite)
+    movement.Devise = piece.Devise
+}
+
+// convertMoney convertit un montant d'une devise à une autre selon les taux
+// configurés; une devise source vide désigne la devise cible
+func (s *StockService) convertMoney(amount models.Decimal, from, to string) (models.Decimal, error) {
+    if from == "" {
+        return amount, nil
+    }
+    from = models.CanonicalCurrency(from)
+    to = models.CanonicalCurrency(to)
+    if from == to {
+        return amount, nil
+    }
+
+    fromRate, ok := s.config.ExchangeRates[from]
+    if !ok {
+        return 0, fmt.Errorf("devise invalide: aucun taux de change pour %s", from)
+    }
+    toRate, ok := s.config.ExchangeRates[to]
+    if !ok {
+        return 0, fmt.Errorf("devise invalide: aucun taux de change pour %s", to)
+    }
+    return amount.Mul(fromRate).Div(toRate), nil
+}
+
+// queueCostChange historise un changement de prix moyen pondéré
+func queueCostChange(ctx context.Context, pipe redis.Pipeliner, change *models.CostChange) error {
+    changeJSON, err := change.ToJSON()
+    if err != nil {
+        return fmt.Errorf("erreur de sérialisation du coût: %w", err)
+    }
+    pipe.RPush(ctx, PIECE_COSTS_PREFIX+change.PieceID, changeJSON)
+    return nil
+}
+
+// initialCostChange construit l'entrée d'historique du coût d'une nouvelle pièce
+func initialCostChange(piece *models.Piece, userID string) *models.CostChange {
+    return &models.CostChange{
+        PieceID:       piece.ID,
+        Source:        models.COST_SOURCE_INITIAL,
+        NouveauCout:   piece.PrixMoyenPondere,
+        QuantiteRecue: piece.Quantite,
+        CoutReception: piece.PrixMoyenPondere,
+        Devise:        piece.Devise,
+        UserID:        userID,
+        CreatedAt:     piece.CreatedAt,
+    }
+}
+
+// receiveCost valorise une entrée: avec un coût de réception, le prix moyen
+// pondéré est recalculé et le changement retourné; sans coût, l'entrée est
+// valorisée au coût unitaire courant. Le coût est exprimé dans la devise de
+// la pièce.
+func (s *StockService) receiveCost(piece *models.Piece, quantite float64, cost *models.Decimal, userID string, now time.Time) (models.Decimal, *models.CostChange) {
+    if cost == nil {
+        return s.unitCost(piece), nil
+    }
+
+    avant := piece.Quantite
+    ancien := piece.ReceiveAtCost(quantite, *cost)
+    return *cost, &models.CostChange{
+        PieceID:       piece.ID,
+        Source:        models.COST_SOURCE_RECEIPT,
+        AncienCout:    ancien,
+        NouveauCout:   piece.PrixMoyenPondere,
+        QuantiteAvant: avant,
+        QuantiteRecue: quantite,
+        CoutReception: *cost,
+        Devise:        piece.Devise,
+        UserID:        userID,
+        CreatedAt:     now,
+    }
+}
+
+// GetCostHistory retourne l'historique du prix moyen pondéré d'une pièce,
+// du plus récent au plus ancien
+func (s *StockService) GetCostHistory(pieceID string) ([]models.CostChange, error) {
+    ctx := context.Background()
+
+    if _, err := s.GetPiece(pieceID); err != nil {
+        return nil, err
+    }
+
+    entries, err := s.redis.LRange(ctx, PIECE_COSTS_PREFIX+pieceID, 0, -1).Result()
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération de l'historique des coûts: %w", err)
+    }
+
+    history := make([]models.CostChange, 0, len(entries))
+    for i := len(entries) - 1; i >= 0; i-- {
+        var change models.CostChange
+        if err := change.FromJSON([]byte(entries[i])); err != nil {
+            s.logger.Warn("Entrée d'historique des coûts illisible ignorée")
+            continue
+        }
+        history = append(history, change)
+    }
+
+    return history, nil
+}
+
+// GetValuation valorise le stock selon la méthode configurée, avec les totaux
+// par catégorie et par emplacement. Les valeurs sont converties dans la devise
+// configurée et arrondies pièce par pièce selon ses règles, puis cumulées.
+func (s *StockService) GetValuation(filter models.PieceFilter) (*models.ValuationReport, error) {
+    pieces, err := s.ListPieces(filter)
+    if err != nil {
+        return nil, err
+    }
+
+    report := &models.ValuationReport{
+        Methode:     s.config.ValuationMethod,
+        Devise:      s.config.Currency,
+        GeneratedAt: time.Now(),
+    }
+    byCategory := make(map[string]*models.ValuationLine)
+    byLocation := make(map[string]*models.ValuationLine)
+
+    for _, piece := range pieces {
+        cost, err := s.convertMoney(s.unitCost(&piece), piece.Devise, s.config.Currency)
+        if err != nil {
+            return nil, err
+        }
+        value := models.RoundMoney(cost.MulQuantity(piece.Quantite), s.config.Currency)
+
+        report.NombrePieces++
+        report.QuantiteTotale += piece.Quantite
+        report.ValeurTotale = report.ValeurTotale.Add(value)
+
+        category := piece.Categorie
+        if category == "" {
+            category = "non-classe"
+        }
+        addValuation(byCategory, category, piece.Quantite, value)
+
+        for location, quantite := range piece.Emplacements {
+            if quantite == 0 {
+                continue
+            }
+            addValuation(byLocation, location, quantite, models.RoundMoney(cost.MulQuantity(quantite), s.config.Currency))
+        }
+    }
+
+    report.ParCategorie = sortedValuation(byCategory)
+    report.ParEmplacement = sortedValuation(byLocation)
+
+    return report, nil
+}
+
+// addValuation cumule une quantité et une valeur dans un regroupement
+func addValuation(lines map[string]*models.ValuationLine, key string, quantite float64, value models.Decimal) {
+    line, ok := lines[strings.ToLower(key)]
+    if !ok {
+        line = &models.ValuationLine{Cle: key}
+        lines[strings.ToLower(key)] = line
+    }
+    line.NombrePieces++
+    line.Quantite += quantite
+    line.Valeur = line.Valeur.Add(value)
+}
+
+// sortedValuation retourne les regroupements par valeur décroissante
+func sortedValuation(lines map[string]*models.ValuationLine) []models.ValuationLine {
+    result := make([]models.ValuationLine, 0, len(lines))
+    for _, line := range lines {
+        result = append(result, *line)
+    }
+    sort.Slice(result, func(i, j int) bool {
+        if result[i].Valeur != result[j].Valeur {
+            return result[i].Valeur > result[j].Valeur
+        }
+        return result[i].Cle < result[j].Cle
+    })
+    return result
+}
@@ -0,0 +1,664 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "net/url"
+    "sort"
+    "stock-service/models"
+    "strconv"
+    "strings"
+    "sync"
+    "syscall"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+    "github.com/google/uuid"
+    "go.uber.org/zap"
+)
+
+const (
+    WEBHOOK_KEY_PREFIX          = "stock:webhook:"
+    WEBHOOKS_SET_KEY            = "stock:webhooks"
+    WEBHOOK_DELIVERY_KEY_PREFIX = "stock:webhook:delivery:"
+    WEBHOOK_PENDING_KEY         = "stock:webhooks:pending"    // livraisons par date de prochaine tentative
+    WEBHOOK_DEAD_LETTER_KEY     = "stock:webhooks:deadletter" // livraisons abandonnées, par date d'abandon
+    WEBHOOK_LOCK_PREFIX         = "stock:webhook:lock:"
+
+    // WEBHOOK_POLL_INTERVAL est l'intervalle de scrutation des livraisons dues
+    WEBHOOK_POLL_INTERVAL = time.Second
+    // WEBHOOK_BATCH_SIZE est le nombre maximal de livraisons traitées à chaque scrutation
+    WEBHOOK_BATCH_SIZE = 50
+    // WEBHOOK_MAX_BACKOFF plafonne le délai entre deux tentatives
+    WEBHOOK_MAX_BACKOFF = 6 * time.Hour
+    // WEBHOOK_DELIVERED_TTL est la durée de conservation des livraisons réussies
+    WEBHOOK_DELIVERED_TTL = 7 * 24 * time.Hour
+)
+
+// releaseWebhookLock supprime le verrou d'une livraison seulement s'il porte
+// encore le jeton de l'instance qui l'a pris: un verrou expiré puis repris
+// par une autre instance n'est pas libéré à tort
+var releaseWebhookLock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// thresholdState représente la position d'une pièce par rapport à ses seuils
+type thresholdState struct {
+    low      bool
+    critical bool
+}
+
+// pieceThresholds retourne la position d'une pièce par rapport à ses seuils
+func pieceThresholds(piece *models.Piece) thresholdState {
+    return thresholdState{low: piece.IsLowStock(), critical: piece.IsCriticalStock()}
+}
+
+// thresholdEvents retourne les seuils franchis entre deux états: une chute
+// directe sous le seuil critique franchit aussi le seuil minimum
+func thresholdEvents(before, after thresholdState) []string {
+    events := make([]string, 0, 2)
+    if !before.low && after.low {
+        events = append(events, models.WEBHOOK_EVENT_LOW_STOCK)
+    }
+    if !before.critical && after.critical {
+        events = append(events, models.WEBHOOK_EVENT_CRITICAL)
+    }
+    if before.low && !after.low {
+        events = append(events, models.WEBHOOK_EVENT_RESTORED)
+    }
+    return events
+}
+
+// queueThresholdEvents compare l'état des seuils des pièces modifiées dans la
+// transaction à leur état lu et met en file une livraison par abonnement
+// concerné pour chaque seuil franchi
+func (s *StockService) queueThresholdEvents(t *stockTx) error {
+    var subscriptions []models.WebhookSubscription
+    loaded := false
+
+    for _, id := range t.dirty {
+        before, ok := t.initial[id]
+        if !ok {
+            continue
+        }
+        piece := t.pieces[id]
+        piece.RefreshDisponible()
+        events := thresholdEvents(before, pieceThresholds(piece))
+        if len(events) == 0 {
+            continue
+        }
+
+        if !loaded {
+            var err error
+            subscriptions, err = s.loadWebhooks(t.ctx, t.tx)
+            if err != nil {
+                return err
+            }
+            loaded = true
+        }
+
+        now := time.Now()
+        for _, eventType := range events {
+            event := models.WebhookEvent{
+                ID:                 uuid.New().String(),
+                Type:               eventType,
+                PieceID:            piece.ID,
+                Nom:                piece.Nom,
+                Categorie:          piece.Categorie,
+                Quantite:           piece.Quantite,
+                QuantiteDisponible: piece.QuantiteDisponible,
+                SeuilMin:           piece.SeuilMin,
+                SeuilCritique:      piece.SeuilMin / 2,
+                Unite:              piece.UniteStock,
+                CreatedAt:          now,
+            }
+            if movements := t.movements[id]; len(movements) > 0 {
+                movement := movements[len(movements)-1]
+                event.MouvementID = movement.ID
+                event.MouvementType = movement.Type
+            }
+
+            for _, subscription := range subscriptions {
+                if !subscription.Accepts(eventType) {
+                    continue
+                }
+                delivery := &models.WebhookDelivery{
+                    ID:                 uuid.New().String(),
+                    WebhookID:          subscription.ID,
+                    URL:                subscription.URL,
+                    Evenement:          event,
+                    Statut:             models.WEBHOOK_DELIVERY_PENDING,
+                    ProchaineTentative: &now,
+                    CreatedAt:          now,
+                    UpdatedAt:          now,
+                }
+                t.queue(func(pipe redis.Pipeliner) error {
+                    return queueWebhookDelivery(t.ctx, pipe, delivery)
+                })
+            }
+        }
+    }
+    return nil
+}
+
+// queueWebhookDelivery enregistre une livraison et la planifie à sa
+// prochaine tentative
+func queueWebhookDelivery(ctx context.Context, pipe redis.Pipeliner, delivery *models.WebhookDelivery) error {
+    deliveryJSON, err := delivery.ToJSON()
+    if err != nil {
+        return fmt.Errorf("erreur de sérialisation de la livraison: %w", err)
+    }
+    pipe.Set(ctx, WEBHOOK_DELIVERY_KEY_PREFIX+delivery.ID, deliveryJSON, 0)
+    pipe.ZAdd(ctx, WEBHOOK_PENDING_KEY, &redis.Z{
+        Score:  float64(delivery.ProchaineTentative.UnixMilli()),
+        Member: delivery.ID,
+    })
+    return nil
+}
+
+// validateWebhookURL n'accepte que les adresses HTTP(S) absolues et, sauf
+// configuration contraire, refuse les hôtes internes désignés directement.
+// Un nom d'hôte qui se résout vers une adresse interne est refusé à l'envoi.
+func (s *StockService) validateWebhookURL(raw string) error {
+    parsed, err := url.Parse(raw)
+    if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+        return fmt.Errorf("webhook invalide: adresse %q (http ou https attendu)", raw)
+    }
+    if s.config.WebhookAllowPrivate {
+        return nil
+    }
+
+    host := strings.ToLower(parsed.Hostname())
+    if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+        return fmt.Errorf("webhook invalide: adresse interne %s refusée", host)
+    }
+    if ip := net.ParseIP(host); ip != nil && isInternalIP(ip) {
+        return fmt.Errorf("webhook invalide: adresse interne %s refusée", host)
+    }
+    return nil
+}
+
+// isInternalIP indique si une adresse désigne la machine, le lien local ou un
+// réseau privé
+func isInternalIP(ip net.IP) bool {
+    return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+        ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// newWebhookClient crée le client HTTP des envois. Sauf configuration
+// contraire, l'adresse effectivement contactée est contrôlée à la connexion,
+// ce qui couvre les noms résolus vers une adresse interne et les redirections.
+func (s *StockService) newWebhookClient() *http.Client {
+    dialer := &net.Dialer{Timeout: s.webhookTimeout()}
+    if !s.config.WebhookAllowPrivate {
+        dialer.Control = func(network, address string, _ syscall.RawConn) error {
+            host, _, err := net.SplitHostPort(address)
+            if err != nil {
+                return err
+            }
+            if ip := net.ParseIP(host); ip == nil || isInternalIP(ip) {
+                return fmt.Errorf("adresse interne %s refusée", host)
+            }
+            return nil
+        }
+    }
+
+    return &http.Client{
+        Transport: &http.Transport{
+            DialContext:         dialer.DialContext,
+            TLSHandshakeTimeout: s.webhookTimeout(),
+            MaxIdleConnsPerHost: 2,
+            IdleConnTimeout:     90 * time.Second,
+        },
+    }
+}
+
+// CreateWebhook enregistre un abonnement aux franchissements de seuil
+func (s *StockService) CreateWebhook(req *models.CreateWebhookRequest, userID string) (*models.WebhookSubscription, error) {
+    ctx := context.Background()
+
+    if err := s.validateWebhookURL(req.URL); err != nil {
+        return nil, err
+    }
+
+    events := req.Evenements
+    if events == nil {
+        events = []string{}
+    }
+
+    now := time.Now()
+    subscription := &models.WebhookSubscription{
+        ID:          uuid.New().String(),
+        URL:         req.URL,
+        Evenements:  events,
+        Secret:      req.Secret,
+        Description: req.Description,
+        Actif:       true,
+        UserID:      userID,
+        CreatedAt:   now,
+        UpdatedAt:   now,
+    }
+
+    subscriptionJSON, err := subscription.ToJSON()
+    if err != nil {
+        return nil, fmt.Errorf("erreur de sérialisation: %w", err)
+    }
+
+    pipe := s.redis.TxPipeline()
+    pipe.Set(ctx, WEBHOOK_KEY_PREFIX+subscription.ID, subscriptionJSON, 0)
+    pipe.SAdd(ctx, WEBHOOKS_SET_KEY, subscription.ID)
+    if _, err := pipe.Exec(ctx); err != nil {
+        return nil, fmt.Errorf("erreur lors de la création de l'abonnement: %w", err)
+    }
+
+    s.logger.Info("Abonnement webhook créé",
+        zap.String("webhook_id", subscription.ID),
+        zap.String("url", subscription.URL),
+        zap.Strings("evenements", subscription.Evenements))
+
+    return subscription, nil
+}
+
+// GetWebhook récupère un abonnement par ID
+func (s *StockService) GetWebhook(id string) (*models.WebhookSubscription, error) {
+    ctx := context.Background()
+
+    subscriptionJSON, err := s.redis.Get(ctx, WEBHOOK_KEY_PREFIX+id).Result()
+    if err == redis.Nil {
+        return nil, fmt.Errorf("webhook non trouvé: %s", id)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération de l'abonnement: %w", err)
+    }
+
+    var subscription models.WebhookSubscription
+    if err := subscription.FromJSON([]byte(subscriptionJSON)); err != nil {
+        return nil, fmt.Errorf("erreur de désérialisation: %w", err)
+    }
+
+    return &subscription, nil
+}
+
+// GetWebhooks récupère les abonnements par date de création
+func (s *StockService) GetWebhooks() ([]models.WebhookSubscription, error) {
+    return s.loadWebhooks(context.Background(), s.redis)
+}
+
+// loadWebhooks lit les abonnements par la connexion fournie. Une transaction
+// lit par sa propre connexion: en attendre une autre du pool pendant qu'elle
+// garde la sienne peut épuiser le pool sous forte concurrence.
+func (s *StockService) loadWebhooks(ctx context.Context, reader redis.Cmdable) ([]models.WebhookSubscription, error) {
+    ids, err := reader.SMembers(ctx, WEBHOOKS_SET_KEY).Result()
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération des abonnements: %w", err)
+    }
+
+    subscriptions := make([]models.WebhookSubscription, 0, len(ids))
+    for _, id := range ids {
+        subscriptionJSON, err := reader.Get(ctx, WEBHOOK_KEY_PREFIX+id).Result()
+        if err != nil {
+            s.logger.Warn("Impossible de récupérer l'abonnement", zap.String("id", id), zap.Error(err))
+            continue
+        }
+        var subscription models.WebhookSubscription
+        if err := subscription.FromJSON([]byte(subscriptionJSON)); err != nil {
+            s.logger.Warn("Impossible de récupérer l'abonnement", zap.String("id", id), zap.Error(err))
+            continue
+        }
+        subscriptions = append(subscriptions, subscription)
+    }
+
+    sort.Slice(subscriptions, func(i, j int) bool {
+        return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+    })
+
+    return subscriptions, nil
+}
+
+// UpdateWebhook met à jour un abonnement
+func (s *StockService) UpdateWebhook(id string, updates *models.UpdateWebhookRequest) (*models.WebhookSubscription, error) {
+    ctx := context.Background()
+
+    subscription, err := s.GetWebhook(id)
+    if err != nil {
+        return nil, err
+    }
+
+    if updates.URL != nil {
+        if err := s.validateWebhookURL(*updates.URL); err != nil {
+            return nil, err
+        }
+        subscription.URL = *updates.URL
+    }
+    if updates.Evenements != nil {
+        subscription.Evenements = updates.Evenements
+    }
+    if updates.Secret != nil {
+        subscription.Secret = *updates.Secret
+    }
+    if updates.Description != nil {
+        subscription.Description = *updates.Description
+    }
+    if updates.Actif != nil {
+        subscription.Actif = *updates.Actif
+    }
+    subscription.UpdatedAt = time.Now()
+
+    subscriptionJSON, err := subscription.ToJSON()
+    if err != nil {
+        return nil, fmt.Errorf("erreur de sérialisation: %w", err)
+    }
+    if err := s.redis.Set(ctx, WEBHOOK_KEY_PREFIX+id, subscriptionJSON, 0).Err(); err != nil {
+        return nil, fmt.Errorf("erreur lors de la mise à jour de l'abonnement: %w", err)
+    }
+
+    s.logger.Info("Abonnement webhook mis à jour", zap.String("webhook_id", id))
+
+    return subscription, nil
+}
+
+// DeleteWebhook supprime un abonnement; ses livraisons en attente sont
+// abandonnées à leur prochaine tentative
+func (s *StockService) DeleteWebhook(id string) error {
+    ctx := context.Background()
+
+    if _, err := s.GetWebhook(id); err != nil {
+        return err
+    }
+
+    pipe := s.redis.TxPipeline()
+    pipe.Del(ctx, WEBHOOK_KEY_PREFIX+id)
+    pipe.SRem(ctx, WEBHOOKS_SET_KEY, id)
+    if _, err := pipe.Exec(ctx); err != nil {
+        return fmt.Errorf("erreur lors de la suppression de l'abonnement: %w", err)
+    }
+
+    s.logger.Info("Abonnement webhook supprimé", zap.String("webhook_id", id))
+
+    return nil
+}
+
+// getWebhookDelivery récupère une livraison par ID
+func (s *StockService) getWebhookDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+    deliveryJSON, err := s.redis.Get(ctx, WEBHOOK_DELIVERY_KEY_PREFIX+id).Result()
+    if err == redis.Nil {
+        return nil, fmt.Errorf("livraison non trouvée: %s", id)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération de la livraison: %w", err)
+    }
+
+    var delivery models.WebhookDelivery
+    if err := delivery.FromJSON([]byte(deliveryJSON)); err != nil {
+        return nil, fmt.Errorf("erreur de désérialisation: %w", err)
+    }
+    return &delivery, nil
+}
+
+// GetDeadLetters retourne les livraisons abandonnées, des plus récentes aux
+// plus anciennes, éventuellement pour un seul abonnement
+func (s *StockService) GetDeadLetters(webhookID string) ([]models.WebhookDelivery, error) {
+    ctx := context.Background()
+
+    ids, err := s.redis.ZRevRange(ctx, WEBHOOK_DEAD_LETTER_KEY, 0, -1).Result()
+    if err != nil {
+        return nil, fmt.Errorf("erreur lors de la récupération des échecs: %w", err)
+    }
+
+    deliveries := make([]models.WebhookDelivery, 0, len(ids))
+    for _, id := range ids {
+        delivery, err := s.getWebhookDelivery(ctx, id)
+        if err != nil {
+            s.logger.Warn("Livraison en échec illisible ignorée", zap.String("id", id), zap.Error(err))
+            continue
+        }
+        if webhookID != "" && delivery.WebhookID != webhookID {
+            continue
+        }
+        deliveries = append(deliveries, *delivery)
+    }
+
+    return deliveries, nil
+}
+
+// ReplayDeadLetter replanifie immédiatement une livraison abandonnée, avec
+// un nouveau cycle de tentatives
+func (s *StockService) ReplayDeadLetter(id, userID string) (*models.WebhookDelivery, error) {
+    ctx := context.Background()
+
+    delivery, err := s.getWebhookDelivery(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    if delivery.Statut != models.WEBHOOK_DELIVERY_FAILED {
+        return nil, fmt.Errorf("livraison non rejouable: statut %s", delivery.Statut)
+    }
+
+    now := time.Now()
+    delivery.Statut = models.WEBHOOK_DELIVERY_PENDING
+    delivery.Tentatives = 0
+    delivery.ProchaineTentative = &now
+    delivery.UpdatedAt = now
+
+    pipe := s.redis.TxPipeline()
+    pipe.ZRem(ctx, WEBHOOK_DEAD_LETTER_KEY, id)
+    if err := queueWebhookDelivery(ctx, pipe, delivery); err != nil {
+        return nil, err
+    }
+    if _, err := pipe.Exec(ctx); err != nil {
+        return nil, fmt.Errorf("erreur lors de la replanification de la livraison: %w", err)
+    }
+
+    s.logger.Info("Livraison webhook rejouée",
+        zap.String("delivery_id", id),
+        zap.String("webhook_id", delivery.WebhookID),
+        zap.String("user_id", userID))
+
+    return delivery, nil
+}
+
+// RunWebhookDispatcher envoie les livraisons dues jusqu'à l'annulation du
+// contexte. Chaque livraison est verrouillée le temps de son envoi, ce qui
+// permet à plusieurs instances du service de se partager la file; une
+// livraison dont l'instance s'est arrêtée est reprise à l'expiration du verrou.
+func (s *StockService) RunWebhookDispatcher(ctx context.Context) {
+    ticker := time.NewTicker(WEBHOOK_POLL_INTERVAL)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            s.dispatchWebhooks(ctx)
+        }
+    }
+}
+
+// dispatchWebhooks envoie en parallèle les livraisons dont la prochaine
+// tentative est échue
+func (s *StockService) dispatchWebhooks(ctx context.Context) {
+    ids, err := s.redis.ZRangeByScore(ctx, WEBHOOK_PENDING_KEY, &redis.ZRangeBy{
+        Min:   "-inf",
+        Max:   strconv.FormatInt(time.Now().UnixMilli(), 10),
+        Count: WEBHOOK_BATCH_SIZE,
+    }).Result()
+    if err != nil {
+        s.logger.Warn("File des webhooks indisponible", zap.Error(err))
+        return
+    }
+
+    lease := 2 * s.webhookTimeout()
+    var wg sync.WaitGroup
+    for _, id := range ids {
+        token := uuid.New().String()
+        acquired, err := s.redis.SetNX(ctx, WEBHOOK_LOCK_PREFIX+id, token, lease).Result()
+        if err != nil || !acquired {
+            continue
+        }
+        wg.Add(1)
+        go func(id string) {
+            defer wg.Done()
+            defer releaseWebhookLock.Run(ctx, s.redis, []string{WEBHOOK_LOCK_PREFIX + id}, token)
+            s.deliverWebhook(ctx, id)
+        }(id)
+    }
+    wg.Wait()
+}
+
+// deliverWebhook effectue une tentative d'envoi et planifie la suivante en
+// cas d'échec, avec un délai doublé à chaque tentative; au-delà du nombre de
+// tentatives configuré, la livraison passe en liste des échecs
+func (s *StockService) deliverWebhook(ctx context.Context, id string) {
+    delivery, err := s.getWebhookDelivery(ctx, id)
+    if err != nil {
+        s.logger.Warn("Livraison webhook abandonnée", zap.String("delivery_id", id), zap.Error(err))
+        s.redis.ZRem(ctx, WEBHOOK_PENDING_KEY, id)
+        return
+    }
+    // Une autre instance a pu traiter la livraison entre la lecture de la
+    // file et la prise du verrou
+    if delivery.Statut != models.WEBHOOK_DELIVERY_PENDING ||
+        (delivery.ProchaineTentative != nil && delivery.ProchaineTentative.After(time.Now())) {
+        return
+    }
+
+    subscription, err := s.GetWebhook(delivery.WebhookID)
+    if err != nil || !subscription.Actif {
+        s.logger.Info("Livraison webhook sans abonnement actif abandonnée",
+            zap.String("delivery_id", id),
+            zap.String("webhook_id", delivery.WebhookID))
+        pipe := s.redis.TxPipeline()
+        pipe.ZRem(ctx, WEBHOOK_PENDING_KEY, id)
+        pipe.Del(ctx, WEBHOOK_DELIVERY_KEY_PREFIX+id)
+        pipe.Exec(ctx)
+        return
+    }
+
+    status, sendErr := s.postWebhook(ctx, subscription, delivery)
+
+    now := time.Now()
+    delivery.URL = subscription.URL
+    delivery.Tentatives++
+    delivery.DernierStatutHTTP = status
+    delivery.UpdatedAt = now
+
+    pipe := s.redis.TxPipeline()
+    var ttl time.Duration
+    switch {
+    case sendErr == nil:
+        delivery.Statut = models.WEBHOOK_DELIVERY_DELIVERED
+        delivery.DerniereErreur = ""
+        delivery.ProchaineTentative = nil
+        delivery.LivreLe = &now
+        ttl = WEBHOOK_DELIVERED_TTL
+        pipe.ZRem(ctx, WEBHOOK_PENDING_KEY, id)
+    case delivery.Tentatives >= s.config.WebhookMaxAttempts:
+        delivery.Statut = models.WEBHOOK_DELIVERY_FAILED
+        delivery.DerniereErreur = sendErr.Error()
+        delivery.ProchaineTentative = nil
+        pipe.ZRem(ctx, WEBHOOK_PENDING_KEY, id)
+        pipe.ZAdd(ctx, WEBHOOK_DEAD_LETTER_KEY, &redis.Z{Score: float64(now.UnixMilli()), Member: id})
+    default:
+        next := now.Add(s.webhookBackoff(delivery.Tentatives))
+        delivery.DerniereErreur = sendErr.Error()
+        delivery.ProchaineTentative = &next
+        pipe.ZAdd(ctx, WEBHOOK_PENDING_KEY, &redis.Z{Score: float64(next.UnixMilli()), Member: id})
+    }
+
+    deliveryJSON, err := delivery.ToJSON()
+    if err != nil {
+        s.logger.Error("Erreur de sérialisation de la livraison", zap.String("delivery_id", id), zap.Error(err))
+        return
+    }
+    pipe.Set(ctx, WEBHOOK_DELIVERY_KEY_PREFIX+id, deliveryJSON, ttl)
+    if _, err := pipe.Exec(ctx); err != nil {
+        s.logger.Error("Erreur lors de l'enregistrement de la livraison", zap.String("delivery_id", id), zap.Error(err))
+        return
+    }
+
+    if sendErr != nil {
+        s.logger.Warn("Échec d'envoi du webhook",
+            zap.String("delivery_id", id),
+            zap.String("webhook_id", delivery.WebhookID),
+            zap.Int("tentatives", delivery.Tentatives),
+            zap.String("statut", delivery.Statut),
+            zap.Error(sendErr))
+    }
+}
+
+// postWebhook envoie l'événement signé à l'abonnement. La signature est le
+// HMAC-SHA256, avec le secret de l'abonnement, de "<horodatage>.<corps>",
+// transmise en hexadécimal dans l'en-tête X-Webhook-Signature ("sha256=...")
+// avec l'horodatage Unix dans X-Webhook-Timestamp. Retourne le statut HTTP
+// reçu, et une erreur hors réponse 2xx.
+func (s *StockService) postWebhook(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+    body, err := json.Marshal(delivery.Evenement)
+    if err != nil {
+        return 0, fmt.Errorf("erreur de sérialisation de l'événement: %w", err)
+    }
+
+    timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+    mac := hmac.New(sha256.New, []byte(subscription.Secret))
+    mac.Write([]byte(timestamp + "."))
+    mac.Write(body)
+
+    ctx, cancel := context.WithTimeout(ctx, s.webhookTimeout())
+    defer cancel()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+    if err != nil {
+        return 0, fmt.Errorf("requête invalide: %w", err)
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("User-Agent", "gmao-stock-service/webhook")
+    req.Header.Set("X-Webhook-ID", delivery.WebhookID)
+    req.Header.Set("X-Webhook-Delivery", delivery.ID)
+    req.Header.Set("X-Webhook-Event", delivery.Evenement.Type)
+    req.Header.Set("X-Webhook-Timestamp", timestamp)
+    req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+    resp, err := s.webhooks.Do(req)
+    if err != nil {
+        return 0, fmt.Errorf("envoi impossible: %w", err)
+    }
+    defer resp.Body.Close()
+    io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        return resp.StatusCode, fmt.Errorf("réponse HTTP %d", resp.StatusCode)
+    }
+    return resp.StatusCode, nil
+}
+
+// webhookTimeout retourne le délai de réponse accordé à chaque envoi
+func (s *StockService) webhookTimeout() time.Duration {
+    if s.config.WebhookTimeoutSeconds <= 0 {
+        return 10 * time.Second
+    }
+    return time.Duration(s.config.WebhookTimeoutSeconds) * time.Second
+}
+
+// webhookBackoff retourne le délai avant la tentative suivant la n-ième:
+// le délai initial configuré, doublé à chaque échec et plafonné
+func (s *StockService) webhookBackoff(attempts int) time.Duration {
+    backoff := time.Duration(s.config.WebhookBackoffSeconds) * time.Second
+    for i := 1; i < attempts && backoff < WEBHOOK_MAX_BACKOFF; i++ {
+        backoff *= 2
+    }
+    if backoff > WEBHOOK_MAX_BACKOFF {
+        backoff = WEBHOOK_MAX_BACKOFF
+    }
+    return backoff
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "io"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "stock-service/models"
+)
+
+func TestThresholdEvents(t *testing.T) {
+    normal := thresholdState{}
+    low := thresholdState{low: true}
+    critical := thresholdState{low: true, critical: true}
+
+    tests := []struct {
+        name   string
+        before thresholdState
+        after  thresholdState
+        want   []string
+    }{
+        {"sans franchissement", normal, normal, nil},
+        {"passage sous le seuil", normal, low, []string{models.WEBHOOK_EVENT_LOW_STOCK}},
+        {"chute directe sous le seuil critique", normal, critical, []string{models.WEBHOOK_EVENT_LOW_STOCK, models.WEBHOOK_EVENT_CRITICAL}},
+        {"aggravation", low, critical, []string{models.WEBHOOK_EVENT_CRITICAL}},
+        {"déjà critique", critical, critical, nil},
+        {"sortie du seuil critique sous le seuil", critical, low, nil},
+        {"rétablissement", low, normal, []string{models.WEBHOOK_EVENT_RESTORED}},
+        {"rétablissement depuis le seuil critique", critical, normal, []string{models.WEBHOOK_EVENT_RESTORED}},
+    }
+
+    for _, tt := range tests {
+        got := thresholdEvents(tt.before, tt.after)
+        if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+            t.Errorf("%s: événements = %v, attendu %v", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestPieceThresholds(t *testing.T) {
+    tests := []struct {
+        disponible float64
+        want       thresholdState
+    }{
+        {11, thresholdState{}},
+        {10, thresholdState{low: true}},
+        {6, thresholdState{low: true}},
+        {5, thresholdState{low: true, critical: true}},
+    }
+
+    for _, tt := range tests {
+        piece := &models.Piece{SeuilMin: 10, QuantiteDisponible: tt.disponible}
+        if got := pieceThresholds(piece); got != tt.want {
+            t.Errorf("disponible %g: état = %+v, attendu %+v", tt.disponible, got, tt.want)
+        }
+    }
+}
+
+func TestWebhookBackoff(t *testing.T) {
+    s, _ := newTestService(t)
+    s.config.WebhookBackoffSeconds = 60
+
+    tests := []struct {
+        attempts int
+        want     time.Duration
+    }{
+        {1, time.Minute},
+        {2, 2 * time.Minute},
+        {4, 8 * time.Minute},
+        {20, WEBHOOK_MAX_BACKOFF},
+    }
+
+    for _, tt := range tests {
+        if got := s.webhookBackoff(tt.attempts); got != tt.want {
+            t.Errorf("tentative %d: délai = %s, attendu %s", tt.attempts, got, tt.want)
+        }
+    }
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+    s, _ := newTestService(t)
+
+    tests := []struct {
+        url     string
+        wantErr bool
+    }{
+        {"https://hooks.example.com/stock", false},
+        {"http://203.0.113.10:8080/hook", false},
+        {"ftp://hooks.example.com", true},
+        {"/relative", true},
+        {"http://localhost:9000", true},
+        {"http://api.localhost", true},
+        {"http://127.0.0.1/hook", true},
+        {"http://10.1.2.3/hook", true},
+        {"http://192.168.0.10/hook", true},
+        {"http://172.20.0.5/hook", true},
+        {"http://169.254.169.254/latest/meta-data", true},
+        {"http://[::1]:8080/hook", true},
+        {"http://[fe80::1]/hook", true},
+        {"http://0.0.0.0/hook", true},
+    }
+
+    for _, tt := range tests {
+        err := s.validateWebhookURL(tt.url)
+        if (err != nil) != tt.wantErr {
+            t.Errorf("%s: erreur = %v, attendu une erreur: %v", tt.url, err, tt.wantErr)
+        }
+    }
+
+    s.config.WebhookAllowPrivate = true
+    if err := s.validateWebhookURL("http://10.1.2.3/hook"); err != nil {
+        t.Errorf("adresse interne autorisée par configuration: %v", err)
+    }
+}
+
+// TestPostWebhookSignature vérifie la signature d'un envoi telle qu'un
+// destinataire la recalcule à partir du secret, de l'horodatage et du corps
+func TestPostWebhookSignature(t *testing.T) {
+    const secret = "s3cr3t"
+
+    var header http.Header
+    var body []byte
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        header = r.Header.Clone()
+        body, _ = io.ReadAll(r.Body)
+        w.WriteHeader(http.StatusNoContent)
+    }))
+    defer server.Close()
+
+    s, _ := newTestService(t)
+    subscription := &models.WebhookSubscription{ID: "W1", URL: server.URL, Secret: secret}
+    delivery := &models.WebhookDelivery{
+        ID:        "D1",
+        WebhookID: "W1",
+        Evenement: models.WebhookEvent{ID: "E1", Type: models.WEBHOOK_EVENT_LOW_STOCK, PieceID: "P1", Quantite: 3},
+    }
+
+    // Le serveur de test écoute en local: refusé à la connexion par défaut
+    if _, err := s.postWebhook(context.Background(), subscription, delivery); err == nil {
+        t.Fatal("envoi vers une adresse locale accepté, attendu un refus")
+    }
+
+    s.config.WebhookAllowPrivate = true
+    s.webhooks = s.newWebhookClient()
+    status, err := s.postWebhook(context.Background(), subscription, delivery)
+    if err != nil || status != http.StatusNoContent {
+        t.Fatalf("envoi: statut %d, erreur %v", status, err)
+    }
+
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(header.Get("X-Webhook-Timestamp") + "." + string(body)))
+    want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+    if got := header.Get("X-Webhook-Signature"); !hmac.Equal([]byte(got), []byte(want)) {
+        t.Errorf("signature = %s, attendu %s", got, want)
+    }
+
+    other := hmac.New(sha256.New, []byte("autre"))
+    other.Write([]byte(header.Get("X-Webhook-Timestamp") + "." + string(body)))
+    if header.Get("X-Webhook-Signature") == "sha256="+hex.EncodeToString(other.Sum(nil)) {
+        t.Error("signature indépendante du secret")
+    }
+
+    if header.Get("X-Webhook-Event") != models.WEBHOOK_EVENT_LOW_STOCK || header.Get("X-Webhook-Delivery") != "D1" {
+        t.Errorf("en-têtes: événement %q, livraison %q", header.Get("X-Webhook-Event"), header.Get("X-Webhook-Delivery"))
+    }
+}
+
+func TestIsInternalIP(t *testing.T) {
+    tests := []struct {
+        ip   string
+        want bool
+    }{
+        {"8.8.8.8", false},
+        {"2001:4860:4860::8888", false},
+        {"127.0.0.53", true},
+        {"10.0.0.1", true},
+        {"fd00::1", true},
+        {"169.254.1.1", true},
+        {"224.0.0.1", true},
+    }
+
+    for _, tt := range tests {
+        if got := isInternalIP(net.ParseIP(tt.ip)); got != tt.want {
+            t.Errorf("%s: interne = %v, attendu %v", tt.ip, got, tt.want)
+        }
+    }
+}